@@ -27,11 +27,13 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package archiver
 
 import (
-	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/rqme/neat"
 )
@@ -41,6 +43,15 @@ type FileSettings interface {
 	ArchivePath() string
 }
 
+// HistorySettings are optional settings of the file archiver. If the archiver's settings provide
+// them, a snapshot of the experiment is kept in a generation-numbered directory alongside the
+// latest files, and any of the snapshots can be restored.
+type HistorySettings interface {
+	ArchiveEvery() int      // Generations between snapshots. If 0, no snapshots are written
+	ArchiveKeep() int       // Number of the most recent snapshots to keep. If 0, all are kept
+	RestoreGeneration() int // Generation of the snapshot to restore. If negative, the latest files are restored
+}
+
 type File struct {
 	FileSettings
 	useTrials bool
@@ -53,78 +64,263 @@ func (a *File) SetTrial(t int) error {
 	return nil
 }
 
-func (a *File) makePath(s string) string {
+// Returns the directory of the archive's latest files
+func (a *File) makeDir() string {
 	p := a.ArchivePath()
 	if a.useTrials {
 		p = path.Join(p, strconv.Itoa(a.trialNum))
 	}
-	return path.Join(p, fmt.Sprintf("%s-%s.json", a.ArchiveName(), s))
+	return p
+}
+
+// Returns the directory of the snapshot of a generation
+func (a *File) snapshotDir(gen int) string {
+	return path.Join(a.makeDir(), fmt.Sprintf("%s-%06d", a.ArchiveName(), gen))
+}
+
+func (a *File) history() (HistorySettings, bool) {
+	hs, ok := a.FileSettings.(HistorySettings)
+	return hs, ok
+}
+
+// Returns the format used when archiving
+func (a *File) format() (Format, error) {
+	if fs, ok := a.FileSettings.(FormatSettings); ok {
+		return FormatByName(fs.ArchiveFormat())
+	}
+	return JSON{}, nil
+}
+
+// Returns the name of the file holding the key's value
+func (a *File) makeName(dir, key string, f Format) string {
+	return path.Join(dir, fmt.Sprintf("%s-%s%s", a.ArchiveName(), key, f.Ext()))
+}
+
+// Returns the generations of the snapshots in the archive, oldest first
+func (a *File) Generations() ([]int, error) {
+	fis, err := ioutil.ReadDir(a.makeDir())
+	if err != nil {
+		return nil, err
+	}
+	prefix := a.ArchiveName() + "-"
+	gens := make([]int, 0, len(fis))
+	for _, fi := range fis {
+		if !fi.IsDir() || !strings.HasPrefix(fi.Name(), prefix) {
+			continue
+		}
+		if g, err := strconv.Atoi(strings.TrimPrefix(fi.Name(), prefix)); err == nil && g >= 0 {
+			gens = append(gens, g)
+		}
+	}
+	sort.Ints(gens)
+	return gens, nil
 }
 
 func (a *File) Archive(ctx neat.Context) error {
 
-	// Save the settings
-	name := a.makePath("config")
-	f, err := os.Create(name)
+	// Save the latest settings and state
+	f, err := a.format()
 	if err != nil {
 		return err
 	}
-	e := json.NewEncoder(f)
-	if err = e.Encode(ctx); err != nil {
-		f.Close()
+	dir := a.makeDir()
+	if err = os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	if err = a.archive(ctx, dir, f); err != nil {
+		return err
+	}
+
+	// Save a snapshot of the generation
+	hs, ok := a.history()
+	if !ok || hs.ArchiveEvery() < 1 {
+		return nil
+	}
+	pop, ok := ctx.State()["population"].(*neat.Population)
+	if !ok || pop.Generation%hs.ArchiveEvery() != 0 {
+		return nil
+	}
+	if err = a.snapshot(ctx, pop.Generation, f); err != nil {
+		return err
+	}
+	return a.prune(hs.ArchiveKeep())
+}
+
+// Writes the settings and state into the directory
+func (a *File) archive(ctx neat.Context, dir string, f Format) error {
+
+	// Save the settings
+	if err := writeFile(a.makeName(dir, "config", f), f, ctx); err != nil {
 		return err
 	}
-	f.Close()
 
 	// Save the state values
 	for k, v := range ctx.State() {
-		name := a.makePath(k)
-		f, err = os.Create(name)
-		if err != nil {
+		if err := writeFile(a.makeName(dir, k, f), f, v); err != nil {
 			return err
 		}
-		e = json.NewEncoder(f)
-		if err = e.Encode(v); err != nil {
-			f.Close()
+	}
+	return nil
+}
+
+// Writes the snapshot of the generation into a temporary directory which replaces any previous
+// snapshot of the generation once complete
+func (a *File) snapshot(ctx neat.Context, gen int, f Format) (err error) {
+	dir := a.snapshotDir(gen)
+	tmp := dir + ".tmp"
+	if err = os.RemoveAll(tmp); err != nil {
+		return
+	}
+	if err = os.MkdirAll(tmp, os.ModePerm); err != nil {
+		return
+	}
+	if err = a.archive(ctx, tmp, f); err != nil {
+		return
+	}
+	if err = os.RemoveAll(dir); err != nil {
+		return
+	}
+	return os.Rename(tmp, dir)
+}
+
+// Removes all but the most recent snapshots. If keep is 0, all are kept.
+func (a *File) prune(keep int) error {
+	if keep < 1 {
+		return nil
+	}
+	gens, err := a.Generations()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(gens)-keep; i++ {
+		if err = os.RemoveAll(a.snapshotDir(gens[i])); err != nil {
 			return err
 		}
-		f.Close()
 	}
 	return nil
 }
 
+// Encodes the value into a temporary file which is renamed to the file once complete so that an
+// interrupted write does not corrupt the previous copy of the file
+func writeFile(name string, f Format, v interface{}) (err error) {
+	var w *os.File
+	if w, err = ioutil.TempFile(path.Dir(name), path.Base(name)+".tmp"); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			w.Close()
+			os.Remove(w.Name())
+		}
+	}()
+	if err = f.Encode(w, v); err != nil {
+		return
+	}
+	if err = w.Chmod(0644); err != nil {
+		return
+	}
+	if err = w.Sync(); err != nil {
+		return
+	}
+	if err = w.Close(); err != nil {
+		return
+	}
+	return os.Rename(w.Name(), name)
+}
+
 func (a *File) Restore(ctx neat.Context) error {
 
-	// Restore the settings
-	name := a.makePath("config")
-	f, err := os.Open(name)
+	// Restore the latest files unless a snapshot is requested
+	dir := a.makeDir()
+	if hs, ok := a.history(); ok && hs.RestoreGeneration() >= 0 {
+		dir = a.snapshotDir(hs.RestoreGeneration())
+		if _, err := os.Stat(dir); err != nil {
+			return fmt.Errorf("archiver.File.Restore - No snapshot of generation %d: %v", hs.RestoreGeneration(), err)
+		}
+	}
+	return a.restore(ctx, dir)
+}
+
+// Restores the settings and state from the directory. The format is that of the settings file,
+// preferring the archiver's own format, so that archives written in any format can be restored.
+func (a *File) restore(ctx neat.Context, dir string) error {
+
+	// Find the settings
+	f, err := a.format()
 	if err != nil {
 		return err
 	}
-	d := json.NewDecoder(f)
-	if err = d.Decode(&ctx); err != nil {
-		f.Close()
+	name := a.makeName(dir, "config", f)
+	for i := 0; i < len(formatOrder); i++ {
+		if _, err = os.Stat(name); err == nil {
+			break
+		}
+		f = Formats[formatOrder[i]]
+		name = a.makeName(dir, "config", f)
+	}
+
+	// Restore the settings
+	if err = readFile(name, f, ctx); err != nil {
 		return err
 	}
-	f.Close()
 
 	// Restore the state values
 	for k, v := range ctx.State() {
-		name := a.makePath(k)
+		name := a.makeName(dir, k, f)
 		if _, err := os.Stat(name); os.IsNotExist(err) {
 			continue
 		}
+		if err = readFile(name, f, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		f, err = os.Open(name)
-		if err != nil {
+// Decodes the file into the value
+func readFile(name string, f Format, v interface{}) error {
+	r, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return f.Decode(r, v)
+}
+
+// Converts the archive restored by src, including its snapshots, into the format and location of
+// dst. Each copy is restored into a new context which must register the state of the same helpers
+// as the experiment which wrote the archive.
+func Convert(newContext func() neat.Context, src, dst *File) error {
+	f, err := dst.format()
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dst.makeDir(), os.ModePerm); err != nil {
+		return err
+	}
+
+	// Convert the latest files
+	ctx := newContext()
+	if err = src.restore(ctx, src.makeDir()); err != nil {
+		return err
+	}
+	if err = dst.archive(ctx, dst.makeDir(), f); err != nil {
+		return err
+	}
+
+	// Convert the snapshots
+	gens, err := src.Generations()
+	if err != nil {
+		return err
+	}
+	for _, g := range gens {
+		ctx = newContext()
+		if err = src.restore(ctx, src.snapshotDir(g)); err != nil {
 			return err
 		}
-		d = json.NewDecoder(f)
-		if err = d.Decode(&v); err != nil {
-			f.Close()
+		if err = dst.snapshot(ctx, g, f); err != nil {
 			return err
 		}
-		f.Close()
 	}
 	return nil
 }
@@ -0,0 +1,277 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package archiver
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+type historySettings struct {
+	storeSettings
+	every, keep, restore int
+}
+
+func (s historySettings) ArchiveEvery() int      { return s.every }
+func (s historySettings) ArchiveKeep() int       { return s.keep }
+func (s historySettings) RestoreGeneration() int { return s.restore }
+
+// Archives the generations of a population, naming the context after each
+func archiveGenerations(t *testing.T, a *File, gens int) {
+	pop := testPopulation(0)
+	for gen := 0; gen < gens; gen++ {
+		pop.Generation = gen
+		pop.Genomes[0].Fitness = float64(gen)
+		ctx := &stateContext{Name: fmt.Sprintf("gen%d", gen), state: map[string]interface{}{"population": pop}}
+		if err := a.Archive(ctx); err != nil {
+			t.Fatalf("Could not archive generation %d: %v", gen, err)
+		}
+	}
+}
+
+// Returns the names of the entries in the directory
+func entries(t *testing.T, dir string) []string {
+	f, err := os.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestFileSnapshots(t *testing.T) {
+	var cases = []struct {
+		Desc        string
+		Every, Keep int
+		Expected    []int
+	}{
+		{"every generation", 1, 0, []int{0, 1, 2, 3, 4, 5}},
+		{"every other generation", 2, 0, []int{0, 2, 4}},
+		{"most recent", 1, 2, []int{4, 5}},
+		{"most recent of every other", 2, 2, []int{2, 4}},
+		{"disabled", 0, 0, []int{}},
+	}
+	for _, c := range cases {
+		dir := t.TempDir()
+		a := &File{FileSettings: historySettings{storeSettings: storeSettings{path: dir}, every: c.Every, keep: c.Keep, restore: -1}}
+		archiveGenerations(t, a, 6)
+		gens, err := a.Generations()
+		if err != nil {
+			t.Fatalf("Case %s: Could not list generations: %v", c.Desc, err)
+		}
+		if !reflect.DeepEqual(gens, c.Expected) {
+			t.Errorf("Case %s: Incorrect snapshots. Expected %v. Actual %v", c.Desc, c.Expected, gens)
+		}
+		for _, g := range c.Expected {
+			name := filepath.Join(dir, fmt.Sprintf("test-%06d", g), "test-population.json")
+			if _, err := os.Stat(name); err != nil {
+				t.Errorf("Case %s: Missing snapshot file: %v", c.Desc, err)
+			}
+		}
+	}
+}
+
+func TestFileRestoresGeneration(t *testing.T) {
+	s := historySettings{storeSettings: storeSettings{path: t.TempDir()}, every: 1, restore: -1}
+	archiveGenerations(t, &File{FileSettings: s}, 4)
+
+	var cases = []struct {
+		Restore int
+		Name    string
+		Gen     int
+	}{
+		{-1, "gen3", 3},
+		{0, "gen0", 0},
+		{2, "gen2", 2},
+	}
+	for _, c := range cases {
+		s.restore = c.Restore
+		pop := new(neat.Population)
+		ctx := &stateContext{state: map[string]interface{}{"population": pop}}
+		if err := (&File{FileSettings: s}).Restore(ctx); err != nil {
+			t.Fatalf("Could not restore generation %d: %v", c.Restore, err)
+		}
+		if ctx.Name != c.Name || pop.Generation != c.Gen || pop.Genomes[0].Fitness != float64(c.Gen) {
+			t.Errorf("Restoring generation %d: Expected %s at generation %d. Actual %s at generation %d with fitness %f", c.Restore, c.Name, c.Gen, ctx.Name, pop.Generation, pop.Genomes[0].Fitness)
+		}
+	}
+
+	s.restore = 7
+	ctx := &stateContext{state: map[string]interface{}{"population": new(neat.Population)}}
+	if err := (&File{FileSettings: s}).Restore(ctx); err == nil || !strings.Contains(err.Error(), "No snapshot of generation 7") {
+		t.Errorf("Expected missing snapshot error. Actual %v", err)
+	}
+}
+
+func TestFileSnapshotsPerTrial(t *testing.T) {
+	dir := t.TempDir()
+	a := &File{FileSettings: historySettings{storeSettings: storeSettings{path: dir}, every: 1, restore: -1}}
+	a.SetTrial(3)
+	archiveGenerations(t, a, 2)
+	for _, name := range []string{"test-config.json", "test-population.json", "test-000000", "test-000001"} {
+		if _, err := os.Stat(filepath.Join(dir, "3", name)); err != nil {
+			t.Errorf("Missing trial file: %v", err)
+		}
+	}
+}
+
+func TestFileWritesAreAtomic(t *testing.T) {
+	dir := t.TempDir()
+	a := &File{FileSettings: historySettings{storeSettings: storeSettings{path: dir}, every: 1, restore: -1}}
+	archiveGenerations(t, a, 3)
+
+	// No temporary files or directories are left behind
+	for _, d := range []string{dir, filepath.Join(dir, "test-000002")} {
+		for _, name := range entries(t, d) {
+			if strings.Contains(name, ".tmp") {
+				t.Errorf("Temporary file %s left in %s", name, d)
+			}
+		}
+	}
+
+	// A failed write leaves the previous file in place
+	name := filepath.Join(dir, "test-population.json")
+	before, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = writeFile(name, JSON{}, make(chan int)); err == nil {
+		t.Fatalf("Expected an error encoding a channel")
+	}
+	after, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(after) != string(before) {
+		t.Errorf("Failed write changed the file")
+	}
+	for _, n := range entries(t, dir) {
+		if strings.Contains(n, ".tmp") {
+			t.Errorf("Failed write left temporary file %s", n)
+		}
+	}
+
+	// A failed snapshot leaves the previous snapshot in place
+	ctx := &stateContext{Name: "bad", state: map[string]interface{}{"population": testPopulation(2), "bad": make(chan int)}}
+	if err = a.snapshot(ctx, 2, JSON{}); err == nil {
+		t.Fatalf("Expected an error archiving a channel")
+	}
+	pop := new(neat.Population)
+	rctx := &stateContext{state: map[string]interface{}{"population": pop}}
+	if err = a.restore(rctx, a.snapshotDir(2)); err != nil {
+		t.Fatalf("Could not restore previous snapshot: %v", err)
+	}
+	if rctx.Name != "gen2" {
+		t.Errorf("Failed snapshot replaced the previous one. Expected gen2. Actual %s", rctx.Name)
+	}
+}
+
+func TestGobRoundTrip(t *testing.T) {
+	pop := testPopulation(3)
+	pop.Genomes[1].Fitness = 0
+	pop.Genomes[2].Conns[101] = neat.Connection{Innovation: 101, Source: 1, Target: 2, Weight: -0.5}
+
+	b := new(bytes.Buffer)
+	if err := (Gob{}).Encode(b, pop); err != nil {
+		t.Fatalf("Could not encode: %v", err)
+	}
+	r := new(neat.Population)
+	if err := (Gob{}).Decode(b, r); err != nil {
+		t.Fatalf("Could not decode: %v", err)
+	}
+	if !reflect.DeepEqual(r, pop) {
+		t.Errorf("Gob round trip changed the population")
+	}
+
+	// Zero fields are not written so decoding into a value keeps its own
+	b.Reset()
+	if err := (Gob{}).Encode(b, neat.Genome{ID: 1}); err != nil {
+		t.Fatalf("Could not encode: %v", err)
+	}
+	g := neat.Genome{ID: 2, Fitness: 5}
+	if err := (Gob{}).Decode(b, &g); err != nil {
+		t.Fatalf("Could not decode: %v", err)
+	}
+	if g.ID != 1 || g.Fitness != 5 {
+		t.Errorf("Expected ID 1 and the fitness kept at 5. Actual %d and %f", g.ID, g.Fitness)
+	}
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	dirs := []string{t.TempDir(), t.TempDir(), t.TempDir()}
+	file := func(i int, format string) *File {
+		return &File{FileSettings: historySettings{storeSettings: storeSettings{path: dirs[i], format: format}, every: 1, restore: -1}}
+	}
+	src := file(0, "json")
+	archiveGenerations(t, src, 3)
+
+	// Convert JSON to gob and back again
+	newContext := func() neat.Context {
+		return &stateContext{state: map[string]interface{}{"population": new(neat.Population)}}
+	}
+	if err := Convert(newContext, src, file(1, "gob")); err != nil {
+		t.Fatalf("Could not convert to gob: %v", err)
+	}
+	if err := Convert(newContext, file(1, "gob"), file(2, "json")); err != nil {
+		t.Fatalf("Could not convert from gob: %v", err)
+	}
+	if gens, err := file(1, "gob").Generations(); err != nil || !reflect.DeepEqual(gens, []int{0, 1, 2}) {
+		t.Errorf("Expected gob snapshots [0 1 2]. Actual %v, %v", gens, err)
+	}
+	if _, err := os.Stat(filepath.Join(dirs[1], "test-000001", "test-population.gob.gz")); err != nil {
+		t.Errorf("Missing gob snapshot: %v", err)
+	}
+
+	// The final files match the original
+	for _, sub := range []string{"", "test-000000", "test-000001", "test-000002"} {
+		for _, name := range []string{"test-config.json", "test-population.json"} {
+			expected, err := ioutil.ReadFile(filepath.Join(dirs[0], sub, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			actual, err := ioutil.ReadFile(filepath.Join(dirs[2], sub, name))
+			if err != nil {
+				t.Fatalf("Missing converted file: %v", err)
+			}
+			if !bytes.Equal(actual, expected) {
+				t.Errorf("Converted %s differs from the original.\nExpected %s\nActual   %s", filepath.Join(sub, name), expected, actual)
+			}
+		}
+	}
+}
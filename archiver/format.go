@@ -0,0 +1,112 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package archiver
+
+import (
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// FormatSettings are optional settings of the file archiver which choose the encoding of the
+// archive's files
+type FormatSettings interface {
+	ArchiveFormat() string // Name of the format used when archiving: "json" or "gob". If empty, JSON is used
+}
+
+// Format encodes and decodes the values held in an archive's files
+type Format interface {
+	Ext() string // Extension of the files, including the leading dot
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+var (
+	// Formats which the file archiver can write and restore, by name
+	Formats = map[string]Format{
+		"json": JSON{},
+		"gob":  Gob{},
+	}
+
+	// Order in which the formats are tried when restoring an archive of unknown format
+	formatOrder = []string{"json", "gob"}
+)
+
+// Returns the named format. An empty name is JSON.
+func FormatByName(name string) (Format, error) {
+	if name == "" {
+		name = "json"
+	}
+	if f, ok := Formats[name]; ok {
+		return f, nil
+	}
+	return nil, fmt.Errorf("archiver.FormatByName - Unknown archive format %q", name)
+}
+
+// JSON writes each value as a JSON document. It is the default format and is easily read by other
+// tools but large populations produce large, slow to encode files.
+type JSON struct{}
+
+func (f JSON) Ext() string { return ".json" }
+
+func (f JSON) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func (f JSON) Decode(r io.Reader, v interface{}) error {
+	return json.NewDecoder(r).Decode(v)
+}
+
+// Gob writes each value as a gzip-compressed gob stream. The files are a fraction of the size of
+// JSON and much faster to encode, but can only be read by Go programs using the same types.
+//
+// Gob does not transmit zero values, so a field which was zero when archived leaves the field it
+// is restored into unchanged. Restore into a context whose settings are zero or defaults which
+// are also zero, or a setting deliberately archived as zero will take the context's value.
+type Gob struct{}
+
+func (f Gob) Ext() string { return ".gob.gz" }
+
+func (f Gob) Encode(w io.Writer, v interface{}) error {
+	z := gzip.NewWriter(w)
+	if err := gob.NewEncoder(z).Encode(v); err != nil {
+		z.Close()
+		return err
+	}
+	return z.Close()
+}
+
+func (f Gob) Decode(r io.Reader, v interface{}) error {
+	z, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer z.Close()
+	return gob.NewDecoder(z).Decode(v)
+}
@@ -0,0 +1,516 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+// Package kv is a small, embedded key-value store kept in a single file. Keys are grouped into
+// buckets and can be iterated in order. Only the keys and the locations of their values are kept in
+// memory; values are read from the file when needed. Changes are made in transactions which are
+// appended to the file as checksummed records followed by a commit record, so a crash loses at most
+// the transaction being written. Values which are overwritten or deleted remain in the file until it
+// is compacted.
+//
+// Every record has the form
+//
+//	op (1 byte) | bucket length (uvarint) | bucket | key length (uvarint) | key |
+//	value length (uvarint) | value | CRC-32 (IEEE, 4 bytes, big endian) of the preceding bytes
+//
+// where op is 1 to put, 2 to delete and 3 to commit the records since the previous commit. The
+// file begins with the 8 byte header "NEATKV1\n".
+package kv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const header = "NEATKV1\n"
+
+const (
+	opPut byte = iota + 1
+	opDelete
+	opCommit
+)
+
+// Returned by Update once the store is closed
+var ErrClosed = errors.New("kv - Store is closed")
+
+// Store of values kept in a single file. It is safe for concurrent use.
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	f       *os.File
+	size    int64 // Size of the file
+	live    int64 // Size of the records holding current values
+	buckets map[string]*bucket
+}
+
+// Location of a value in the file
+type loc struct {
+	off  int64 // Offset of the value
+	n    int64 // Length of the value
+	size int64 // Size of the record holding the value
+}
+
+// Locations of a bucket's values, by key
+type bucket struct {
+	locs map[string]loc
+	keys []string // Sorted keys, or nil if they must be sorted again
+}
+
+func newBucket() *bucket {
+	return &bucket{locs: make(map[string]loc)}
+}
+
+func (b *bucket) sorted() []string {
+	if b.keys == nil {
+		b.keys = make([]string, 0, len(b.locs))
+		for k := range b.locs {
+			b.keys = append(b.keys, k)
+		}
+		sort.Strings(b.keys)
+	}
+	return b.keys
+}
+
+// A change waiting to be committed
+type change struct {
+	op                 byte
+	bucket, key, value []byte
+}
+
+// Opens the store in the file, creating it if necessary. Records following the last commit, such
+// as those of a transaction interrupted by a crash, are discarded.
+func Open(path string) (s *Store, err error) {
+	s = &Store{path: path, buckets: make(map[string]*bucket)}
+	if s.f, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644); err != nil {
+		return nil, err
+	}
+	if err = s.load(); err != nil {
+		s.f.Close()
+		return nil, err
+	}
+	return
+}
+
+// Reads the file, applying each committed transaction, and truncates anything after the last
+// commit
+func (s *Store) load() error {
+	fi, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() == 0 {
+		if _, err = s.f.Write([]byte(header)); err != nil {
+			return err
+		}
+		s.size = int64(len(header))
+		return s.f.Sync()
+	}
+
+	r := bufio.NewReader(s.f)
+	h := make([]byte, len(header))
+	if _, err = io.ReadFull(r, h); err != nil || string(h) != header {
+		return fmt.Errorf("kv.Open - %s is not a key-value store", s.path)
+	}
+	pos := int64(len(header))
+	committed := pos
+	var pending []change
+	var locs []loc
+	for {
+		c, voff, n, err := readRecord(r)
+		if err != nil {
+			break // end of file or a torn record
+		}
+		if c.op == opCommit {
+			for i, p := range pending {
+				s.apply(p, locs[i])
+			}
+			pending, locs = pending[:0], locs[:0]
+			committed = pos + n
+			pos += n
+			continue
+		}
+		pending = append(pending, change{op: c.op, bucket: c.bucket, key: c.key})
+		locs = append(locs, loc{off: pos + voff, n: int64(len(c.value)), size: n})
+		pos += n
+	}
+	if committed < fi.Size() {
+		if err = s.f.Truncate(committed); err != nil {
+			return err
+		}
+	}
+	s.size = committed
+	return nil
+}
+
+// Reads a record and returns it with the offset of its value within the record and its size
+func readRecord(r *bufio.Reader) (c change, voff, n int64, err error) {
+	crc := crc32.NewIEEE()
+	tr := io.TeeReader(r, crc)
+	op := make([]byte, 1)
+	if _, err = io.ReadFull(tr, op); err != nil {
+		return
+	}
+	c.op = op[0]
+	if c.op < opPut || c.op > opCommit {
+		err = fmt.Errorf("kv - Unknown operation %d", c.op)
+		return
+	}
+	n = 1
+	for _, p := range []*[]byte{&c.bucket, &c.key, &c.value} {
+		var l uint64
+		if l, err = binary.ReadUvarint(byteReader{tr}); err != nil {
+			return
+		}
+		n += int64(uvarintLen(l))
+		voff = n
+		*p = make([]byte, l)
+		if _, err = io.ReadFull(tr, *p); err != nil {
+			return
+		}
+		n += int64(l)
+	}
+	sum := make([]byte, 4)
+	if _, err = io.ReadFull(r, sum); err != nil {
+		return
+	}
+	if binary.BigEndian.Uint32(sum) != crc.Sum32() {
+		err = fmt.Errorf("kv - Record checksum does not match")
+		return
+	}
+	n += 4
+	return
+}
+
+// Adapts a reader to the io.ByteReader needed by binary.ReadUvarint
+type byteReader struct{ io.Reader }
+
+func (b byteReader) ReadByte() (byte, error) {
+	x := make([]byte, 1)
+	_, err := io.ReadFull(b.Reader, x)
+	return x[0], err
+}
+
+func uvarintLen(x uint64) int {
+	var b [binary.MaxVarintLen64]byte
+	return binary.PutUvarint(b[:], x)
+}
+
+// Appends the encoded record to the buffer and returns the offset of its value within the record
+// and its size
+func writeRecord(w *bytes.Buffer, c change) (voff, n int64) {
+	start := w.Len()
+	w.WriteByte(c.op)
+	var b [binary.MaxVarintLen64]byte
+	for _, p := range [][]byte{c.bucket, c.key, c.value} {
+		w.Write(b[:binary.PutUvarint(b[:], uint64(len(p)))])
+		voff = int64(w.Len() - start)
+		w.Write(p)
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc32.ChecksumIEEE(w.Bytes()[start:]))
+	w.Write(sum[:])
+	return voff, int64(w.Len() - start)
+}
+
+// Applies a committed change to the locations in memory
+func (s *Store) apply(c change, l loc) {
+	b, ok := s.buckets[string(c.bucket)]
+	if !ok {
+		b = newBucket()
+		s.buckets[string(c.bucket)] = b
+	}
+	k := string(c.key)
+	old, had := b.locs[k]
+	if had {
+		s.live -= old.size
+	}
+	switch c.op {
+	case opPut:
+		b.locs[k] = l
+		s.live += l.size
+		if !had {
+			b.keys = nil
+		}
+	case opDelete:
+		if had {
+			delete(b.locs, k)
+			b.keys = nil
+		}
+	}
+}
+
+// Reads the value at the location
+func (s *Store) read(l loc) ([]byte, error) {
+	if s.f == nil {
+		return nil, ErrClosed
+	}
+	v := make([]byte, l.n)
+	if _, err := s.f.ReadAt(v, l.off); err != nil {
+		return nil, fmt.Errorf("kv - Could not read value: %v", err)
+	}
+	return v, nil
+}
+
+// Closes the store's file
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// Returns the size of the file and the size of its records which hold current values. The
+// difference is reclaimed by Compact.
+func (s *Store) Size() (file, live int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.size, s.live
+}
+
+// Tx is a transaction. Reads see the values committed before the transaction began together with
+// the values put by the transaction itself.
+type Tx struct {
+	s       *Store
+	changes []change
+	pending map[string]map[string]int // Index of the latest change to each key
+	write   bool
+}
+
+// Returns the value of the key in the bucket or nil if there is none
+func (tx *Tx) Get(bucket, key []byte) ([]byte, error) {
+	if i, ok := tx.pending[string(bucket)][string(key)]; ok {
+		c := tx.changes[i]
+		if c.op == opDelete {
+			return nil, nil
+		}
+		return append([]byte(nil), c.value...), nil
+	}
+	if b, ok := tx.s.buckets[string(bucket)]; ok {
+		if l, ok := b.locs[string(key)]; ok {
+			return tx.s.read(l)
+		}
+	}
+	return nil, nil
+}
+
+// Puts the value under the key in the bucket
+func (tx *Tx) Put(bucket, key, value []byte) error {
+	return tx.change(opPut, bucket, key, value)
+}
+
+// Deletes the key from the bucket
+func (tx *Tx) Delete(bucket, key []byte) error {
+	return tx.change(opDelete, bucket, key, nil)
+}
+
+func (tx *Tx) change(op byte, bucket, key, value []byte) error {
+	if !tx.write {
+		return fmt.Errorf("kv.Tx - Cannot change a read-only transaction")
+	}
+	c := change{
+		op:     op,
+		bucket: append([]byte(nil), bucket...),
+		key:    append([]byte(nil), key...),
+		value:  append([]byte(nil), value...),
+	}
+	tx.changes = append(tx.changes, c)
+	if tx.pending == nil {
+		tx.pending = make(map[string]map[string]int)
+	}
+	m, ok := tx.pending[string(bucket)]
+	if !ok {
+		m = make(map[string]int)
+		tx.pending[string(bucket)] = m
+	}
+	m[string(key)] = len(tx.changes) - 1
+	return nil
+}
+
+// Calls the function with each committed key and value in the bucket whose key begins with the
+// prefix, in the order of the keys. Iteration stops at the first error, which is returned.
+func (tx *Tx) ForEach(bucket, prefix []byte, fn func(k, v []byte) error) error {
+	b, ok := tx.s.buckets[string(bucket)]
+	if !ok {
+		return nil
+	}
+	keys := b.sorted()
+	p := string(prefix)
+	for i := sort.SearchStrings(keys, p); i < len(keys) && strings.HasPrefix(keys[i], p); i++ {
+		v, err := tx.s.read(b.locs[keys[i]])
+		if err != nil {
+			return err
+		}
+		if err = fn([]byte(keys[i]), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Runs the function in a read-only transaction
+func (s *Store) View(fn func(tx *Tx) error) error {
+	s.mu.Lock() // sorting the keys of a bucket modifies it
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return ErrClosed
+	}
+	return fn(&Tx{s: s})
+}
+
+// Runs the function in a transaction whose changes are written to the file and synced once it
+// returns without error
+func (s *Store) Update(fn func(tx *Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return ErrClosed
+	}
+	tx := &Tx{s: s, write: true}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.changes) == 0 {
+		return nil
+	}
+
+	// Write the changes and the commit
+	w := new(bytes.Buffer)
+	locs := make([]loc, len(tx.changes))
+	for i, c := range tx.changes {
+		start := int64(w.Len())
+		voff, n := writeRecord(w, c)
+		locs[i] = loc{off: s.size + start + voff, n: int64(len(c.value)), size: n}
+	}
+	writeRecord(w, change{op: opCommit})
+	if _, err := s.f.WriteAt(w.Bytes(), s.size); err != nil {
+		s.f.Truncate(s.size)
+		return err
+	}
+	if err := s.f.Sync(); err != nil {
+		s.f.Truncate(s.size)
+		return err
+	}
+	s.size += int64(w.Len())
+
+	// Apply them to the locations in memory
+	for i, c := range tx.changes {
+		s.apply(c, locs[i])
+	}
+	return nil
+}
+
+// Rewrites the file with only the current values. The new file replaces the old one once it is
+// complete and the store's bookkeeping is only updated after the replacement, so the store remains
+// intact if the compaction fails or is interrupted.
+func (s *Store) Compact() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return ErrClosed
+	}
+
+	// Write the current values as a single transaction to a new file, recording their new
+	// locations on the side
+	tmp := s.path + ".tmp"
+	var f *os.File
+	if f, err = os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+	}()
+	bw := bufio.NewWriter(f)
+	if _, err = bw.WriteString(header); err != nil {
+		return
+	}
+	names := make([]string, 0, len(s.buckets))
+	for n := range s.buckets {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	buckets := make(map[string]*bucket, len(s.buckets))
+	pos := int64(len(header))
+	var live int64
+	w := new(bytes.Buffer)
+	for _, n := range names {
+		b := s.buckets[n]
+		nb := newBucket()
+		for _, k := range b.sorted() {
+			var v []byte
+			if v, err = s.read(b.locs[k]); err != nil {
+				return
+			}
+			w.Reset()
+			voff, size := writeRecord(w, change{op: opPut, bucket: []byte(n), key: []byte(k), value: v})
+			if _, err = bw.Write(w.Bytes()); err != nil {
+				return
+			}
+			nb.locs[k] = loc{off: pos + voff, n: int64(len(v)), size: size}
+			pos += size
+			live += size
+		}
+		buckets[n] = nb
+	}
+	w.Reset()
+	_, size := writeRecord(w, change{op: opCommit})
+	if _, err = bw.Write(w.Bytes()); err != nil {
+		return
+	}
+	pos += size
+	if err = bw.Flush(); err != nil {
+		return
+	}
+	if err = f.Sync(); err != nil {
+		return
+	}
+	if err = os.Rename(tmp, s.path); err != nil {
+		return
+	}
+
+	// Switch to the new file
+	s.f.Close()
+	s.f = f
+	s.buckets = buckets
+	s.size = pos
+	s.live = live
+	return
+}
@@ -0,0 +1,312 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package kv
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	numbers = []byte("numbers")
+	letters = []byte("letters")
+)
+
+func open(t *testing.T, path string) *Store {
+	s, err := Open(path)
+	if err != nil {
+		t.Fatalf("Could not open store: %v", err)
+	}
+	return s
+}
+
+func put(t *testing.T, s *Store, bucket []byte, kvs ...string) {
+	err := s.Update(func(tx *Tx) error {
+		for i := 0; i < len(kvs); i += 2 {
+			if err := tx.Put(bucket, []byte(kvs[i]), []byte(kvs[i+1])); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Could not update store: %v", err)
+	}
+}
+
+// Returns the value of the key or "<nil>" if there is none
+func get(t *testing.T, s *Store, bucket []byte, k string) (v string) {
+	err := s.View(func(tx *Tx) error {
+		b, err := tx.Get(bucket, []byte(k))
+		if b == nil {
+			v = "<nil>"
+		} else {
+			v = string(b)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Could not read store: %v", err)
+	}
+	return
+}
+
+// Returns the keys and values in the bucket with the prefix as k=v pairs
+func list(t *testing.T, s *Store, bucket []byte, prefix string) string {
+	b := new(bytes.Buffer)
+	err := s.View(func(tx *Tx) error {
+		return tx.ForEach(bucket, []byte(prefix), func(k, v []byte) error {
+			fmt.Fprintf(b, "%s=%s ", k, v)
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatalf("Could not iterate store: %v", err)
+	}
+	return b.String()
+}
+
+func TestPutGetDeleteAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s := open(t, path)
+	put(t, s, numbers, "b", "2", "a", "1", "c", "3")
+	put(t, s, letters, "a", "x")
+	put(t, s, numbers, "b", "two")
+	err := s.Update(func(tx *Tx) error {
+		if err := tx.Delete(numbers, []byte("c")); err != nil {
+			return err
+		}
+		if v, _ := tx.Get(numbers, []byte("c")); v != nil {
+			t.Errorf("Deleted key is still visible in its transaction")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Could not delete: %v", err)
+	}
+	check := func(s *Store) {
+		if got, want := list(t, s, numbers, ""), "a=1 b=two "; got != want {
+			t.Errorf("Numbers are %q, expected %q", got, want)
+		}
+		if got := get(t, s, letters, "a"); got != "x" {
+			t.Errorf("Letter a is %q, expected x", got)
+		}
+		if got := get(t, s, numbers, "c"); got != "<nil>" {
+			t.Errorf("Deleted key has value %q", got)
+		}
+	}
+	check(s)
+	if err = s.Close(); err != nil {
+		t.Fatalf("Could not close store: %v", err)
+	}
+	if err = s.Update(func(tx *Tx) error { return nil }); err != ErrClosed {
+		t.Errorf("Expected ErrClosed updating a closed store but got %v", err)
+	}
+	s = open(t, path)
+	defer s.Close()
+	check(s)
+}
+
+func TestForEachPrefix(t *testing.T) {
+	s := open(t, filepath.Join(t.TempDir(), "test.db"))
+	defer s.Close()
+	put(t, s, numbers, "ab", "1", "b", "2", "aa", "3", "a", "4")
+	if got, want := list(t, s, numbers, "a"), "a=4 aa=3 ab=1 "; got != want {
+		t.Errorf("Keys with prefix are %q, expected %q", got, want)
+	}
+}
+
+func TestFailedUpdateIsDiscarded(t *testing.T) {
+	s := open(t, filepath.Join(t.TempDir(), "test.db"))
+	defer s.Close()
+	put(t, s, numbers, "a", "1")
+	size, _ := s.Size()
+	err := s.Update(func(tx *Tx) error {
+		tx.Put(numbers, []byte("a"), []byte("2"))
+		return fmt.Errorf("failed")
+	})
+	if err == nil {
+		t.Fatalf("Expected the function's error")
+	}
+	if got := get(t, s, numbers, "a"); got != "1" {
+		t.Errorf("Value is %q after a failed update, expected 1", got)
+	}
+	if after, _ := s.Size(); after != size {
+		t.Errorf("File grew from %d to %d after a failed update", size, after)
+	}
+}
+
+func TestRecoverDiscardsUncommittedAndTornRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s := open(t, path)
+	put(t, s, numbers, "a", "1")
+	put(t, s, numbers, "b", "2")
+	s.Close()
+	fi, _ := os.Stat(path)
+	committed := fi.Size()
+
+	// Append a complete record without its commit followed by a torn record
+	w := new(bytes.Buffer)
+	writeRecord(w, change{op: opPut, bucket: numbers, key: []byte("a"), value: []byte("lost")})
+	writeRecord(w, change{op: opPut, bucket: numbers, key: []byte("c"), value: []byte("torn")})
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(w.Bytes()[:w.Len()-3])
+	f.Close()
+
+	s = open(t, path)
+	if got, want := list(t, s, numbers, ""), "a=1 b=2 "; got != want {
+		t.Errorf("Recovered values are %q, expected %q", got, want)
+	}
+	if fi, _ = os.Stat(path); fi.Size() != committed {
+		t.Errorf("File is %d bytes after recovery, expected the committed %d", fi.Size(), committed)
+	}
+
+	// The store continues after the recovered records
+	put(t, s, numbers, "c", "3")
+	s.Close()
+	s = open(t, path)
+	defer s.Close()
+	if got, want := list(t, s, numbers, ""), "a=1 b=2 c=3 "; got != want {
+		t.Errorf("Values after recovery and update are %q, expected %q", got, want)
+	}
+}
+
+func TestRecoverIgnoresCorruptRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s := open(t, path)
+	put(t, s, numbers, "a", "1")
+	size, _ := s.Size()
+	put(t, s, numbers, "a", "2")
+	s.Close()
+
+	// Flip a byte of the second transaction's value so its checksum fails
+	b, _ := os.ReadFile(path)
+	i := bytes.LastIndex(b, []byte("2"))
+	b[i] = '9'
+	os.WriteFile(path, b, 0644)
+
+	s = open(t, path)
+	defer s.Close()
+	if got := get(t, s, numbers, "a"); got != "1" {
+		t.Errorf("Value is %q, expected the last intact value 1", got)
+	}
+	if after, _ := s.Size(); after != size {
+		t.Errorf("Store is %d bytes, expected %d", after, size)
+	}
+}
+
+func TestOpenRejectsOtherFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	os.WriteFile(path, []byte("not a store"), 0644)
+	if _, err := Open(path); err == nil {
+		t.Errorf("Expected an error opening a file which is not a store")
+	}
+}
+
+func TestCompactReclaimsSpace(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s := open(t, path)
+	for i := 0; i < 20; i++ {
+		put(t, s, numbers, "a", fmt.Sprint(i), "b", "fixed")
+	}
+	put(t, s, letters, "x", "y")
+	before, live := s.Size()
+	if err := s.Compact(); err != nil {
+		t.Fatalf("Could not compact: %v", err)
+	}
+	after, live2 := s.Size()
+	if after >= before || live2 != live {
+		t.Errorf("Compaction left %d bytes (live %d) from %d (live %d)", after, live2, before, live)
+	}
+	if fi, _ := os.Stat(path); fi.Size() != after {
+		t.Errorf("File is %d bytes but the store reports %d", fi.Size(), after)
+	}
+	check := func(s *Store, want string) {
+		if got := list(t, s, numbers, ""); got != want {
+			t.Errorf("Values are %q, expected %q", got, want)
+		}
+		if got := get(t, s, letters, "x"); got != "y" {
+			t.Errorf("Letter x is %q, expected y", got)
+		}
+	}
+	check(s, "a=19 b=fixed ")
+
+	// Updates continue in the compacted file
+	put(t, s, numbers, "c", "3")
+	s.Close()
+	s = open(t, path)
+	defer s.Close()
+	check(s, "a=19 b=fixed c=3 ")
+}
+
+func TestFailedCompactLeavesStoreIntact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	s := open(t, path)
+	for i := 0; i < 5; i++ {
+		put(t, s, numbers, "a", fmt.Sprint(i))
+	}
+	size, live := s.Size()
+
+	// A directory in place of the temporary file makes the compaction fail
+	if err := os.MkdirAll(filepath.Join(path+".tmp", "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Compact(); err == nil {
+		t.Fatalf("Expected the compaction to fail")
+	}
+	if size2, live2 := s.Size(); size2 != size || live2 != live {
+		t.Errorf("Sizes are %d/%d after a failed compaction, expected %d/%d", size2, live2, size, live)
+	}
+	if got := get(t, s, numbers, "a"); got != "4" {
+		t.Errorf("Value is %q after a failed compaction, expected 4", got)
+	}
+	put(t, s, numbers, "a", "5", "b", "1")
+
+	// The live size matches that of a store which was never compacted
+	ctl := open(t, filepath.Join(t.TempDir(), "control.db"))
+	defer ctl.Close()
+	for i := 0; i < 5; i++ {
+		put(t, ctl, numbers, "a", fmt.Sprint(i))
+	}
+	put(t, ctl, numbers, "a", "5", "b", "1")
+	_, live = s.Size()
+	if _, want := ctl.Size(); live != want {
+		t.Errorf("Live size is %d after a failed compaction, expected %d", live, want)
+	}
+	s.Close()
+	s = open(t, path)
+	defer s.Close()
+	if got, want := list(t, s, numbers, ""), "a=5 b=1 "; got != want {
+		t.Errorf("Values are %q after reopening, expected %q", got, want)
+	}
+}
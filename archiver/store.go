@@ -0,0 +1,419 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package archiver
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/archiver/kv"
+)
+
+// Buckets of the store
+var (
+	settingsBucket   = []byte("settings")
+	stateBucket      = []byte("state")
+	generationBucket = []byte("generations")
+	genomeBucket     = []byte("genomes")
+	digestBucket     = []byte("genome-digests")
+	memberBucket     = []byte("genome-history")  // genome ID, generation
+	speciesBucket    = []byte("species-history") // species ID, generation
+	rosterBucket     = []byte("generation-species")
+)
+
+// Summary of an archived generation
+type GenerationRecord struct {
+	Generation  int
+	Genomes     int     // Number of genomes in the population
+	Species     int     // Number of species in the population
+	Best        int     // ID of the genome with the highest fitness
+	BestFitness float64 // Fitness of the best genome
+	MeanFitness float64 // Mean fitness of the population
+}
+
+// Appearance of a genome in an archived generation
+type GenomeRecord struct {
+	ID         int
+	Generation int
+	Species    int // ID of the genome's species
+	Fitness    float64
+}
+
+// State of a species in an archived generation
+type SpeciesRecord struct {
+	ID              int
+	Generation      int
+	Age             int
+	Stagnation      int
+	Improvement     float64
+	Size            int     // Number of genomes in the species
+	Champion        int     // ID of the genome in the species with the highest fitness
+	ChampionFitness float64 // Fitness of the champion
+	MeanFitness     float64 // Mean fitness of the species' genomes
+}
+
+// Store archives the experiment into an embedded key-value store kept in a single file,
+// <path>/<name>.db. In addition to the latest settings and state, which it can restore, the store
+// keeps every archived genome and a record of each generation and species so that the history of
+// the experiment can be queried.
+type Store struct {
+	FileSettings
+	useTrials bool
+	trialNum  int
+	db        *kv.Store
+}
+
+// Sets the trial number, closing the previous trial's file
+func (a *Store) SetTrial(t int) error {
+	if a.useTrials && a.trialNum == t {
+		return nil
+	}
+	a.useTrials = true
+	a.trialNum = t
+	return a.Close()
+}
+
+func (a *Store) makePath() string {
+	p := a.ArchivePath()
+	if a.useTrials {
+		p = path.Join(p, strconv.Itoa(a.trialNum))
+	}
+	return path.Join(p, a.ArchiveName()+".db")
+}
+
+// Returns the open key-value store
+func (a *Store) open() (*kv.Store, error) {
+	if a.db == nil {
+		p := a.makePath()
+		if err := os.MkdirAll(path.Dir(p), os.ModePerm); err != nil {
+			return nil, err
+		}
+		db, err := kv.Open(p)
+		if err != nil {
+			return nil, err
+		}
+		a.db = db
+	}
+	return a.db, nil
+}
+
+// Closes the store's file. The file is opened again if the store is used.
+func (a *Store) Close() error {
+	if a.db == nil {
+		return nil
+	}
+	err := a.db.Close()
+	a.db = nil
+	return err
+}
+
+func (a *Store) format() (Format, error) {
+	if fs, ok := a.FileSettings.(FormatSettings); ok {
+		return FormatByName(fs.ArchiveFormat())
+	}
+	return JSON{}, nil
+}
+
+// Decodes the value, recognising the gzip header of the gob format so that a store can be read
+// whichever format it was written in
+func decode(b []byte, v interface{}) error {
+	var f Format = JSON{}
+	if len(b) > 1 && b[0] == 0x1f && b[1] == 0x8b {
+		f = Gob{}
+	}
+	return f.Decode(bytes.NewReader(b), v)
+}
+
+// Returns a digest of the genome's canonical encoding. Unlike gob, JSON writes the genome's maps in
+// key order so the same genome always has the same digest. A genome which cannot be encoded, such as
+// one with a NaN fitness, has no digest.
+func digest(g neat.Genome) []byte {
+	b, err := json.Marshal(g)
+	if err != nil {
+		return nil
+	}
+	d := sha1.Sum(b)
+	return d[:]
+}
+
+// Returns the key made from the numbers. Numbers are written big endian so that keys sort in
+// numeric order.
+func key(xs ...int) []byte {
+	b := make([]byte, 8*len(xs))
+	for i, x := range xs {
+		binary.BigEndian.PutUint64(b[i*8:], uint64(x))
+	}
+	return b
+}
+
+func (a *Store) Archive(ctx neat.Context) error {
+	db, err := a.open()
+	if err != nil {
+		return err
+	}
+	f, err := a.format()
+	if err != nil {
+		return err
+	}
+	encode := func(v interface{}) ([]byte, error) {
+		b := new(bytes.Buffer)
+		err := f.Encode(b, v)
+		return b.Bytes(), err
+	}
+
+	err = db.Update(func(tx *kv.Tx) error {
+
+		// Save the settings and state
+		b, err := encode(ctx)
+		if err != nil {
+			return err
+		}
+		tx.Put(settingsBucket, []byte("config"), b)
+		for k, v := range ctx.State() {
+			if b, err = encode(v); err != nil {
+				return err
+			}
+			tx.Put(stateBucket, []byte(k), b)
+		}
+
+		// Record the generation
+		pop, ok := ctx.State()["population"].(*neat.Population)
+		if !ok || len(pop.Genomes) == 0 {
+			return nil
+		}
+		gen := pop.Generation
+		gr := GenerationRecord{Generation: gen, Genomes: len(pop.Genomes), Species: len(pop.Species)}
+		srs := make([]SpeciesRecord, len(pop.Species))
+		for i, s := range pop.Species {
+			srs[i] = SpeciesRecord{ID: s.ID, Generation: gen, Age: s.Age, Stagnation: s.Stagnation, Improvement: s.Improvement}
+		}
+		for i, g := range pop.Genomes {
+			if i == 0 || g.Fitness > gr.BestFitness {
+				gr.Best, gr.BestFitness = g.ID, g.Fitness
+			}
+			gr.MeanFitness += g.Fitness / float64(len(pop.Genomes))
+
+			// Save the genome, unless it is unchanged, and its appearance in this generation
+			d := digest(g)
+			var old []byte
+			if old, err = tx.Get(digestBucket, key(g.ID)); err != nil {
+				return err
+			}
+			if d == nil || !bytes.Equal(old, d) {
+				if b, err = encode(g); err != nil {
+					return err
+				}
+				tx.Put(genomeBucket, key(g.ID), b)
+				tx.Put(digestBucket, key(g.ID), d)
+			}
+			mr := GenomeRecord{ID: g.ID, Generation: gen, Fitness: g.Fitness, Species: -1}
+			if g.SpeciesIdx >= 0 && g.SpeciesIdx < len(srs) {
+				sr := &srs[g.SpeciesIdx]
+				mr.Species = sr.ID
+				if sr.Size == 0 || g.Fitness > sr.ChampionFitness {
+					sr.Champion, sr.ChampionFitness = g.ID, g.Fitness
+				}
+				sr.Size += 1
+				sr.MeanFitness += g.Fitness
+			}
+			if b, err = encode(mr); err != nil {
+				return err
+			}
+			tx.Put(memberBucket, key(g.ID, gen), b)
+		}
+		for _, sr := range srs {
+			if sr.Size > 0 {
+				sr.MeanFitness /= float64(sr.Size)
+			}
+			if b, err = encode(sr); err != nil {
+				return err
+			}
+			tx.Put(speciesBucket, key(sr.ID, gen), b)
+			tx.Put(rosterBucket, key(gen, sr.ID), b)
+		}
+		if b, err = encode(gr); err != nil {
+			return err
+		}
+		return tx.Put(generationBucket, key(gen), b)
+	})
+	if err != nil {
+		return err
+	}
+
+	// Reclaim the space of the replaced settings and state once it outweighs the current values
+	if size, live := db.Size(); size > 1<<20 && size > 2*live {
+		return db.Compact()
+	}
+	return nil
+}
+
+// Restores the latest settings and state. The store does not keep the state of earlier
+// generations so they cannot be restored.
+func (a *Store) Restore(ctx neat.Context) error {
+	if hs, ok := a.FileSettings.(HistorySettings); ok && hs.RestoreGeneration() >= 0 {
+		return fmt.Errorf("archiver.Store.Restore - The store only keeps the latest state. Generation %d cannot be restored", hs.RestoreGeneration())
+	}
+	if _, err := os.Stat(a.makePath()); err != nil {
+		return err
+	}
+	db, err := a.open()
+	if err != nil {
+		return err
+	}
+	return db.View(func(tx *kv.Tx) error {
+		b, err := tx.Get(settingsBucket, []byte("config"))
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("archiver.Store.Restore - No settings have been archived in %s", a.makePath())
+		}
+		if err := decode(b, ctx); err != nil {
+			return err
+		}
+		for k, v := range ctx.State() {
+			if b, err = tx.Get(stateBucket, []byte(k)); err != nil {
+				return err
+			}
+			if b == nil {
+				continue
+			}
+			if err := decode(b, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Decodes each value in the bucket whose key begins with the prefix. Next returns the value to
+// decode into and is called once per value.
+func (a *Store) query(bucket, prefix []byte, next func() interface{}) error {
+	db, err := a.open()
+	if err != nil {
+		return err
+	}
+	return db.View(func(tx *kv.Tx) error {
+		return tx.ForEach(bucket, prefix, func(k, v []byte) error {
+			return decode(v, next())
+		})
+	})
+}
+
+// Returns the records of the archived generations, oldest first
+func (a *Store) Generations() (rs []GenerationRecord, err error) {
+	err = a.query(generationBucket, nil, func() interface{} {
+		rs = append(rs, GenerationRecord{})
+		return &rs[len(rs)-1]
+	})
+	return
+}
+
+// Returns the latest archived copy of the genome
+func (a *Store) Genome(id int) (g neat.Genome, err error) {
+	found := false
+	err = a.query(genomeBucket, key(id), func() interface{} {
+		found = true
+		return &g
+	})
+	if err == nil && !found {
+		err = fmt.Errorf("archiver.Store.Genome - Genome %d has not been archived", id)
+	}
+	return
+}
+
+// Returns the genome's appearance in each archived generation, oldest first
+func (a *Store) GenomeHistory(id int) (rs []GenomeRecord, err error) {
+	err = a.query(memberBucket, key(id), func() interface{} {
+		rs = append(rs, GenomeRecord{})
+		return &rs[len(rs)-1]
+	})
+	return
+}
+
+// Returns the records of the species in an archived generation
+func (a *Store) Species(generation int) (rs []SpeciesRecord, err error) {
+	err = a.query(rosterBucket, key(generation), func() interface{} {
+		rs = append(rs, SpeciesRecord{})
+		return &rs[len(rs)-1]
+	})
+	return
+}
+
+// Returns the records of the species in each archived generation in which it appears, oldest
+// first
+func (a *Store) SpeciesHistory(id int) (rs []SpeciesRecord, err error) {
+	err = a.query(speciesBucket, key(id), func() interface{} {
+		rs = append(rs, SpeciesRecord{})
+		return &rs[len(rs)-1]
+	})
+	return
+}
+
+// Returns the champion of the species in each archived generation in which it appears, oldest
+// first. Genomes which are champions of consecutive generations are returned once.
+func (a *Store) Champions(id int) (gs []neat.Genome, err error) {
+	var rs []SpeciesRecord
+	if rs, err = a.SpeciesHistory(id); err != nil {
+		return
+	}
+	for i, r := range rs {
+		if i > 0 && rs[i-1].Champion == r.Champion {
+			continue
+		}
+		var g neat.Genome
+		if g, err = a.Genome(r.Champion); err != nil {
+			return
+		}
+		gs = append(gs, g)
+	}
+	return
+}
+
+// Returns the genome and its archived ancestors, following the first parent of each genome. The
+// lineage ends at a genome without parents or whose parent was never archived.
+func (a *Store) Lineage(id int) (gs []neat.Genome, err error) {
+	var g neat.Genome
+	if g, err = a.Genome(id); err != nil {
+		return
+	}
+	gs = append(gs, g)
+	for len(g.Parents) > 0 {
+		if g, err = a.Genome(g.Parents[0]); err != nil {
+			return gs, nil
+		}
+		gs = append(gs, g)
+	}
+	return
+}
@@ -0,0 +1,172 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package archiver
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+type storeSettings struct {
+	path, format string
+}
+
+func (s storeSettings) ArchiveName() string   { return "test" }
+func (s storeSettings) ArchivePath() string   { return s.path }
+func (s storeSettings) ArchiveFormat() string { return s.format }
+
+// Context holding only the state
+type stateContext struct {
+	neat.Context `json:"-"`
+	Name         string
+	state        map[string]interface{}
+}
+
+func (c *stateContext) State() map[string]interface{} { return c.state }
+
+// Returns a genome with enough nodes and connections that gob's map ordering varies
+func testGenome(id int, fitness float64) neat.Genome {
+	g := neat.Genome{ID: id, Fitness: fitness, Nodes: make(neat.Nodes), Conns: make(neat.Connections)}
+	for i := 0; i < 20; i++ {
+		g.Nodes[i] = neat.Node{Innovation: i, NeuronType: neat.Hidden, X: float64(i), Y: float64(id)}
+		g.Conns[100+i] = neat.Connection{Innovation: 100 + i, Source: i, Target: (i + 1) % 20, Weight: float64(i), Enabled: true}
+	}
+	return g
+}
+
+func testPopulation(gen int) *neat.Population {
+	pop := &neat.Population{Generation: gen}
+	for i := 0; i < 10; i++ {
+		g := testGenome(i, float64(i))
+		g.SpeciesIdx = i % 2
+		pop.Genomes = append(pop.Genomes, g)
+	}
+	pop.Species = []neat.Species{{ID: 0}, {ID: 1}}
+	return pop
+}
+
+func fileSize(t *testing.T, a *Store) int64 {
+	fi, err := os.Stat(a.makePath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fi.Size()
+}
+
+func TestStoreSkipsUnchangedGenomes(t *testing.T) {
+	for _, format := range []string{"json", "gob"} {
+		a := &Store{FileSettings: storeSettings{path: t.TempDir(), format: format}}
+		pop := testPopulation(0)
+		ctx := &stateContext{state: map[string]interface{}{"population": pop}}
+
+		// Archiving the same population again rewrites the state but none of the genomes.
+		// Changing one genome should grow the next archive by at least that genome.
+		grow := func() int64 {
+			before := fileSize(t, a)
+			if err := a.Archive(ctx); err != nil {
+				t.Fatalf("Could not archive: %v", err)
+			}
+			return fileSize(t, a) - before
+		}
+		if err := a.Archive(ctx); err != nil {
+			t.Fatalf("Could not archive: %v", err)
+		}
+		pop.Generation = 1
+		unchanged := grow()
+		pop.Generation = 2
+		pop.Genomes[3].Fitness = 42
+		changed := grow()
+
+		f, _ := FormatByName(format)
+		b := new(bytes.Buffer)
+		f.Encode(b, pop.Genomes[3])
+		if changed-unchanged < int64(b.Len()) {
+			t.Errorf("%s store grew by %d bytes for unchanged genomes and %d bytes after changing a genome of %d bytes", format, unchanged, changed, b.Len())
+		}
+		g, err := a.Genome(3)
+		if err != nil {
+			t.Fatalf("Could not read genome: %v", err)
+		}
+		if g.Fitness != 42 || len(g.Nodes) != 20 {
+			t.Errorf("%s store returned genome with fitness %f and %d nodes, expected 42 and 20", format, g.Fitness, len(g.Nodes))
+		}
+		a.Close()
+	}
+}
+
+func TestStoreQueriesAndRestore(t *testing.T) {
+	s := storeSettings{path: t.TempDir(), format: "gob"}
+	a := &Store{FileSettings: s}
+	pop := testPopulation(0)
+	ctx := &stateContext{Name: "first", state: map[string]interface{}{"population": pop}}
+	for gen := 0; gen < 3; gen++ {
+		pop.Generation = gen
+		pop.Genomes[gen].Fitness = float64(10 + gen) // a new best each generation
+		if err := a.Archive(ctx); err != nil {
+			t.Fatalf("Could not archive: %v", err)
+		}
+	}
+
+	gs, err := a.Generations()
+	if err != nil || len(gs) != 3 {
+		t.Fatalf("Expected 3 generations but got %d: %v", len(gs), err)
+	}
+	if gs[2].Best != 2 || gs[2].BestFitness != 12 || gs[2].Genomes != 10 || gs[2].Species != 2 {
+		t.Errorf("Unexpected record of last generation: %+v", gs[2])
+	}
+	hs, err := a.GenomeHistory(4)
+	if err != nil || len(hs) != 3 || hs[0].Species != 0 || hs[2].Generation != 2 {
+		t.Errorf("Unexpected history of genome 4: %+v %v", hs, err)
+	}
+	ss, err := a.Species(1)
+	if err != nil || len(ss) != 2 || ss[0].Size != 5 || ss[1].Champion != 1 {
+		t.Errorf("Unexpected species of generation 1: %+v %v", ss, err)
+	}
+	cs, err := a.Champions(0)
+	if err != nil || len(cs) != 2 || cs[0].ID != 0 || cs[1].ID != 2 {
+		t.Errorf("Unexpected champions of species 0: %v", err)
+	}
+	if _, err = a.Genome(99); err == nil {
+		t.Errorf("Expected an error reading a genome which was never archived")
+	}
+	a.Close()
+
+	// Restore into a new context
+	b := &Store{FileSettings: s}
+	rpop := &neat.Population{}
+	rctx := &stateContext{state: map[string]interface{}{"population": rpop}}
+	if err = b.Restore(rctx); err != nil {
+		t.Fatalf("Could not restore: %v", err)
+	}
+	if rctx.Name != "first" || rpop.Generation != 2 || len(rpop.Genomes) != 10 || rpop.Genomes[2].Fitness != 12 {
+		t.Errorf("Restored %q generation %d with %d genomes", rctx.Name, rpop.Generation, len(rpop.Genomes))
+	}
+	b.Close()
+}
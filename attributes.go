@@ -26,6 +26,8 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 package neat
 
+import "context"
+
 // Provides setup actions in the helper's lifestyle
 type Setupable interface {
 	// Sets up the helper
@@ -84,3 +86,38 @@ type Trialable interface {
 type Improvable interface {
 	Improvement() float64
 }
+
+// Objectivable describes a result which scores the phenome against several objectives. All
+// objectives are maximized.
+type Objectivable interface {
+	Objectives() []float64
+}
+
+// Resettable describes a network which retains state between activations, such as a recurrent
+// network. Evaluators should reset the network before beginning a new, independent trial.
+type Resettable interface {
+	// Clears the state of the network
+	Reset()
+}
+
+// Checkpointable describes a helper with internal state which must be archived in order to resume
+// an experiment exactly where it left off
+type Checkpointable interface {
+	// Returns a pointer to the helper's state. The archiver encodes the value when archiving and
+	// decodes into it when restoring.
+	Checkpoint() interface{}
+}
+
+// Cancelable describes a searcher which stops searching once its context is done
+type Cancelable interface {
+	// Searches the problem's solution space using the phenomes, returning the context's error if
+	// it is done before the search completes
+	SearchContext(c context.Context, phenomes []Phenome) ([]Result, error)
+}
+
+// Genealogical describes a helper, such as a generator, which records the genealogy of the genomes
+// it creates
+type Genealogical interface {
+	// Returns the record of the genomes created during the experiment
+	Genealogy() *Genealogy
+}
@@ -109,6 +109,9 @@ func (g *sortConnsByKey) Less(i, j int) bool {
 	ti := g.nodeMap[g.conns[i].Target]
 	tj := g.nodeMap[g.conns[j].Target]
 	if ti.Y == tj.Y {
+		if ti.X == tj.X {
+			return g.conns[i].Innovation < g.conns[j].Innovation // Keep the order repeatable
+		}
 		return ti.X < tj.X
 	} else {
 		return ti.Y < tj.Y
@@ -25,6 +25,12 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 */
 package neat
 
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
 type Context interface {
 	// Component helpers
 	Archiver() Archiver
@@ -46,4 +52,38 @@ type Context interface {
 
 	// Returns the innovation number for the gene
 	Innovation(t InnoType, k InnoKey) int
+
+	// Returns the random number generator shared by the helpers. Helpers are called one at a time
+	// so that an experiment using the same seed (and evaluator) is reproducible.
+	Rand() *rand.Rand
+}
+
+// Returns the context's random number generator or, if there is no context, a generator shared by
+// the helpers used without one. The shared generator is safe for concurrent use but, as it is
+// seeded from the clock, its sequence is not repeatable.
+func Rand(ctx Context) *rand.Rand {
+	if ctx == nil {
+		return shared
+	}
+	return ctx.Rand()
+}
+
+var shared = rand.New(&lockedSource{src: rand.NewSource(time.Now().UnixNano())})
+
+// Source which can be used by several goroutines at once
+type lockedSource struct {
+	mu  sync.Mutex
+	src rand.Source
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
 }
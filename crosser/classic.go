@@ -42,6 +42,12 @@ type ClassicSettings interface {
 
 type Classic struct {
 	ClassicSettings
+	ctx neat.Context
+}
+
+func (c *Classic) SetContext(x neat.Context) error {
+	c.ctx = x
+	return nil
 }
 
 // Returns a new genome that is a cross between the two parent genomes
@@ -54,7 +60,7 @@ type Classic struct {
 // is inherited from either parent randomly. Disabled genes have a chance of being reenabled during
 // crossover, allowing networks to make use of older genes once again. (Stanley, 38)
 func (c Classic) Cross(p1, p2 neat.Genome) (child neat.Genome, err error) {
-	rng := rand.New(rand.NewSource(rand.Int63()))
+	rng := neat.Rand(c.ctx)
 
 	// Ensure the more fit parent is first
 	same := (p1.Fitness == p2.Fitness)
@@ -135,34 +141,60 @@ func (c *Classic) addConns(rng *rand.Rand, same bool, p1, p2 neat.Genome, child
 
 // Enables connections based on probability
 func (c *Classic) enableConns(rng *rand.Rand, child *neat.Genome) {
-	for k, conn := range child.Conns {
+	_, conns := child.GenesByInnovation()
+	for _, conn := range conns {
 		if !conn.Enabled && rng.Float64() < c.EnableProbability() {
 			conn.Enabled = true
-			child.Conns[k] = conn
+			child.Conns[conn.Innovation] = conn
 		}
 	}
 }
 
-// Ensures that child has proper nodes for each connection
+// Ensures that child has proper nodes for each connection. A connection referring to a node found
+// in neither parent, which can only come from a malformed parent, is dropped along with any hidden
+// node which only it used.
 func (c *Classic) ensureNodes(rng *rand.Rand, p1, p2 neat.Genome, child *neat.Genome) {
 	child.Nodes = make(map[int]neat.Node, len(p1.Nodes))
 	for k, node := range p1.Nodes {
 		child.Nodes[k] = node
 	}
-	for _, conn := range child.Conns {
-		var k int
-		for i := 0; i < 2; i++ {
-			if i == 0 {
-				k = conn.Source
+	var dropped []neat.Connection
+	for ck, conn := range child.Conns {
+		missing := make(map[int]neat.Node, 2) // nodes to take from the second parent
+		found := true
+		for _, k := range []int{conn.Source, conn.Target} {
+			if _, ok := child.Nodes[k]; ok {
+				continue
+			}
+			if node, ok := p2.Nodes[k]; ok {
+				missing[k] = node
 			} else {
-				k = conn.Target
+				found = false
 			}
-			if _, ok := child.Nodes[k]; !ok {
-				node, ok := p2.Nodes[k]
-				if !ok {
-					panic("MISSING3")
-				}
-				child.Nodes[k] = node
+		}
+		if !found {
+			delete(child.Conns, ck)
+			dropped = append(dropped, conn)
+			continue
+		}
+		for k, node := range missing {
+			child.Nodes[k] = node
+		}
+	}
+	if len(dropped) == 0 {
+		return
+	}
+
+	// Remove the hidden nodes left without connections
+	used := make(map[int]bool, len(child.Nodes))
+	for _, conn := range child.Conns {
+		used[conn.Source] = true
+		used[conn.Target] = true
+	}
+	for _, conn := range dropped {
+		for _, k := range []int{conn.Source, conn.Target} {
+			if node, ok := child.Nodes[k]; ok && node.NeuronType == neat.Hidden && !used[k] {
+				delete(child.Nodes, k)
 			}
 		}
 	}
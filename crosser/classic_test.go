@@ -0,0 +1,126 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package crosser
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+// Context providing only the random number generator
+type randContext struct {
+	neat.Context
+	rng *rand.Rand
+}
+
+func (c randContext) Rand() *rand.Rand { return c.rng }
+
+type settings struct{}
+
+func (s settings) EnableProbability() float64          { return 0 }
+func (s settings) MateByAveragingProbability() float64 { return 0 }
+
+func newClassic() *Classic {
+	c := &Classic{ClassicSettings: settings{}}
+	c.SetContext(randContext{rng: rand.New(rand.NewSource(1))})
+	return c
+}
+
+func TestCrossDropsConnectionsToMissingNodes(t *testing.T) {
+	p1 := neat.Genome{
+		Fitness: 1,
+		Nodes: map[int]neat.Node{
+			1: {Innovation: 1, NeuronType: neat.Input, X: 0, Y: 0},
+			2: {Innovation: 2, NeuronType: neat.Output, X: 0, Y: 1},
+			4: {Innovation: 4, NeuronType: neat.Hidden, X: 0.2, Y: 0.5},
+			5: {Innovation: 5, NeuronType: neat.Hidden, X: 0.4, Y: 0.5},
+		},
+		Conns: map[int]neat.Connection{
+			10: {Innovation: 10, Source: 1, Target: 2, Enabled: true},
+			13: {Innovation: 13, Source: 4, Target: 98, Enabled: true}, // malformed
+		},
+	}
+	p2 := neat.Genome{
+		Fitness: 1,
+		Nodes: map[int]neat.Node{
+			1: {Innovation: 1, NeuronType: neat.Input, X: 0, Y: 0},
+			2: {Innovation: 2, NeuronType: neat.Output, X: 0, Y: 1},
+			3: {Innovation: 3, NeuronType: neat.Hidden, X: 0.6, Y: 0.5},
+			6: {Innovation: 6, NeuronType: neat.Hidden, X: 0.8, Y: 0.5},
+		},
+		Conns: map[int]neat.Connection{
+			10: {Innovation: 10, Source: 1, Target: 2, Enabled: true},
+			12: {Innovation: 12, Source: 3, Target: 99, Enabled: true}, // malformed
+			14: {Innovation: 14, Source: 1, Target: 6, Enabled: true},
+		},
+	}
+	child, err := newClassic().Cross(p1, p2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, k := range []int{12, 13} {
+		if _, ok := child.Conns[k]; ok {
+			t.Errorf("Connection %d to a missing node was not dropped", k)
+		}
+	}
+	for _, k := range []int{10, 14} {
+		if _, ok := child.Conns[k]; !ok {
+			t.Errorf("Connection %d was not inherited", k)
+		}
+	}
+	for k, want := range map[int]bool{1: true, 2: true, 3: false, 4: false, 5: true, 6: true} {
+		if _, ok := child.Nodes[k]; ok != want {
+			t.Errorf("Node %d in child is %v, expected %v", k, ok, want)
+		}
+	}
+	if err = child.Validate(); err != nil {
+		t.Errorf("Child is not valid: %v", err)
+	}
+}
+
+func TestCrossWithoutContext(t *testing.T) {
+	p := neat.Genome{
+		Fitness: 1,
+		Nodes: map[int]neat.Node{
+			1: {Innovation: 1, NeuronType: neat.Input, X: 0, Y: 0},
+			2: {Innovation: 2, NeuronType: neat.Output, X: 0, Y: 1},
+		},
+		Conns: map[int]neat.Connection{
+			3: {Innovation: 3, Source: 1, Target: 2, Weight: 0.5, Enabled: true},
+		},
+	}
+	c := &Classic{ClassicSettings: settings{}}
+	child, err := c.Cross(p, neat.CopyGenome(p))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(child.Nodes) != 2 || len(child.Conns) != 1 {
+		t.Errorf("Unexpected child %v", child)
+	}
+}
@@ -27,8 +27,15 @@ import (
 	"github.com/rqme/neat/network"
 )
 
-// Helper that decodes the genome into a neural network
-type Classic struct{}
+type ClassicSettings interface {
+	ActivationIterations() int // Iterations per activation of a recurrent network. If 0, calculated from the network
+}
+
+// Helper that decodes the genome into a neural network. Genomes with recurrent or self-looping
+// connections are decoded into a recurrent network.
+type Classic struct {
+	ClassicSettings
+}
 
 // Decodes the genome into a phenome
 func (d Classic) Decode(g neat.Genome) (p neat.Phenome, err error) {
@@ -58,12 +65,9 @@ func (d Classic) decode(g neat.Genome) (net neat.Network, err error) {
 	}
 
 	// Create the synapses
-	//forward := true // Keep track of conenctions to determine if this is a feed-forward only network
 	synapses := make([]network.Synapse, 0, len(conns))
 	for _, cg := range conns {
 		if cg.Enabled {
-			//src, tgt := nodes[nmap[cg.Source]], nodes[nmap[cg.Target]]
-			//forward = forward && src.Y < tgt.Y
 			synapses = append(synapses, network.Synapse{
 				Source: nmap[cg.Source],
 				Target: nmap[cg.Target],
@@ -72,11 +76,31 @@ func (d Classic) decode(g neat.Genome) (net neat.Network, err error) {
 		}
 	}
 
-	net, err = network.New(neurons, synapses)
+	// Networks without cycles, whatever the positions of their nodes, use the faster, compiled
+	// classic network
+	var c *network.Classic
+	if c, err = network.New(neurons, synapses); err != nil {
+		return
+	}
+	if c.FeedForward() {
+		net, err = network.Compile(c)
+		return
+	}
+
+	// Otherwise, create a recurrent network
+	var iters int
+	if d.ClassicSettings != nil {
+		iters = d.ActivationIterations()
+	}
+	if iters == 0 {
+		iters = calcIters(neurons, synapses)
+	}
+	net, err = network.NewRecurrent(neurons, synapses, iters)
 	return
 }
 
-// Removed recurrent functionality 2015-09-15 (BSH) to simplify and improve performance. Leaving this for now in case I bring it back.
+// Returns the number of iterations needed for a signal to pass through every layer of the network,
+// including the layers revisited by recurrent connections
 func calcIters(neurons []network.Neuron, synapses []network.Synapse) int {
 	a := make(map[float64]bool, 10)
 	b := make(map[float64]bool, 10)
@@ -0,0 +1,89 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package decoder
+
+import (
+	"testing"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/network"
+)
+
+// Returns a genome with an input, two hidden nodes in the same layer and an output. The input
+// feeds the first hidden node, which feeds the second and that feeds the output.
+func sameLayer(extra ...neat.Connection) neat.Genome {
+	g := neat.Genome{
+		Nodes: neat.Nodes{
+			1: {Innovation: 1, NeuronType: neat.Input, ActivationType: neat.Direct, Y: 0},
+			2: {Innovation: 2, NeuronType: neat.Hidden, ActivationType: neat.Direct, X: 0.75, Y: 0.5},
+			3: {Innovation: 3, NeuronType: neat.Hidden, ActivationType: neat.Direct, X: 0.25, Y: 0.5},
+			4: {Innovation: 4, NeuronType: neat.Output, ActivationType: neat.Direct, Y: 1},
+		},
+		Conns: neat.Connections{
+			5: {Innovation: 5, Source: 1, Target: 2, Weight: 2, Enabled: true},
+			6: {Innovation: 6, Source: 2, Target: 3, Weight: 3, Enabled: true},
+			7: {Innovation: 7, Source: 3, Target: 4, Weight: 0.5, Enabled: true},
+		},
+	}
+	for _, c := range extra {
+		g.Conns[c.Innovation] = c
+	}
+	return g
+}
+
+func TestClassicCompilesLinksWithinALayer(t *testing.T) {
+	p, err := Classic{}.Decode(sameLayer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(Phenome).Network.(*network.Compiled); !ok {
+		t.Fatalf("Expected a link between nodes in the same layer to be compiled, not %T", p.(Phenome).Network)
+	}
+	if out, err := p.Activate([]float64{1}); err != nil || out[0] != 3 {
+		t.Errorf("Expected output 3 but got %v (%v)", out, err)
+	}
+}
+
+func TestClassicDecodesCyclesAsRecurrent(t *testing.T) {
+	for name, c := range map[string]neat.Connection{
+		"cycle":     {Innovation: 8, Source: 3, Target: 2, Weight: 1, Enabled: true},
+		"self-loop": {Innovation: 8, Source: 2, Target: 2, Weight: 1, Enabled: true},
+	} {
+		p, err := Classic{}.Decode(sameLayer(c))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := p.(Phenome).Network.(*network.Recurrent); !ok {
+			t.Errorf("Expected a genome with a %s to decode as a recurrent network, not %T", name, p.(Phenome).Network)
+		}
+	}
+
+	// A disabled connection does not make the network recurrent
+	p, err := Classic{}.Decode(sameLayer(neat.Connection{Innovation: 8, Source: 3, Target: 2, Weight: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := p.(Phenome).Network.(*network.Compiled); !ok {
+		t.Errorf("Expected a genome whose cycle is disabled to be compiled, not %T", p.(Phenome).Network)
+	}
+}
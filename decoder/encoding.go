@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package decoder
+
+import (
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Names of the built-in CPPN inputs
+const (
+	PositionsInput = "positions" // Coordinates of the source followed by those of the target
+	DeltasInput    = "deltas"    // Difference between the target's and source's coordinates
+	DistanceInput  = "distance"  // Euclidean distance between the source and target
+	BiasInput      = "bias"      // Constant input of 1
+	DirectionInput = "direction" // 1 if the connection was found from its source, 0 if from its target
+)
+
+// CppnInput encodes part of the CPPN's inputs from the positions of a connection's source and
+// target nodes by appending values to the inputs. Outgoing is true if the connection was found
+// by searching from its source, as HyperNEAT does for every connection and ES-HyperNEAT does
+// for those leaving the inputs and hidden nodes, and false if it was found from its target.
+type CppnInput func(inputs, src, tgt []float64, outgoing bool) []float64
+
+// Parts of the CPPN's inputs, by name, which the HyperNEAT decoders' settings can choose from
+var (
+	cppnInputsMu sync.RWMutex
+	cppnInputs   = map[string]CppnInput{
+		PositionsInput: func(inputs, src, tgt []float64, outgoing bool) []float64 {
+			return append(append(inputs, src...), tgt...)
+		},
+		DeltasInput: func(inputs, src, tgt []float64, outgoing bool) []float64 {
+			for i := range src {
+				inputs = append(inputs, tgt[i]-src[i])
+			}
+			return inputs
+		},
+		DistanceInput: func(inputs, src, tgt []float64, outgoing bool) []float64 {
+			sum := 0.0
+			for i := range src {
+				sum += (tgt[i] - src[i]) * (tgt[i] - src[i])
+			}
+			return append(inputs, math.Sqrt(sum))
+		},
+		BiasInput: func(inputs, src, tgt []float64, outgoing bool) []float64 {
+			return append(inputs, 1.0)
+		},
+		DirectionInput: func(inputs, src, tgt []float64, outgoing bool) []float64 {
+			if outgoing {
+				return append(inputs, 1.0)
+			}
+			return append(inputs, 0.0)
+		},
+	}
+)
+
+// Registers a custom part of the CPPN's inputs under the name so that the decoders' settings can
+// choose it. Applications should register their encodings before decoding.
+func RegisterCppnInput(name string, fn CppnInput) error {
+	cppnInputsMu.Lock()
+	defer cppnInputsMu.Unlock()
+	if fn == nil {
+		return fmt.Errorf("decoder.RegisterCppnInput - No function provided for %s", name)
+	}
+	if _, ok := cppnInputs[name]; ok {
+		return fmt.Errorf("decoder.RegisterCppnInput - CPPN input %s is already registered", name)
+	}
+	cppnInputs[name] = fn
+	return nil
+}
+
+// Encodes the positions of a connection's source and target nodes as the CPPN's inputs
+type cppnEncoding []CppnInput
+
+// Returns the encoding made of the named inputs, in order. If there are no names, the defaults
+// are used.
+func newEncoding(names []string, defaults ...string) (cppnEncoding, error) {
+	if len(names) == 0 {
+		names = defaults
+	}
+	cppnInputsMu.RLock()
+	defer cppnInputsMu.RUnlock()
+	e := make(cppnEncoding, len(names))
+	for i, name := range names {
+		fn, ok := cppnInputs[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown CPPN input %q", name)
+		}
+		e[i] = fn
+	}
+	return e, nil
+}
+
+// Returns the CPPN's inputs for the connection from src to tgt
+func (e cppnEncoding) encode(src, tgt []float64, outgoing bool) []float64 {
+	inputs := make([]float64, 0, 2*len(src)+len(e))
+	for _, fn := range e {
+		inputs = fn(inputs, src, tgt, outgoing)
+	}
+	return inputs
+}
+
+// Returns the number of inputs the CPPN needs for positions with the dimensions
+func (e cppnEncoding) count(dims int) int {
+	p := make([]float64, dims)
+	return len(e.encode(p, p, true))
+}
@@ -0,0 +1,80 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package decoder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEncodingBuiltInInputs(t *testing.T) {
+	src, tgt := []float64{0, 1}, []float64{3, 5}
+	for _, c := range []struct {
+		names    []string
+		defaults []string
+		outgoing bool
+		want     []float64
+	}{
+		{nil, []string{PositionsInput}, true, []float64{0, 1, 3, 5}},
+		{nil, []string{PositionsInput, DirectionInput}, true, []float64{0, 1, 3, 5, 1}},
+		{nil, []string{PositionsInput, DirectionInput}, false, []float64{0, 1, 3, 5, 0}},
+		{[]string{DeltasInput, DistanceInput, BiasInput}, []string{PositionsInput}, true, []float64{3, 4, 5, 1}},
+	} {
+		e, err := newEncoding(c.names, c.defaults...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := e.encode(src, tgt, c.outgoing); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("Encoding %v (default %v) gave %v, expected %v", c.names, c.defaults, got, c.want)
+		}
+		if n := e.count(2); n != len(c.want) {
+			t.Errorf("Encoding %v (default %v) counts %d inputs, expected %d", c.names, c.defaults, n, len(c.want))
+		}
+	}
+	if _, err := newEncoding([]string{"unknown"}); err == nil {
+		t.Errorf("Expected an error for an unknown input")
+	}
+}
+
+func TestRegisterCppnInput(t *testing.T) {
+	fn := func(inputs, src, tgt []float64, outgoing bool) []float64 { return append(inputs, src[0]*tgt[0]) }
+	if err := RegisterCppnInput("test-product", fn); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterCppnInput("test-product", fn); err == nil {
+		t.Errorf("Expected an error registering an input twice")
+	}
+	if err := RegisterCppnInput(PositionsInput, fn); err == nil {
+		t.Errorf("Expected an error replacing a built-in input")
+	}
+	if err := RegisterCppnInput("test-nil", nil); err == nil {
+		t.Errorf("Expected an error registering no function")
+	}
+	e, err := newEncoding([]string{"test-product"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := e.encode([]float64{2}, []float64{3}, true); !reflect.DeepEqual(got, []float64{6}) {
+		t.Errorf("Registered input gave %v, expected [6]", got)
+	}
+}
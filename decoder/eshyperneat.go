@@ -23,7 +23,10 @@ THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package decoder
 
 import (
+	"fmt"
 	"math"
+	"strings"
+	"sync"
 
 	"github.com/rqme/neat"
 )
@@ -36,6 +39,8 @@ type ESHyperNEATSettings interface {
 	BandThreshold() float64
 	IterationLevels() int
 
+	// Of the HyperNEAT settings, only the substrate, weight range and CPPN inputs are used. The
+	// LEO, expression threshold, weight scaling, bias and activation settings must be left unset.
 	HyperNEATSettings
 }
 
@@ -43,35 +48,47 @@ type ESHyperNEAT struct {
 	ESHyperNEATSettings
 	CppnDecoder neat.Decoder
 
+	once sync.Once
 	dims int
 	divs [][]float64
+	trimmer
 }
 
 func NewESHyperNEAT(cfg ESHyperNEATSettings, dec neat.Decoder) *ESHyperNEAT {
-	d := &ESHyperNEAT{ESHyperNEATSettings: cfg, CppnDecoder: dec}
-
-	// Create the division factors
-	d.dims = len(d.SubstrateLayers()[0][0].Position)
-	k := int(math.Pow(2, float64(d.dims)))
-	d.divs = make([][]float64, k)
-	for i := 0; i < k; i++ {
-		d.divs[i] = make([]float64, d.dims)
-		for j := 0; j < d.dims; j++ {
-			x := int(math.Floor(float64(i)/math.Pow(2, float64(j)))) % 2
-			if x == 0 {
-				d.divs[i][j] = -1
-			} else {
-				d.divs[i][j] = 1
+	return &ESHyperNEAT{ESHyperNEATSettings: cfg, CppnDecoder: dec}
+}
+
+// Creates the division factors for the dimensions of the substrate's positions. This waits
+// until the first decode as the substrate may be read with the settings after the decoder is
+// created.
+func (d *ESHyperNEAT) divisions() error {
+	layers := d.SubstrateLayers()
+	if len(layers) < 2 || len(layers[0]) == 0 {
+		return fmt.Errorf("The substrate needs at least 2 layers and 1 input node")
+	}
+	d.once.Do(func() {
+		d.dims = len(layers[0][0].Position)
+		k := int(math.Pow(2, float64(d.dims)))
+		d.divs = make([][]float64, k)
+		for i := 0; i < k; i++ {
+			d.divs[i] = make([]float64, d.dims)
+			for j := 0; j < d.dims; j++ {
+				x := int(math.Floor(float64(i)/math.Pow(2, float64(j)))) % 2
+				if x == 0 {
+					d.divs[i][j] = -1
+				} else {
+					d.divs[i][j] = 1
+				}
 			}
 		}
-	}
-	return d
+	})
+	return nil
 }
 
 //
 //
 //
-func (d *ESHyperNEAT) divAndInit(cppn neat.Network, t int, a []float64, outgoing bool) (root *espoint, err error) {
+func (d *ESHyperNEAT) divAndInit(cppn neat.Network, enc cppnEncoding, t int, a []float64, outgoing bool) (root *espoint, err error) {
 	root = &espoint{
 		position: make([]float64, d.dims),
 		width:    1,
@@ -102,12 +119,7 @@ func (d *ESHyperNEAT) divAndInit(cppn neat.Network, t int, a []float64, outgoing
 		// Process the children
 		for _, c := range p.children {
 			var outputs []float64
-			if outgoing {
-				outputs, err = cppn.Activate(append(a, append(c.position, 1.0)...))
-			} else {
-				outputs, err = cppn.Activate(append(c.position, append(a, 0.0)...))
-			}
-			if err != nil {
+			if outputs, err = query(cppn, enc, a, c.position, outgoing); err != nil {
 				return
 			}
 			c.weight = outputs[t]
@@ -123,11 +135,11 @@ func (d *ESHyperNEAT) divAndInit(cppn neat.Network, t int, a []float64, outgoing
 	return
 }
 
-func (d *ESHyperNEAT) pruneAndExtract(cppn neat.Network, t int, a []float64, p *espoint, outgoing bool) (conns esconns, err error) {
+func (d *ESHyperNEAT) pruneAndExtract(cppn neat.Network, enc cppnEncoding, t int, a []float64, p *espoint, outgoing bool) (conns esconns, err error) {
 	for _, c := range p.children {
 		if variance(c) > d.VarianceThreshold() {
 			var con2 esconns
-			con2, err = d.pruneAndExtract(cppn, t, a, c, outgoing)
+			con2, err = d.pruneAndExtract(cppn, enc, t, a, c, outgoing)
 			if err != nil {
 				return
 			}
@@ -145,12 +157,7 @@ func (d *ESHyperNEAT) pruneAndExtract(cppn neat.Network, t int, a []float64, p *
 					} else {
 						c.position[i] += p.width
 					}
-					if outgoing {
-						outputs, err = cppn.Activate(append(a, append(c.position, 1.0)...))
-					} else {
-						outputs, err = cppn.Activate(append(c.position, append(a, 0.0)...))
-					}
-					if err != nil {
+					if outputs, err = query(cppn, enc, a, c.position, outgoing); err != nil {
 						return
 					}
 					if min > outputs[t] {
@@ -188,6 +195,10 @@ func (d *ESHyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 	if cppn, err = d.CppnDecoder.Decode(g); err != nil {
 		return
 	}
+	var enc cppnEncoding
+	if enc, err = d.validate(g); err != nil {
+		return
+	}
 
 	// Create a new substratre
 	s := &Substrate{
@@ -195,16 +206,18 @@ func (d *ESHyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 		Conns: make([]SubstrateConn, 0, 100),
 	}
 
-	// Create the hidden layer(s)
+	// Create the hidden layer(s), one for each iteration, between the inputs and outputs
 	layers := d.SubstrateLayers()
-	layers = append(layers[:1], append(make([]SubstrateNodes, d.IterationLevels()), layers[1:]...)...)
+	layers = append(layers[:1:1], append(make([]SubstrateNodes, d.IterationLevels()+1), layers[1:]...)...)
 
 	// Assign ids to the inputs
 	id := 0
 	for i := 0; i < len(layers[0]); i++ {
 		layers[0][i].id = id
 		id += 1
-		s.Nodes = append(s.Nodes, layers[0][i])
+		n := layers[0][i]
+		n.Layer = 0
+		s.Nodes = append(s.Nodes, n)
 	}
 
 	// Create hidden nodes and connections
@@ -217,17 +230,17 @@ func (d *ESHyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 
 			// Analyze the outgoing connectivity pattern form this input
 			var root *espoint
-			if root, err = d.divAndInit(cppn, t, inputs[i].Position, true); err != nil {
+			if root, err = d.divAndInit(cppn, enc, t, inputs[i].Position, true); err != nil {
 				return
 			}
 
 			// Traverse the tree and add conections to the list
 			var conns esconns
-			if conns, err = d.pruneAndExtract(cppn, t, inputs[i].Position, root, true); err != nil {
+			if conns, err = d.pruneAndExtract(cppn, enc, t, inputs[i].Position, root, true); err != nil {
 				return
 			}
 			for _, c := range conns {
-				n := SubstrateNode{Position: c.target, NeuronType: neat.Hidden}
+				n := SubstrateNode{Position: c.target, NeuronType: neat.Hidden, Layer: t + 1}
 				idx := hidden.IndexOf(n)
 				if idx == -1 {
 					n.id = id
@@ -250,7 +263,9 @@ func (d *ESHyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 		//outputs[i] = layers[len(layers)-1][i]
 		outputs[i].id = id
 		id += 1
-		s.Nodes = append(s.Nodes, outputs[i])
+		n := outputs[i]
+		n.Layer = len(layers) - 1
+		s.Nodes = append(s.Nodes, n)
 	}
 
 	// Output to hidden layer(s)
@@ -260,17 +275,17 @@ func (d *ESHyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 
 			// Analyze the outgoing connectivity pattern form this input
 			var root *espoint
-			if root, err = d.divAndInit(cppn, t, outputs[i].Position, false); err != nil {
+			if root, err = d.divAndInit(cppn, enc, t, outputs[i].Position, false); err != nil {
 				return
 			}
 
 			// Traverse the tree and add conections to the list
 			var conns esconns
-			if conns, err = d.pruneAndExtract(cppn, t, outputs[i].Position, root, false); err != nil {
+			if conns, err = d.pruneAndExtract(cppn, enc, t, outputs[i].Position, root, false); err != nil {
 				return
 			}
 			for _, c := range conns {
-				n := SubstrateNode{Position: c.target, NeuronType: neat.Hidden}
+				n := SubstrateNode{Position: c.source, NeuronType: neat.Hidden}
 				idx := hidden.IndexOf(n)
 				if idx == -1 {
 					// Ignore as it would create an unconnected node
@@ -282,6 +297,9 @@ func (d *ESHyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 		}
 	}
 
+	// Remove the hidden nodes which do not lie on a path from the inputs to the outputs
+	d.record(s.trim())
+
 	// Return the new network
 	var net neat.Network
 	net, err = s.Decode()
@@ -292,6 +310,15 @@ func (d *ESHyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 	return
 }
 
+// Activates the CPPN for the connection between a and the point p, from a if outgoing and to it
+// otherwise
+func query(cppn neat.Network, enc cppnEncoding, a, p []float64, outgoing bool) ([]float64, error) {
+	if outgoing {
+		return cppn.Activate(enc.encode(a, p, true))
+	}
+	return cppn.Activate(enc.encode(p, a, false))
+}
+
 type espoint struct {
 	position []float64
 	width    float64
@@ -333,3 +360,51 @@ func (e esconns) contains(c *esconn) bool {
 	}
 	return false
 }
+
+// Validates the CPPN's input and output counts against the encoding and iteration levels. The
+// HyperNEAT settings which ES-HyperNEAT does not support are rejected rather than ignored.
+func (d *ESHyperNEAT) validate(g neat.Genome) (enc cppnEncoding, err error) {
+	if err = d.divisions(); err != nil {
+		return
+	}
+	var unsupported []string
+	if d.LinkExpressionOutput() {
+		unsupported = append(unsupported, "LinkExpressionOutput")
+	}
+	if d.ExpressionThreshold() != 0 {
+		unsupported = append(unsupported, "ExpressionThreshold")
+	}
+	if d.WeightScaling() != "" {
+		unsupported = append(unsupported, "WeightScaling")
+	}
+	if d.BiasOutputs() {
+		unsupported = append(unsupported, "BiasOutputs")
+	}
+	if len(d.NodeActivations()) > 0 {
+		unsupported = append(unsupported, "NodeActivations")
+	}
+	if len(unsupported) > 0 {
+		err = fmt.Errorf("ES-HyperNEAT does not support the HyperNEAT settings %s", strings.Join(unsupported, ", "))
+		return
+	}
+
+	var icnt, ocnt int
+	for _, n := range g.Nodes {
+		if n.NeuronType == neat.Input {
+			icnt += 1
+		} else if n.NeuronType == neat.Output {
+			ocnt += 1
+		}
+	}
+	if enc, err = newEncoding(d.CppnInputs(), PositionsInput, DirectionInput); err != nil {
+		return
+	}
+	if need := enc.count(d.dims); icnt < need {
+		err = fmt.Errorf("Insufficient number of inputs to decode substrate. Need %d but have %d", need, icnt)
+		return
+	}
+	if need := d.IterationLevels() + 1; ocnt < need {
+		err = fmt.Errorf("Insufficient number of outputs to decode substrate. Need %d but have %d", need, ocnt)
+	}
+	return
+}
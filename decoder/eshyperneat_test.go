@@ -0,0 +1,95 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package decoder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+type esSettings struct {
+	ESHyperNEATSettings
+	leo, bias bool
+	threshold float64
+	scaling   string
+	acts      []neat.ActivationType
+}
+
+func (s esSettings) SubstrateLayers() []SubstrateNodes {
+	return []SubstrateNodes{
+		{{Position: []float64{0, -1}, NeuronType: neat.Input}},
+		{{Position: []float64{0, 1}, NeuronType: neat.Output}},
+	}
+}
+func (s esSettings) CppnInputs() []string                   { return nil }
+func (s esSettings) IterationLevels() int                   { return 1 }
+func (s esSettings) LinkExpressionOutput() bool             { return s.leo }
+func (s esSettings) ExpressionThreshold() float64           { return s.threshold }
+func (s esSettings) WeightScaling() string                  { return s.scaling }
+func (s esSettings) BiasOutputs() bool                      { return s.bias }
+func (s esSettings) NodeActivations() []neat.ActivationType { return s.acts }
+
+// Returns a CPPN genome with the number of inputs and outputs
+func cppnGenome(inputs, outputs int) neat.Genome {
+	g := neat.Genome{Nodes: make(neat.Nodes)}
+	for i := 0; i < inputs+outputs; i++ {
+		t := neat.Input
+		if i >= inputs {
+			t = neat.Output
+		}
+		g.Nodes[i] = neat.Node{Innovation: i, NeuronType: t, ActivationType: neat.Tanh}
+	}
+	return g
+}
+
+func TestESHyperNEATRejectsUnsupportedSettings(t *testing.T) {
+	g := cppnGenome(5, 2)
+	if _, err := NewESHyperNEAT(esSettings{}, nil).validate(g); err != nil {
+		t.Fatalf("Expected the CPPN to be valid but got %v", err)
+	}
+	for name, s := range map[string]esSettings{
+		"LinkExpressionOutput": {leo: true},
+		"ExpressionThreshold":  {threshold: 0.3},
+		"WeightScaling":        {scaling: LinearScaling},
+		"BiasOutputs":          {bias: true},
+		"NodeActivations":      {acts: []neat.ActivationType{neat.Sigmoid}},
+	} {
+		_, err := NewESHyperNEAT(s, nil).validate(g)
+		if err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected %s to be rejected but got %v", name, err)
+		}
+	}
+}
+
+type noLayers struct{ esSettings }
+
+func (s noLayers) SubstrateLayers() []SubstrateNodes { return nil }
+
+func TestESHyperNEATWaitsForSubstrate(t *testing.T) {
+	d := NewESHyperNEAT(noLayers{}, nil)
+	if _, err := d.validate(cppnGenome(5, 2)); err == nil {
+		t.Errorf("Expected an error validating without a substrate")
+	}
+}
@@ -0,0 +1,52 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package decoder
+
+import (
+	"fmt"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/network"
+)
+
+// Returns the interchange form of the phenome's network so that it can be written as DOT, JSON or
+// Go source
+func Interchange(p neat.Phenome) (*network.Interchange, error) {
+	if x, ok := p.(Phenome); ok {
+		return network.NewInterchange(x.Network)
+	}
+	if x, ok := p.(*Phenome); ok {
+		return network.NewInterchange(x.Network)
+	}
+	return nil, fmt.Errorf("decoder.Interchange - Phenome %d was not created by this package", p.ID())
+}
+
+// Returns the interchange form of the substrate's network. The neurons keep the full positions of
+// their nodes.
+func (s Substrate) Interchange() (*network.Interchange, error) {
+	net, err := s.Decode()
+	if err != nil {
+		return nil, err
+	}
+	return network.NewInterchange(net)
+}
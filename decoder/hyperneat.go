@@ -32,21 +32,120 @@ import (
 // Special case: 1 layer of nodes in this case, examine nodes to separate out "vitural layers" by neuron type
 // othewise connect every neuron in one layer to the subsequent layer
 
+const (
+	DefaultExpressionThreshold = 0.2 // Threshold of the weight output when there is no LEO
+
+	ThresholdScaling = "threshold" // Weights are scaled from the expression threshold to the weight range
+	LinearScaling    = "linear"    // Weights are the weight output times the weight range
+)
+
 type HyperNEATSettings interface {
 	SubstrateLayers() []SubstrateNodes // Substrate definitions
 	WeightRange() float64              // Weight range for new connections
+
+	// Names of the parts of the CPPN's inputs, in order, from those built in or registered with
+	// RegisterCppnInput. If empty, the inputs are the positions of the source and target and, for
+	// ES-HyperNEAT, the direction in which the connection was found.
+	CppnInputs() []string
+
+	// Use a separate link expression output (LEO) for each pair of layers to decide if a
+	// connection is expressed instead of the magnitude of its weight
+	LinkExpressionOutput() bool
+
+	// Threshold the weight's magnitude, or the LEO, must exceed for a connection to be expressed. If
+	// 0, DefaultExpressionThreshold is used without LEO and 0 with it.
+	ExpressionThreshold() float64
+
+	// Scaling of the weight output, threshold or linear. If empty, weights are scaled from the
+	// threshold without LEO and linearly with it.
+	WeightScaling() string
+
+	// Use an output for each layer after the first to set the bias of the layer's nodes
+	BiasOutputs() bool
+
+	// Activations from which an output for each layer after the first selects those of the
+	// layer's nodes. If empty, the nodes use the sigmoid activation.
+	NodeActivations() []neat.ActivationType
 }
 
 type HyperNEAT struct {
 	HyperNEATSettings
 	CppnDecoder neat.Decoder
+	trimmer
+}
+
+// Indexes of the first CPPN output of each group. Each group has one output per pair of
+// consecutive layers, starting with the weights and followed by those the settings enable, in
+// this order: LEO, bias and activation. Unused groups have a negative index.
+type cppnOutputs struct {
+	leo, bias, act int
+	count          int // Number of outputs required
+}
+
+func (d *HyperNEAT) outputs() (o cppnOutputs) {
+	n := len(d.SubstrateLayers()) - 1
+	o = cppnOutputs{leo: -1, bias: -1, act: -1, count: n}
+	if d.LinkExpressionOutput() {
+		o.leo, o.count = o.count, o.count+n
+	}
+	if d.BiasOutputs() {
+		o.bias, o.count = o.count, o.count+n
+	}
+	if len(d.NodeActivations()) > 0 {
+		o.act, o.count = o.count, o.count+n
+	}
+	return
+}
+
+// Returns the expression threshold
+func (d *HyperNEAT) threshold() float64 {
+	if t := d.ExpressionThreshold(); t > 0 {
+		return t
+	}
+	if d.LinkExpressionOutput() {
+		return 0
+	}
+	return DefaultExpressionThreshold
+}
+
+// Returns the weight scaling
+func (d *HyperNEAT) scaling() string {
+	if s := d.WeightScaling(); s != "" {
+		return s
+	}
+	if d.LinkExpressionOutput() {
+		return LinearScaling
+	}
+	return ThresholdScaling
+}
+
+// Returns the weight of the connection between the pair of layers and whether it is expressed
+func (d *HyperNEAT) express(outputs []float64, o cppnOutputs, pair int) (float64, bool) {
+	w, t := outputs[pair], d.threshold()
+	if o.leo >= 0 {
+		if outputs[o.leo+pair] <= t {
+			return 0, false
+		}
+	} else if math.Abs(w) <= t {
+		return 0, false
+	}
+	if d.scaling() == ThresholdScaling {
+		return math.Copysign((math.Abs(w)-t)*d.WeightRange()/(1-t), w), true
+	}
+	return w * d.WeightRange(), true
 }
 
-// Outputs 0..len(substrate layers) = weights. if len(outputs) = 2* that number, second set is activation function, 3rd is bias connection? Need flags for these
-// n = number of layers - 1
-// first n = weights
-// flags for bias oututs = 1 or 2 meaning use outputs starting at 1*n or 2*n
-// activation, too.
+// Returns the activation selected by the output, dividing [-1, 1] evenly among the activations
+func selectActivation(acts []neat.ActivationType, x float64) neat.ActivationType {
+	if math.IsNaN(x) {
+		x = 0
+	}
+	i := int((math.Max(-1, math.Min(1, x)) + 1) / 2 * float64(len(acts)))
+	if i >= len(acts) {
+		i = len(acts) - 1
+	}
+	return acts[i]
+}
 
 func (d *HyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 	// Validate the number of inputs and outputs
@@ -76,10 +175,11 @@ func (d *HyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 
 	// Add the nodes to the substrate
 	i := 0
-	for _, l := range layers {
-		// TODO: Should I sort the nodes by position in the network?
-		for j, n := range l {
+	for k, l := range layers {
+		for j := range l {
 			l[j].id = i
+			n := l[j]
+			n.Layer = k
 			s.Nodes = append(s.Nodes, n)
 			i += 1
 		}
@@ -87,26 +187,58 @@ func (d *HyperNEAT) Decode(g neat.Genome) (p neat.Phenome, err error) {
 
 	// Create connections
 	var outputs []float64 // output from the Cppn
-	wr := d.WeightRange()
+	o := d.outputs()
+	enc, err := newEncoding(d.CppnInputs(), PositionsInput)
+	if err != nil {
+		return nil, err
+	}
 	for l := 1; l < len(layers); l++ {
 		for _, src := range layers[l-1] {
 			for _, tgt := range layers[l] {
-				outputs, err = cppn.Activate(append(src.Position, tgt.Position...))
+				outputs, err = cppn.Activate(enc.encode(src.Position, tgt.Position, true))
 				if err != nil {
 					return nil, err
 				}
-				w := math.Abs(outputs[l-1])
-				if w > 0.2 {
+				if w, ok := d.express(outputs, o, l-1); ok {
 					s.Conns = append(s.Conns, SubstrateConn{
 						Source: src.id,
 						Target: tgt.id,
-						Weight: math.Copysign((w-0.2)*wr/0.8, outputs[l-1]),
+						Weight: w,
 					})
 				}
 			}
 		}
 	}
 
+	// Set the bias and activation of each node after the first layer by querying the CPPN from the
+	// origin to the node. Biases are the weights of connections from a bias node at the origin.
+	if o.bias >= 0 || o.act >= 0 {
+		origin := make([]float64, len(layers[0][0].Position))
+		bias := SubstrateNode{id: i, Position: origin, NeuronType: neat.Bias}
+		acts := d.NodeActivations()
+		for j := len(layers[0]); j < i; j++ {
+			sn := &s.Nodes[j]
+			outputs, err = cppn.Activate(enc.encode(origin, sn.Position, true))
+			if err != nil {
+				return nil, err
+			}
+			if o.bias >= 0 {
+				if w := outputs[o.bias+sn.Layer-1] * d.WeightRange(); w != 0 {
+					s.Conns = append(s.Conns, SubstrateConn{Source: bias.id, Target: sn.id, Weight: w})
+				}
+			}
+			if o.act >= 0 {
+				sn.Activation = selectActivation(acts, outputs[o.act+sn.Layer-1])
+			}
+		}
+		if o.bias >= 0 {
+			s.Nodes = append(s.Nodes, bias)
+		}
+	}
+
+	// Remove the parts of the substrate which cannot affect the outputs
+	d.record(s.trim())
+
 	// Return the new network
 	var net neat.Network
 	net, err = s.Decode()
@@ -139,6 +271,9 @@ func (d *HyperNEAT) validate(g neat.Genome) error {
 	}
 
 	layers := d.SubstrateLayers()
+	if len(layers) < 2 || len(layers[0]) == 0 {
+		return fmt.Errorf("The substrate needs at least 2 layers and 1 input node")
+	}
 	cnt := len(layers[0][0].Position)
 	for i, l := range layers {
 		for j, n := range l {
@@ -147,12 +282,36 @@ func (d *HyperNEAT) validate(g neat.Genome) error {
 			}
 		}
 	}
-	if cnt*2 < icnt {
-		return fmt.Errorf("Insufficient number of inputs to decode substrate. Need %d but have %d", cnt*2, icnt)
+	enc, err := newEncoding(d.CppnInputs(), PositionsInput)
+	if err != nil {
+		return err
+	}
+	if need := enc.count(cnt); icnt < need {
+		return fmt.Errorf("Insufficient number of inputs to decode substrate. Need %d but have %d", need, icnt)
 	}
 
-	if ocnt < len(layers)-1 {
-		return fmt.Errorf("Insufficient number of outputs to decode substrate. Need %d but have %d", len(layers)-1, ocnt)
+	o := d.outputs()
+	if ocnt < o.count {
+		return fmt.Errorf("Insufficient number of outputs to decode substrate. Need %d but have %d", o.count, ocnt)
+	}
+
+	switch d.scaling() {
+	case ThresholdScaling:
+		if o.leo >= 0 {
+			return fmt.Errorf("Threshold weight scaling cannot be used with link expression outputs")
+		}
+		if d.threshold() >= 1 {
+			return fmt.Errorf("Expression threshold %f must be less than 1 for threshold weight scaling", d.threshold())
+		}
+	case LinearScaling:
+	default:
+		return fmt.Errorf("Unknown weight scaling %q", d.WeightScaling())
+	}
+
+	for i, a := range d.NodeActivations() {
+		if _, ok := a.Func(); !ok {
+			return fmt.Errorf("Unknown activation %d for substrate nodes at index %d", byte(a), i)
+		}
 	}
 
 	return nil
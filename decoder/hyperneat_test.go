@@ -0,0 +1,234 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package decoder
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/network"
+)
+
+type hnSettings struct {
+	HyperNEATSettings
+	leo, bias bool
+	threshold float64
+	scaling   string
+	acts      []neat.ActivationType
+}
+
+// Returns a substrate of two inputs, a hidden node and an output in separate layers
+func (s hnSettings) SubstrateLayers() []SubstrateNodes {
+	return []SubstrateNodes{
+		{{Position: []float64{-1, -1}, NeuronType: neat.Input}, {Position: []float64{1, -1}, NeuronType: neat.Input}},
+		{{Position: []float64{0.5, 0}, NeuronType: neat.Hidden}},
+		{{Position: []float64{0, 1}, NeuronType: neat.Output}},
+	}
+}
+func (s hnSettings) WeightRange() float64                   { return 3 }
+func (s hnSettings) CppnInputs() []string                   { return nil }
+func (s hnSettings) LinkExpressionOutput() bool             { return s.leo }
+func (s hnSettings) ExpressionThreshold() float64           { return s.threshold }
+func (s hnSettings) WeightScaling() string                  { return s.scaling }
+func (s hnSettings) BiasOutputs() bool                      { return s.bias }
+func (s hnSettings) NodeActivations() []neat.ActivationType { return s.acts }
+
+// CPPN returning fixed outputs for each of its expected inputs. It decodes every genome into
+// itself.
+type stubCppn map[string][]float64
+
+func (c stubCppn) Decode(g neat.Genome) (neat.Phenome, error) { return Phenome{g, c}, nil }
+
+func (c stubCppn) Activate(inputs []float64) ([]float64, error) {
+	if outputs, ok := c[fmt.Sprint(inputs)]; ok {
+		return outputs, nil
+	}
+	return nil, fmt.Errorf("Unexpected CPPN inputs %v", inputs)
+}
+
+// Inputs of the CPPN's queries: the connections from each input to the hidden node and from the
+// hidden node to the output, and those from the origin to the hidden node and the output
+const (
+	qA = "[-1 -1 0.5 0]"
+	qB = "[1 -1 0.5 0]"
+	qH = "[0.5 0 0 1]"
+	bH = "[0 0 0.5 0]"
+	bO = "[0 0 0 1]"
+)
+
+func TestHyperNEATDecode(t *testing.T) {
+	var cases = []struct {
+		Desc     string
+		Settings hnSettings
+		Cppn     stubCppn
+		Synapses map[string]float64             // Weights of the synapses by their neurons' positions
+		Acts     map[string]neat.ActivationType // Activations of the neurons by their positions
+	}{
+		{
+			Desc: "threshold scaling", Settings: hnSettings{},
+			Cppn:     stubCppn{qA: {0.1, 0}, qB: {-0.6, 0}, qH: {0, 0.7}},
+			Synapses: map[string]float64{"[1 -1]->[0.5 0]": -1.5, "[0.5 0]->[0 1]": 1.875},
+			Acts:     map[string]neat.ActivationType{"[1 -1]": neat.Direct, "[0.5 0]": neat.Sigmoid, "[0 1]": neat.Sigmoid},
+		},
+		{
+			Desc: "linear scaling", Settings: hnSettings{scaling: LinearScaling},
+			Cppn:     stubCppn{qA: {0.1, 0}, qB: {-0.6, 0}, qH: {0, 0.7}},
+			Synapses: map[string]float64{"[1 -1]->[0.5 0]": -1.8, "[0.5 0]->[0 1]": 2.1},
+		},
+		{
+			Desc: "custom threshold", Settings: hnSettings{threshold: 0.5},
+			Cppn:     stubCppn{qA: {0.1, 0}, qB: {-0.6, 0}, qH: {0, 0.7}},
+			Synapses: map[string]float64{"[1 -1]->[0.5 0]": -0.6, "[0.5 0]->[0 1]": 1.2},
+		},
+		{
+			Desc: "weight at threshold", Settings: hnSettings{threshold: 0.6, scaling: LinearScaling},
+			Cppn:     stubCppn{qA: {0.1, 0}, qB: {-0.6, 0}, qH: {0, 0.7}},
+			Synapses: map[string]float64{},
+		},
+		{
+			Desc: "link expression output", Settings: hnSettings{leo: true},
+			Cppn:     stubCppn{qA: {0.1, 0, 1, 0}, qB: {-0.6, 0, -0.5, 0}, qH: {0, -0.4, 0, 0.5}},
+			Synapses: map[string]float64{"[-1 -1]->[0.5 0]": 0.3, "[0.5 0]->[0 1]": -1.2},
+		},
+		{
+			Desc: "link expression output threshold", Settings: hnSettings{leo: true, threshold: 0.75},
+			Cppn:     stubCppn{qA: {0.1, 0, 1, 0}, qB: {-0.6, 0, 0.7, 0}, qH: {0, -0.4, 0, 0.8}},
+			Synapses: map[string]float64{"[-1 -1]->[0.5 0]": 0.3, "[0.5 0]->[0 1]": -1.2},
+		},
+		{
+			Desc: "bias outputs", Settings: hnSettings{bias: true},
+			Cppn: stubCppn{qA: {0.5, 0, 0, 0}, qB: {0, 0, 0, 0}, qH: {0, 0.5, 0, 0},
+				bH: {0, 0, 0.25, 0}, bO: {0, 0, 0, -0.5}},
+			Synapses: map[string]float64{"[-1 -1]->[0.5 0]": 1.125, "[0.5 0]->[0 1]": 1.125,
+				"[0 0]->[0.5 0]": 0.75, "[0 0]->[0 1]": -1.5},
+			Acts: map[string]neat.ActivationType{"[0 0]": neat.Direct},
+		},
+		{
+			Desc: "zero bias", Settings: hnSettings{bias: true},
+			Cppn: stubCppn{qA: {0.5, 0, 0, 0}, qB: {0, 0, 0, 0}, qH: {0, 0.5, 0, 0},
+				bH: {0, 0, 0, 0}, bO: {0, 0, 0, -0.5}},
+			Synapses: map[string]float64{"[-1 -1]->[0.5 0]": 1.125, "[0.5 0]->[0 1]": 1.125, "[0 0]->[0 1]": -1.5},
+		},
+		{
+			Desc: "activations", Settings: hnSettings{acts: []neat.ActivationType{neat.Sigmoid, neat.Tanh, neat.ReLU}},
+			Cppn: stubCppn{qA: {0.5, 0, 0, 0}, qB: {0, 0, 0, 0}, qH: {0, 0.5, 0, 0},
+				bH: {0, 0, -1, 0}, bO: {0, 0, 0, 0.9}},
+			Synapses: map[string]float64{"[-1 -1]->[0.5 0]": 1.125, "[0.5 0]->[0 1]": 1.125},
+			Acts:     map[string]neat.ActivationType{"[-1 -1]": neat.Direct, "[0.5 0]": neat.Sigmoid, "[0 1]": neat.ReLU},
+		},
+		{
+			Desc: "all outputs", Settings: hnSettings{leo: true, bias: true, acts: []neat.ActivationType{neat.Sigmoid, neat.Tanh}},
+			Cppn: stubCppn{qA: {0.5, 0, 0.1, 0, 0, 0, 0, 0}, qB: {0.5, 0, 0, 0, 0, 0, 0, 0}, qH: {0, 0.5, 0, 0.1, 0, 0, 0, 0},
+				bH: {0, 0, 0, 0, 0.25, 0, 0.5, 0}, bO: {0, 0, 0, 0, 0, -0.5, 0, -0.5}},
+			Synapses: map[string]float64{"[-1 -1]->[0.5 0]": 1.5, "[0.5 0]->[0 1]": 1.5,
+				"[0 0]->[0.5 0]": 0.75, "[0 0]->[0 1]": -1.5},
+			Acts: map[string]neat.ActivationType{"[0 0]": neat.Direct, "[0.5 0]": neat.Tanh, "[0 1]": neat.Sigmoid},
+		},
+	}
+	for _, c := range cases {
+		d := &HyperNEAT{HyperNEATSettings: c.Settings, CppnDecoder: c.Cppn}
+		p, err := d.Decode(cppnGenome(4, d.outputs().count))
+		if err != nil {
+			t.Errorf("Case %s: Could not decode: %v", c.Desc, err)
+			continue
+		}
+		net := p.(Phenome).Network.(*network.Compiled)
+
+		// Compare the synapses
+		synapses := make(map[string]float64, len(net.Synapses))
+		for _, s := range net.Synapses {
+			synapses[fmt.Sprint(net.Neurons[s.Source].Position)+"->"+fmt.Sprint(net.Neurons[s.Target].Position)] = s.Weight
+		}
+		if len(synapses) != len(c.Synapses) {
+			t.Errorf("Case %s: Expected synapses %v. Actual %v", c.Desc, c.Synapses, synapses)
+			continue
+		}
+		for k, w := range c.Synapses {
+			if a, ok := synapses[k]; !ok || math.Abs(a-w) > 1e-9 {
+				t.Errorf("Case %s: Expected synapse %s with weight %f. Actual %v", c.Desc, k, w, synapses)
+			}
+		}
+
+		// Compare the activations
+		acts := make(map[string]neat.ActivationType, len(net.Neurons))
+		for _, n := range net.Neurons {
+			acts[fmt.Sprint(n.Position)] = n.ActivationType
+		}
+		for k, a := range c.Acts {
+			if acts[k] != a {
+				t.Errorf("Case %s: Expected neuron at %s to use %v. Actual %v", c.Desc, k, a, acts[k])
+			}
+		}
+	}
+}
+
+func TestHyperNEATRejectsSettings(t *testing.T) {
+	var cases = []struct {
+		Desc     string
+		Settings hnSettings
+		Error    string
+	}{
+		{"threshold scaling with LEO", hnSettings{leo: true, scaling: ThresholdScaling}, "Threshold weight scaling"},
+		{"threshold of 1", hnSettings{threshold: 1}, "must be less than 1"},
+		{"unknown scaling", hnSettings{scaling: "log"}, "Unknown weight scaling"},
+		{"unknown activation", hnSettings{acts: []neat.ActivationType{0}}, "Unknown activation"},
+	}
+	for _, c := range cases {
+		d := &HyperNEAT{HyperNEATSettings: c.Settings, CppnDecoder: stubCppn{}}
+		if err := d.validate(cppnGenome(4, d.outputs().count)); err == nil || !strings.Contains(err.Error(), c.Error) {
+			t.Errorf("Case %s: Expected error containing %q. Actual %v", c.Desc, c.Error, err)
+		}
+	}
+
+	// Too few outputs for the LEO
+	d := &HyperNEAT{HyperNEATSettings: hnSettings{leo: true}, CppnDecoder: stubCppn{}}
+	if err := d.validate(cppnGenome(4, 2)); err == nil || !strings.Contains(err.Error(), "Need 4 but have 2") {
+		t.Errorf("Expected too few outputs. Actual %v", err)
+	}
+}
+
+func TestSelectActivation(t *testing.T) {
+	acts := []neat.ActivationType{neat.Sigmoid, neat.Tanh, neat.ReLU}
+	var cases = []struct {
+		X        float64
+		Expected neat.ActivationType
+	}{
+		{-5, neat.Sigmoid},
+		{-1, neat.Sigmoid},
+		{-0.4, neat.Sigmoid},
+		{-0.3, neat.Tanh},
+		{0, neat.Tanh},
+		{math.NaN(), neat.Tanh},
+		{0.4, neat.ReLU},
+		{1, neat.ReLU},
+		{5, neat.ReLU},
+	}
+	for _, c := range cases {
+		if a := selectActivation(acts, c.X); a != c.Expected {
+			t.Errorf("Output %f: Expected %v. Actual %v", c.X, c.Expected, a)
+		}
+	}
+}
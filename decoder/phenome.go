@@ -45,3 +45,21 @@ func (p Phenome) Traits() []float64 { return p.Genome.Traits }
 func (p Phenome) Activate(inputs []float64) (outputs []float64, err error) {
 	return p.Network.Activate(inputs)
 }
+
+// Writes the results of processing the inputs with the neural network into outputs. If the network
+// cannot activate into the slice, the outputs are copied from a regular activation.
+func (p Phenome) ActivateInto(inputs, outputs []float64) error {
+	if an, ok := p.Network.(neat.IntoActivatable); ok {
+		return an.ActivateInto(inputs, outputs)
+	}
+	x, err := p.Network.Activate(inputs)
+	copy(outputs, x)
+	return err
+}
+
+// Clears the state of the network, if any
+func (p Phenome) Reset() {
+	if rn, ok := p.Network.(neat.Resettable); ok {
+		rn.Reset()
+	}
+}
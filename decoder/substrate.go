@@ -25,6 +25,9 @@ package decoder
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"sort"
+	"sync"
 
 	"github.com/rqme/neat"
 	"github.com/rqme/neat/network"
@@ -34,6 +37,8 @@ type SubstrateNode struct {
 	id       int // internal ID of node in substrate
 	Position []float64
 	neat.NeuronType
+	Layer      int                 // Index of the substrate layer containing the node
+	Activation neat.ActivationType // Activation of the node. If 0, inputs and biases are direct and others sigmoid
 }
 
 func (n SubstrateNode) String() string {
@@ -90,17 +95,36 @@ func (s Substrate) String() string {
 	}
 	return b.String()
 }
+
+// Decodes the substrate into a network. The neurons are ordered by type, as the network requires,
+// and then by substrate layer so that hidden neurons are activated layer by layer. Each neuron
+// keeps the full position of its node. Its 2D hint places it across the network by the node's
+// first coordinate and up the network by its layer or, if the substrate has a single layer, by
+// its second coordinate.
 func (s Substrate) Decode() (neat.Network, error) {
 
+	// Order the nodes
+	nodes := make(SubstrateNodes, len(s.Nodes))
+	copy(nodes, s.Nodes)
+	sort.SliceStable(nodes, func(i, j int) bool {
+		if nodes[i].NeuronType != nodes[j].NeuronType {
+			return nodes[i].NeuronType < nodes[j].NeuronType
+		}
+		return nodes[i].Layer < nodes[j].Layer
+	})
+	xs, ys := nodes.hints()
+
 	// Create neurons from the nodes
-	ns := make([]network.Neuron, len(s.Nodes))
-	nm := make(map[int]int, len(s.Nodes))
-	for i, sn := range s.Nodes {
+	ns := make([]network.Neuron, len(nodes))
+	nm := make(map[int]int, len(nodes))
+	for i, sn := range nodes {
 		nm[sn.id] = i
-		ns[i] = network.Neuron{NeuronType: sn.NeuronType}
-		ns[i].X, ns[i].Y = sn.Position[0], sn.Position[1] // TODO: Improve this as all layers will be collapsed
-		switch sn.NeuronType {
-		case neat.Input, neat.Bias:
+		ns[i] = network.Neuron{NeuronType: sn.NeuronType, X: xs[i], Y: ys[i]}
+		ns[i].Position = append([]float64(nil), sn.Position...)
+		switch {
+		case sn.Activation != 0:
+			ns[i].ActivationType = sn.Activation
+		case sn.NeuronType == neat.Input, sn.NeuronType == neat.Bias:
 			ns[i].ActivationType = neat.Direct
 		default:
 			ns[i].ActivationType = neat.Sigmoid
@@ -117,13 +141,180 @@ func (s Substrate) Decode() (neat.Network, error) {
 		}
 	}
 
-	// Return the new network
-	return network.New(ns, cs)
+	// Return the new, compiled network
+	net, err := network.New(ns, cs)
+	if err != nil {
+		return nil, err
+	}
+	return network.Compile(net)
+}
+
+// Returns the 2D hints of the nodes' positions, each scaled into [0, 1]
+func (s SubstrateNodes) hints() (xs, ys []float64) {
+	xs = make([]float64, len(s))
+	ys = make([]float64, len(s))
+	layered := false
+	for i, n := range s {
+		if len(n.Position) > 0 {
+			xs[i] = n.Position[0]
+		}
+		ys[i] = float64(n.Layer)
+		layered = layered || n.Layer != s[0].Layer
+	}
+	if !layered {
+		for i, n := range s {
+			if len(n.Position) > 1 {
+				ys[i] = n.Position[1]
+			}
+		}
+	}
+	scale(xs)
+	scale(ys)
+	return
+}
+
+// Scales the values into [0, 1]. If the values are the same, they are centred.
+func scale(xs []float64) {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, x := range xs {
+		lo, hi = math.Min(lo, x), math.Max(hi, x)
+	}
+	for i, x := range xs {
+		if hi > lo {
+			xs[i] = (x - lo) / (hi - lo)
+		} else {
+			xs[i] = 0.5
+		}
+	}
+}
+
+// Statistics of the nodes and connections trimmed from substrates
+type TrimStats struct {
+	Substrates   int // Number of substrates trimmed
+	Nodes        int // Nodes before trimming
+	Conns        int // Connections before trimming
+	TrimmedNodes int // Hidden nodes removed
+	TrimmedConns int // Connections removed
+}
+
+// Returns the statistics with those of another set added
+func (t TrimStats) Add(o TrimStats) TrimStats {
+	t.Substrates += o.Substrates
+	t.Nodes += o.Nodes
+	t.Conns += o.Conns
+	t.TrimmedNodes += o.TrimmedNodes
+	t.TrimmedConns += o.TrimmedConns
+	return t
+}
+
+// Returns the statistics less those of another set, such as an earlier copy of the same totals
+func (t TrimStats) Sub(o TrimStats) TrimStats {
+	t.Substrates -= o.Substrates
+	t.Nodes -= o.Nodes
+	t.Conns -= o.Conns
+	t.TrimmedNodes -= o.TrimmedNodes
+	t.TrimmedConns -= o.TrimmedConns
+	return t
+}
+
+// Returns the fractions of the nodes and connections which were trimmed
+func (t TrimStats) Fractions() (nodes, conns float64) {
+	if t.Nodes > 0 {
+		nodes = float64(t.TrimmedNodes) / float64(t.Nodes)
+	}
+	if t.Conns > 0 {
+		conns = float64(t.TrimmedConns) / float64(t.Conns)
+	}
+	return
+}
+
+func (t TrimStats) String() string {
+	n, c := t.Fractions()
+	return fmt.Sprintf("Trimmed %d of %d nodes (%.1f%%) and %d of %d connections (%.1f%%) from %d substrates",
+		t.TrimmedNodes, t.Nodes, n*100, t.TrimmedConns, t.Conns, c*100, t.Substrates)
+}
+
+// Totals the statistics of the substrates trimmed by a decoder, which may decode concurrently
+type trimmer struct {
+	sync.Mutex
+	stats TrimStats
+}
+
+// Returns the statistics of the substrates trimmed so far
+func (t *trimmer) TrimStats() TrimStats {
+	t.Lock()
+	defer t.Unlock()
+	return t.stats
+}
+
+func (t *trimmer) record(s TrimStats) {
+	t.Lock()
+	t.stats = t.stats.Add(s)
+	t.Unlock()
 }
 
 // Trims the substrate of connections and hidden nodes that are not part of a valid path from
-// input to outpout
-func (s *Substrate) trim() {
+// input to output. A hidden node is kept only if it can be reached from an input or bias node and
+// an output can be reached from it. Inputs, biases and outputs are always kept. Removing hidden
+// nodes which no input reaches drops the constant they would otherwise feed forward.
+func (s *Substrate) trim() (ts TrimStats) {
+	ts = TrimStats{Substrates: 1, Nodes: len(s.Nodes), Conns: len(s.Conns)}
+
+	// Index the connections by node
+	outgoing := make(map[int][]int, len(s.Nodes))
+	incoming := make(map[int][]int, len(s.Nodes))
+	for _, c := range s.Conns {
+		outgoing[c.Source] = append(outgoing[c.Source], c.Target)
+		incoming[c.Target] = append(incoming[c.Target], c.Source)
+	}
 
-	// Iterate output neurons
+	// Mark the nodes reachable from the inputs and those from which an output can be reached
+	fwd := make(map[int]bool, len(s.Nodes))
+	bwd := make(map[int]bool, len(s.Nodes))
+	var fq, bq []int
+	for _, n := range s.Nodes {
+		switch n.NeuronType {
+		case neat.Input, neat.Bias:
+			fq = append(fq, n.id)
+		case neat.Output:
+			bq = append(bq, n.id)
+		}
+	}
+	mark(fq, fwd, outgoing)
+	mark(bq, bwd, incoming)
+
+	// Remove the dead nodes and connections
+	nodes := s.Nodes[:0]
+	for _, n := range s.Nodes {
+		if n.NeuronType != neat.Hidden || (fwd[n.id] && bwd[n.id]) {
+			nodes = append(nodes, n)
+		}
+	}
+	conns := s.Conns[:0]
+	for _, c := range s.Conns {
+		if fwd[c.Source] && bwd[c.Target] {
+			conns = append(conns, c)
+		}
+	}
+	ts.TrimmedNodes = len(s.Nodes) - len(nodes)
+	ts.TrimmedConns = len(s.Conns) - len(conns)
+	s.Nodes, s.Conns = nodes, conns
+	return
+}
+
+// Marks the nodes reachable from those in the queue by following the links
+func mark(q []int, seen map[int]bool, links map[int][]int) {
+	for _, id := range q {
+		seen[id] = true
+	}
+	for len(q) > 0 {
+		id := q[0]
+		q = q[1:]
+		for _, x := range links[id] {
+			if !seen[x] {
+				seen[x] = true
+				q = append(q, x)
+			}
+		}
+	}
 }
@@ -0,0 +1,192 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package decoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/network"
+)
+
+func node(id int, t neat.NeuronType, layer int) SubstrateNode {
+	return SubstrateNode{id: id, NeuronType: t, Layer: layer, Position: []float64{float64(id), float64(layer)}}
+}
+
+// Returns a substrate with a live path through hidden node 4, a dead end at hidden node 5 and
+// hidden nodes 6 and 7 which no input reaches
+func trimSubstrate() Substrate {
+	return Substrate{
+		Nodes: SubstrateNodes{
+			node(1, neat.Input, 0),
+			node(2, neat.Bias, 0),
+			node(3, neat.Output, 2),
+			node(4, neat.Hidden, 1),
+			node(5, neat.Hidden, 1),
+			node(6, neat.Hidden, 1),
+			node(7, neat.Hidden, 1),
+		},
+		Conns: SubstrateConns{
+			{Source: 1, Target: 4, Weight: 1},
+			{Source: 2, Target: 4, Weight: -0.5},
+			{Source: 4, Target: 3, Weight: 2},
+			{Source: 1, Target: 3, Weight: 0.5},
+			{Source: 1, Target: 5, Weight: 1},
+			{Source: 7, Target: 6, Weight: 1},
+			{Source: 6, Target: 3, Weight: 1},
+		},
+	}
+}
+
+func TestTrimRemovesDeadNodesAndConnections(t *testing.T) {
+	s := trimSubstrate()
+	ts := s.trim()
+	if ts != (TrimStats{Substrates: 1, Nodes: 7, Conns: 7, TrimmedNodes: 3, TrimmedConns: 3}) {
+		t.Errorf("Unexpected statistics %+v", ts)
+	}
+	ids := make(map[int]bool)
+	for _, n := range s.Nodes {
+		ids[n.id] = true
+	}
+	for id, kept := range map[int]bool{1: true, 2: true, 3: true, 4: true, 5: false, 6: false, 7: false} {
+		if ids[id] != kept {
+			t.Errorf("Node %d kept: %v, expected %v", id, ids[id], kept)
+		}
+	}
+	for _, c := range s.Conns {
+		if !ids[c.Source] || !ids[c.Target] {
+			t.Errorf("Connection %s refers to a trimmed node", c)
+		}
+	}
+	if len(s.Conns) != 4 {
+		t.Errorf("Expected 4 connections to remain but got %d", len(s.Conns))
+	}
+
+	// Trimming again changes nothing
+	if ts = s.trim(); ts.TrimmedNodes != 0 || ts.TrimmedConns != 0 {
+		t.Errorf("Expected nothing left to trim but got %+v", ts)
+	}
+}
+
+func TestTrimKeepsOutputsOfLiveNodes(t *testing.T) {
+
+	// Without the nodes no input reaches, trimming only removes the dead end, which cannot
+	// change the outputs
+	s := trimSubstrate()
+	s.Nodes = s.Nodes[:5]
+	s.Conns = s.Conns[:5]
+	before, err := s.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.trim()
+	after, err := s.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, x := range []float64{-1, 0, 0.25, 1} {
+		a, err := before.Activate([]float64{x})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := after.Activate([]float64{x})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a[0] != b[0] {
+			t.Errorf("Output for input %f changed from %f to %f", x, a[0], b[0])
+		}
+	}
+}
+
+func TestTrimStats(t *testing.T) {
+	a := TrimStats{Substrates: 1, Nodes: 10, Conns: 20, TrimmedNodes: 2, TrimmedConns: 5}
+	b := a.Add(a)
+	if n, c := b.Fractions(); n != 0.2 || c != 0.25 {
+		t.Errorf("Expected fractions 0.2 and 0.25 but got %f and %f", n, c)
+	}
+	if b.Sub(a) != a {
+		t.Errorf("Expected %+v but got %+v", a, b.Sub(a))
+	}
+	if n, c := (TrimStats{}).Fractions(); n != 0 || c != 0 {
+		t.Errorf("Expected no fractions of empty statistics but got %f and %f", n, c)
+	}
+}
+
+func TestDecodeKeepsPositions(t *testing.T) {
+
+	// A 3-D substrate whose nodes are listed out of order
+	pos := map[int][]float64{1: {-1, -1, -1}, 2: {1, -1, -1}, 3: {0, 1, 1}, 4: {0, 0.5, 0}, 5: {1, 0, 0}}
+	s := Substrate{
+		Nodes: SubstrateNodes{
+			{id: 3, NeuronType: neat.Output, Layer: 2, Position: pos[3]},
+			{id: 4, NeuronType: neat.Hidden, Layer: 1, Position: pos[4]},
+			{id: 1, NeuronType: neat.Input, Layer: 0, Position: pos[1]},
+			{id: 2, NeuronType: neat.Input, Layer: 0, Position: pos[2]},
+			{id: 5, NeuronType: neat.Hidden, Layer: 1, Position: pos[5]},
+		},
+		Conns: SubstrateConns{
+			{Source: 1, Target: 4, Weight: 1},
+			{Source: 2, Target: 5, Weight: 1},
+			{Source: 4, Target: 3, Weight: 1},
+			{Source: 5, Target: 3, Weight: 1},
+		},
+	}
+	net, err := s.Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, ok := net.(*network.Compiled)
+	if !ok {
+		t.Fatalf("Expected a compiled network, got %T", net)
+	}
+
+	// Neurons are ordered by type and layer, keep every coordinate and are hinted across by their
+	// first coordinate and up by their layer
+	order := []int{1, 2, 4, 5, 3}
+	hints := [][2]float64{{0, 0}, {1, 0}, {0.5, 0.5}, {1, 0.5}, {0.5, 1}}
+	for i, n := range c.Neurons {
+		if !reflect.DeepEqual(n.Position, pos[order[i]]) {
+			t.Errorf("Expected neuron %d at %v, got %v", i, pos[order[i]], n.Position)
+		}
+		if n.X != hints[i][0] || n.Y != hints[i][1] {
+			t.Errorf("Expected neuron %d hinted at %v, got (%f, %f)", i, hints[i], n.X, n.Y)
+		}
+	}
+	if str := c.String(); !strings.Contains(str, "Position: [0 1 1]") {
+		t.Errorf("Expected the output's full position in %s", str)
+	}
+
+	// The positions are exported
+	x, err := s.Interchange()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range x.Neurons {
+		if !reflect.DeepEqual(n.Position, pos[order[i]]) {
+			t.Errorf("Expected exported neuron %d at %v, got %v", i, pos[order[i]], n.Position)
+		}
+	}
+}
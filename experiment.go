@@ -28,9 +28,12 @@ package neat
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
+	"runtime"
 	"sort"
+	"sync"
 
 	. "github.com/rqme/errors"
 )
@@ -40,6 +43,7 @@ type ExperimentSettings interface {
 	Traits() Traits
 	FitnessType() FitnessType
 	ExperimentName() string
+	NumWorkers() int // Number of genomes decoded concurrently. If less than 1, GOMAXPROCS is used
 }
 
 // Experiment provides the definition of how to solve the problem using NEAT
@@ -53,30 +57,115 @@ type Experiment struct {
 	best       Genome
 	iteration  int
 	stopped    bool
+	generation int // Generation of the last checkpoint
+	halted     bool
+	listeners  []Listener
+	hooks      []Listener // Helpers which are also listeners
 }
 
 func (e *Experiment) SetContext(x Context) error {
 	e.ctx = x
+	e.generation = -1
 	e.ctx.State()["population"] = &e.population
+	e.ctx.State()["experiment"] = e.Checkpoint()
+
+	// Register the internal state of the helpers and those helpers which are listeners
+	hs := []struct {
+		key string
+		h   interface{}
+	}{
+		{"archiver", x.Archiver()},
+		{"comparer", x.Comparer()},
+		{"crosser", x.Crosser()},
+		{"decoder", x.Decoder()},
+		{"evaluator", x.Evaluator()},
+		{"generator", x.Generator()},
+		{"mutator", x.Mutator()},
+		{"searcher", x.Searcher()},
+		{"speciater", x.Speciater()},
+		{"visualizer", x.Visualizer()},
+	}
+	e.hooks = e.hooks[:0]
+	for _, h := range hs {
+		if ch, ok := h.h.(Checkpointable); ok {
+			e.ctx.State()[h.key] = ch.Checkpoint()
+		}
+		if lh, ok := h.h.(Listener); ok {
+			e.hooks = append(e.hooks, lh)
+		}
+	}
 	return nil
 }
 
+// Returns the experiment's progress so that it can be archived
+func (e *Experiment) Checkpoint() interface{} {
+	return &struct {
+		Best       *Genome
+		Iteration  *int
+		Stopped    *bool
+		Generation *int
+	}{&e.best, &e.iteration, &e.stopped, &e.generation}
+}
+
 func (e Experiment) Context() Context { return e.ctx }
 
+// Returns the genealogy recorded by the generator or nil if the generator does not record one
+func (e Experiment) Genealogy() *Genealogy {
+	if e.ctx == nil {
+		return nil
+	}
+	if gh, ok := e.ctx.Generator().(Genealogical); ok {
+		return gh.Genealogy()
+	}
+	return nil
+}
+
 func (e Experiment) Population() Population { return e.population }
 
 func (e Experiment) Stopped() bool { return e.stopped }
 
 func (e Experiment) Iteration() int { return e.iteration }
 
+func (e Experiment) Best() Genome { return e.best }
+
 // String returns a description of the experiment
 func (e Experiment) String() string {
 	return fmt.Sprintf("Experiment %s at iteration %d has best genome %d with fitness %f", e.ExperimentName(), e.iteration, e.best.ID, e.best.Fitness)
 }
 
+// Error returned by RunContext when the experiment is interrupted before it completes
+type CanceledError struct {
+	Iteration int   // Iteration at which the experiment was interrupted
+	Err       error // Reason given by the context
+}
+
+func (e CanceledError) Error() string {
+	return fmt.Sprintf("Experiment canceled at iteration %d: %v", e.Iteration, e.Err)
+}
+
+// Returns the context's reason for the cancellation
+func (e CanceledError) Unwrap() error { return e.Err }
+
+// Returns true if the error was caused by the experiment being canceled
+func IsCanceled(err error) bool {
+	_, ok := err.(CanceledError)
+	return ok
+}
+
 // Runs a configured experiment. If restoring, including just the configuration, this must be done
-// prior to calling Run.
+// prior to calling Run. A restored experiment resumes from its last checkpoint.
 func Run(e *Experiment) error {
+	return RunContext(context.Background(), e)
+}
+
+// Runs a configured experiment until it completes or the context is done. Cancellation is checked
+// between generations and, if the searcher is Cancelable, during the search. When canceled between
+// generations, a final archive is taken. When canceled during a search, the partially evaluated
+// generation is discarded and the last evaluated population is restored. No archive is taken then,
+// as the helpers have already moved on to the discarded generation. The archive taken once the
+// restored population was evaluated, before the helpers moved on, remains the one to resume from.
+// In either case a CanceledError is returned.
+func RunContext(c context.Context, e *Experiment) error {
 
 	// Ensure this is a valid experiment
 	if e.Iterations() < 1 {
@@ -84,63 +173,91 @@ func Run(e *Experiment) error {
 	}
 
 	// Iterate the experiment
-	for e.iteration = 0; e.iteration < e.Iterations(); e.iteration++ {
+	e.halted = false
+	for e.iteration < e.Iterations() && !e.stopped && !e.halted {
+
+		// Check for cancellation between generations
+		if err := c.Err(); err != nil {
+			if aerr := checkpoint(e, true); aerr != nil {
+				return fmt.Errorf("Could not take last archive of canceled experiment: %v", aerr)
+			}
+			return CanceledError{Iteration: e.iteration, Err: err}
+		}
 		//fmt.Println("iteration", e.iteration, "best", e.best.Fitness)
 		// Reset the innovation history
 		//e.mrk.Reset()
 
 		// Advance the population
+		last := e.population
+		prev := e.population.Species
 		if err := advance(e); err != nil {
 			return fmt.Errorf("Could not advance the population: %v", err)
 		}
+		notifySpecies(e, prev, e.population.Species)
 
 		// Update the phenome cache
 		if err := updateCache(e); err != nil {
 			return fmt.Errorf("Couuld not update cache in the experiment: %v", err)
 		}
+		e.notify(func(l Listener) { l.GenerationStarted(e) })
 
 		// Evaluate the population
-		if stop, err := search(e); err != nil {
+		if stop, err := search(c, e); err != nil {
+			if cerr := c.Err(); cerr != nil {
+				e.population = last
+				return CanceledError{Iteration: e.iteration, Err: cerr}
+			}
 			return fmt.Errorf("Error evaluating the population: %v", err)
 		} else if stop {
 			e.stopped = true
+			e.notify(func(l Listener) { l.GenerationEnded(e) })
+			e.notify(func(l Listener) { l.Stopped(e) })
 			break
 		}
+		e.notify(func(l Listener) { l.GenerationEnded(e) })
+
+		// Checkpoint the evaluated population
+		e.iteration += 1
+		if err := checkpoint(e, false); err != nil {
+			return fmt.Errorf("Could not checkpoint the experiment: %v", err)
+		}
 	}
 
 	// Take one last archive and return
-	if err := e.ctx.Archiver().Archive(e.ctx); err != nil {
+	if err := checkpoint(e, true); err != nil {
 		return fmt.Errorf("Could not take last archive of experiment: %v", err)
 	}
-	if err := e.ctx.Visualizer().Visualize(e.population); err != nil {
-		return fmt.Errorf("Could not visualize the experiment for the last time: %v", err)
-	}
 	return nil
 }
 
 // Advances the experiment to the next generation
 func advance(e *Experiment) error {
-
-	curr := e.population
-	next, err := e.ctx.Generator().Generate(curr)
+	next, err := e.ctx.Generator().Generate(e.population)
 	if err != nil {
 		return err
 	}
+	e.population = next
+	return nil
+}
 
-	if next.Generation > curr.Generation {
+// Visualizes and archives the experiment if the population has advanced to a new generation since
+// the last checkpoint. The checkpoint is taken after the population is evaluated and before the
+// next one is generated so that every helper's state reflects the same moment. If forced, the
+// archive is taken even if the generation has not changed.
+func checkpoint(e *Experiment, force bool) (err error) {
+	if e.population.Generation <= e.generation && !force {
+		return
+	}
+	if e.population.Generation > e.generation {
+		e.generation = e.population.Generation
 		if err = e.ctx.Visualizer().Visualize(e.population); err != nil {
-			return err
-		}
-		if err = e.ctx.Archiver().Archive(e.ctx); err != nil {
-			return err
-		}
-		if err = updateSettings(e, e.best); err != nil {
-			return err
+			return
 		}
 	}
-
-	e.population = next
-	return nil
+	if err = e.ctx.Archiver().Archive(e.ctx); err != nil {
+		return
+	}
+	return updateSettings(e, e.best)
 }
 
 // Update the settings based on the traits of a genome
@@ -172,36 +289,65 @@ func updateCache(e *Experiment) (err error) {
 	}
 	e.cache = make(map[int]Phenome, len(e.population.Genomes))
 
-	errs := new(Errors)
-	pc := make(chan Phenome)
-	cnt := 0
+	// Reuse the existing phenomes and collect the genomes needing decoding
+	gs := make([]Genome, 0, len(e.population.Genomes))
 	for _, g := range e.population.Genomes {
 		if p, ok := old[g.ID]; ok {
 			e.cache[g.ID] = p
 		} else {
-			cnt += 1
-			go func(g Genome) {
-				p, err := e.ctx.Decoder().Decode(g)
-				if err != nil {
-					errs.Add(fmt.Errorf("Unable to decode genome [%d]: %v", g.ID, err))
-				}
-				pc <- p
-			}(g)
+			gs = append(gs, g)
 		}
 	}
 
-	for i := 0; i < cnt; i++ {
-		p := <-pc
+	// Decode the new genomes using a fixed number of workers
+	n := e.NumWorkers()
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
+	}
+	errs := new(Errors)
+	ps := make([]Phenome, len(gs))
+	jobs := make(chan int)
+	wg := new(sync.WaitGroup)
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ps[i] = decode(e.ctx.Decoder(), gs[i], errs)
+			}
+		}()
+	}
+	for i := range gs {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, p := range ps {
 		if p != nil {
 			e.cache[p.ID()] = p
 		}
-
 	}
 	return errs.Err()
 }
 
+// Decodes a single genome, recording any error or panic
+func decode(d Decoder, g Genome, errs *Errors) (p Phenome) {
+	defer func() {
+		if r := recover(); r != nil {
+			errs.Add(fmt.Errorf("Panic while decoding genome [%d]: %v", g.ID, r))
+			p = nil
+		}
+	}()
+	p, err := d.Decode(g)
+	if err != nil {
+		errs.Add(fmt.Errorf("Unable to decode genome [%d]: %v", g.ID, err))
+	}
+	return
+}
+
 // Searches the population and updates the genomes' fitness
-func search(e *Experiment) (stop bool, err error) {
+func search(c context.Context, e *Experiment) (stop bool, err error) {
 
 	// Map the genomes for convenience
 	m := make(map[int]int, len(e.population.Genomes))
@@ -209,10 +355,19 @@ func search(e *Experiment) (stop bool, err error) {
 		m[g.ID] = i
 	}
 
-	// Perform the search
+	// Perform the search. Phenomes are listed in the population's order to keep runs repeatable.
 	phenomes := make([]Phenome, 0, len(e.cache))
-	for _, p := range e.cache {
-		phenomes = append(phenomes, p)
+	for _, g := range e.population.Genomes {
+		if p, ok := e.cache[g.ID]; ok {
+			phenomes = append(phenomes, p)
+		}
+	}
+
+	// Clear the state that phenomes cached from an earlier generation kept from their evaluation
+	for _, p := range phenomes {
+		if rp, ok := p.(Resettable); ok {
+			rp.Reset()
+		}
 	}
 	for _, h := range []interface{}{e.ctx.Searcher(), e.ctx.Evaluator()} {
 		if ph, ok := h.(Phenomable); ok {
@@ -228,7 +383,12 @@ func search(e *Experiment) (stop bool, err error) {
 	}
 
 	var rs Results
-	if rs, err = e.ctx.Searcher().Search(phenomes); err != nil {
+	if ch, ok := e.ctx.Searcher().(Cancelable); ok {
+		rs, err = ch.SearchContext(c, phenomes)
+	} else {
+		rs, err = e.ctx.Searcher().Search(phenomes)
+	}
+	if err != nil {
 		return
 	}
 
@@ -241,7 +401,6 @@ func search(e *Experiment) (stop bool, err error) {
 	}
 
 	// Update the fitnesses
-	var best Genome
 	errs := new(Errors)
 	// := make([]float64, len(e.population.Genomes))
 	// TODO: make this concurrent
@@ -249,6 +408,7 @@ func search(e *Experiment) (stop bool, err error) {
 		i := m[r.ID()]
 		if err = r.Err(); err != nil {
 			errs.Add(fmt.Errorf("Error updating fitness for genome [%d]: %v", r.ID(), r.Err()))
+			e.notify(func(l Listener) { l.EvaluationFailed(e, r.ID(), r.Err()) })
 		}
 		e.population.Genomes[i].Fitness = r.Fitness()
 		if imp, ok := r.(Improvable); ok {
@@ -256,22 +416,35 @@ func search(e *Experiment) (stop bool, err error) {
 		} else {
 			e.population.Genomes[i].Improvement = e.population.Genomes[i].Fitness
 		}
-		//fit[i] = e.population.Genomes[i].Fitness
-		if e.population.Genomes[i].Fitness > best.Fitness {
-			best = e.population.Genomes[i]
+		if oh, ok := r.(Objectivable); ok {
+			e.population.Genomes[i].Objectives = oh.Objectives()
 		}
+		//fit[i] = e.population.Genomes[i].Fitness
 		stop = stop || r.Stop()
 	}
 
+	// Identify the best genome. The population is used, instead of the results, so that ties are
+	// settled the same way regardless of the order in which the results arrived.
+	var best Genome
+	for _, g := range e.population.Genomes {
+		if g.Fitness > best.Fitness {
+			best = g
+		}
+	}
+
 	// Update the best genome
 	if errs.Err() == nil {
+		improved := best.Fitness > e.best.Fitness
 		if e.FitnessType() == Absolute {
-			if best.Fitness > e.best.Fitness {
+			if improved {
 				e.best = best
 			}
 		} else {
 			e.best = best
 		}
+		if improved {
+			e.notify(func(l Listener) { l.BestImproved(e, best) })
+		}
 	}
 
 	// Leave the genomes sorted by their fitness descending
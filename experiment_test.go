@@ -0,0 +1,253 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package neat
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type testSettings struct{ iterations int }
+
+func (s testSettings) Iterations() int          { return s.iterations }
+func (s testSettings) Traits() Traits           { return nil }
+func (s testSettings) FitnessType() FitnessType { return Absolute }
+func (s testSettings) ExperimentName() string   { return "test" }
+func (s testSettings) NumWorkers() int          { return 2 }
+
+// Context with only the helpers a test sets. The rest are nil.
+type testContext struct {
+	Context
+	arc   Archiver
+	dec   Decoder
+	evl   Evaluator
+	gen   Generator
+	src   Searcher
+	spc   Speciater
+	vis   Visualizer
+	state map[string]interface{}
+}
+
+func (c *testContext) Archiver() Archiver     { return c.arc }
+func (c *testContext) Comparer() Comparer     { return nil }
+func (c *testContext) Crosser() Crosser       { return nil }
+func (c *testContext) Decoder() Decoder       { return c.dec }
+func (c *testContext) Evaluator() Evaluator   { return c.evl }
+func (c *testContext) Generator() Generator   { return c.gen }
+func (c *testContext) Mutator() Mutator       { return nil }
+func (c *testContext) Searcher() Searcher     { return c.src }
+func (c *testContext) Speciater() Speciater   { return c.spc }
+func (c *testContext) Visualizer() Visualizer { return c.vis }
+func (c *testContext) State() map[string]interface{} {
+	if c.state == nil {
+		c.state = make(map[string]interface{})
+	}
+	return c.state
+}
+
+// Phenome whose single output counts its activations since it was last reset
+type countingPhenome struct {
+	id           int
+	count, reset int
+}
+
+func (p *countingPhenome) ID() int           { return p.id }
+func (p *countingPhenome) Traits() []float64 { return nil }
+func (p *countingPhenome) Activate(inputs []float64) ([]float64, error) {
+	p.count += 1
+	return []float64{float64(p.count)}, nil
+}
+func (p *countingPhenome) Reset() {
+	p.count = 0
+	p.reset += 1
+}
+
+type testResult struct {
+	id      int
+	fitness float64
+	err     error
+}
+
+func (r testResult) ID() int          { return r.id }
+func (r testResult) Fitness() float64 { return r.fitness }
+func (r testResult) Err() error       { return r.err }
+func (r testResult) Stop() bool       { return false }
+
+// Evaluator which activates the phenome twice and uses the last output as the fitness
+type activatingEvaluator struct{}
+
+func (activatingEvaluator) Evaluate(p Phenome) Result {
+	p.Activate(nil)
+	out, err := p.Activate(nil)
+	return testResult{id: p.ID(), fitness: out[0], err: err}
+}
+
+// Searcher which evaluates the phenomes in order
+type orderedSearcher struct{ evl Evaluator }
+
+func (s orderedSearcher) Search(ps []Phenome) ([]Result, error) {
+	rs := make([]Result, len(ps))
+	for i, p := range ps {
+		rs[i] = s.evl.Evaluate(p)
+	}
+	return rs, nil
+}
+
+func TestSearchResetsCachedPhenomes(t *testing.T) {
+	ctx := &testContext{evl: activatingEvaluator{}, src: orderedSearcher{activatingEvaluator{}}}
+	e := &Experiment{ExperimentSettings: testSettings{iterations: 1}}
+	e.SetContext(ctx)
+	ps := []*countingPhenome{{id: 1}, {id: 2}}
+	e.population = Population{Genomes: []Genome{{ID: 1}, {ID: 2}}}
+	e.cache = map[int]Phenome{1: ps[0], 2: ps[1]}
+
+	// The cached phenomes are evaluated again in the next generation, from a cleared state
+	for gen := 1; gen <= 2; gen++ {
+		if _, err := search(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+		for _, g := range e.population.Genomes {
+			if g.Fitness != 2 {
+				t.Errorf("Generation %d: genome %d has fitness %f, expected 2 from a reset phenome", gen, g.ID, g.Fitness)
+			}
+		}
+		for _, p := range ps {
+			if p.reset != gen {
+				t.Errorf("Generation %d: phenome %d was reset %d times", gen, p.id, p.reset)
+			}
+		}
+	}
+}
+
+// Generator which numbers the genomes of each generation from a running counter
+type countingGenerator struct{ next int }
+
+func (g *countingGenerator) Generate(curr Population) (Population, error) {
+	next := Population{Generation: curr.Generation + 1, Genomes: make([]Genome, 2)}
+	for i := range next.Genomes {
+		g.next += 1
+		next.Genomes[i] = Genome{ID: g.next}
+	}
+	return next, nil
+}
+
+type countingDecoder struct{}
+
+func (countingDecoder) Decode(g Genome) (Phenome, error) { return &countingPhenome{id: g.ID}, nil }
+
+// Archiver which records the generator's counter at each archive
+type recordingArchiver struct {
+	gen      *countingGenerator
+	archived []int
+}
+
+func (a *recordingArchiver) Archive(Context) error {
+	a.archived = append(a.archived, a.gen.next)
+	return nil
+}
+
+type nullVisualizer struct{}
+
+func (nullVisualizer) Visualize(Population) error { return nil }
+
+// Searcher which cancels the experiment during the search of a generation
+type cancelingSearcher struct {
+	orderedSearcher
+	cancel     func()
+	generation int
+	searches   int
+}
+
+func (s *cancelingSearcher) SearchContext(c context.Context, ps []Phenome) ([]Result, error) {
+	if s.searches += 1; s.searches == s.generation {
+		s.cancel()
+		return nil, c.Err()
+	}
+	return s.Search(ps)
+}
+
+// Listener which records the generations started and ended
+type generationListener struct {
+	NullListener
+	started, ended int
+}
+
+func (l *generationListener) GenerationStarted(e *Experiment) { l.started += 1 }
+func (l *generationListener) GenerationEnded(e *Experiment)   { l.ended += 1 }
+
+func TestCancelDuringSearchKeepsLastArchive(t *testing.T) {
+	c, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	gen := &countingGenerator{}
+	arc := &recordingArchiver{gen: gen}
+	src := &cancelingSearcher{orderedSearcher: orderedSearcher{activatingEvaluator{}}, cancel: cancel, generation: 3}
+	ctx := &testContext{arc: arc, dec: countingDecoder{}, evl: activatingEvaluator{}, gen: gen, src: src, vis: nullVisualizer{}}
+	e := &Experiment{ExperimentSettings: testSettings{iterations: 5}}
+	e.SetContext(ctx)
+	l := &generationListener{}
+	e.AddListener(l)
+
+	err := RunContext(c, e)
+	if !IsCanceled(err) {
+		t.Fatalf("Expected a CanceledError, got %v", err)
+	}
+
+	// The population and the archive are those of the last evaluated generation, before the
+	// generator numbered the canceled one
+	if e.Iteration() != 2 || e.Population().Generation != 2 {
+		t.Errorf("Expected to resume from generation 2, have iteration %d and generation %d", e.Iteration(), e.Population().Generation)
+	}
+	if len(arc.archived) != 2 || arc.archived[1] != 4 {
+		t.Errorf("Expected archives after genomes 2 and 4, got %v", arc.archived)
+	}
+	if l.started != 3 || l.ended != 2 {
+		t.Errorf("Expected 3 generations started and 2 ended, got %d and %d", l.started, l.ended)
+	}
+}
+
+// Decoder which panics for the genome
+type panickingDecoder struct{ id int }
+
+func (d panickingDecoder) Decode(g Genome) (Phenome, error) {
+	if g.ID == d.id {
+		panic("bad genome")
+	}
+	return &countingPhenome{id: g.ID}, nil
+}
+
+func TestPanickingDecodeIsAnError(t *testing.T) {
+	gen := &countingGenerator{}
+	ctx := &testContext{arc: &recordingArchiver{gen: gen}, dec: panickingDecoder{id: 2}, evl: activatingEvaluator{},
+		gen: gen, src: orderedSearcher{activatingEvaluator{}}, vis: nullVisualizer{}}
+	e := &Experiment{ExperimentSettings: testSettings{iterations: 1}}
+	e.SetContext(ctx)
+	err := Run(e)
+	if err == nil || !strings.Contains(err.Error(), "Panic while decoding genome [2]: bad genome") {
+		t.Errorf("Expected the panic as an error, got %v", err)
+	}
+}
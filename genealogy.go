@@ -0,0 +1,345 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package neat
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Names of the operations recorded in a genome's mutations
+const (
+	CrossoverOp  = "crossover"  // Genes were inherited from two parents
+	AddNodeOp    = "add-node"   // A node was added
+	DelNodeOp    = "del-node"   // A node was removed
+	AddConnOp    = "add-conn"   // A connection was added
+	DelConnOp    = "del-conn"   // A connection was removed
+	EnableOp     = "enable"     // A connection was enabled
+	DisableOp    = "disable"    // A connection was disabled
+	WeightOp     = "weight"     // Connection weights were changed
+	ActivationOp = "activation" // Node activations were changed
+	TraitOp      = "trait"      // Trait values were changed
+	RepairOp     = "repair"     // The genome was repaired after mutation or replaced by a copy of its parent
+)
+
+// Returns the operations which changed the genome from before to after, in a fixed order
+func MutationsBetween(before, after Genome) []string {
+	ops := make([]string, 0, 4)
+	add := func(op string, ok bool) {
+		if ok {
+			ops = append(ops, op)
+		}
+	}
+	var addNode, delNode, act bool
+	for k, n := range after.Nodes {
+		if b, ok := before.Nodes[k]; !ok {
+			addNode = true
+		} else if b.ActivationType != n.ActivationType {
+			act = true
+		}
+	}
+	for k := range before.Nodes {
+		if _, ok := after.Nodes[k]; !ok {
+			delNode = true
+		}
+	}
+	var addConn, delConn, enable, disable, weight bool
+	for k, c := range after.Conns {
+		b, ok := before.Conns[k]
+		switch {
+		case !ok:
+			addConn = true
+		default:
+			enable = enable || (!b.Enabled && c.Enabled)
+			disable = disable || (b.Enabled && !c.Enabled)
+			weight = weight || b.Weight != c.Weight
+		}
+	}
+	for k := range before.Conns {
+		if _, ok := after.Conns[k]; !ok {
+			delConn = true
+		}
+	}
+	trait := len(before.Traits) != len(after.Traits)
+	for i := 0; !trait && i < len(after.Traits); i++ {
+		trait = before.Traits[i] != after.Traits[i]
+	}
+	add(AddNodeOp, addNode)
+	add(DelNodeOp, delNode)
+	add(AddConnOp, addConn)
+	add(DelConnOp, delConn)
+	add(EnableOp, enable)
+	add(DisableOp, disable)
+	add(WeightOp, weight)
+	add(ActivationOp, act)
+	add(TraitOp, trait)
+	return ops
+}
+
+// Ancestor is the record of a genome kept by a genealogy after the genome leaves the population
+type Ancestor struct {
+	ID          int
+	Birth       int      // Generation during which the genome was born
+	Parents     []int    // IDs of the genomes from which the genome was created
+	Mutations   []string // Operations which created the genome from its parents
+	Innovations []int    // Innovation numbers of the genes which first appeared in the genome
+	Fitness     float64  // Fitness of the genome once evaluated
+}
+
+// Origin of an innovation
+type Origin struct {
+	Generation int // Generation in which the innovation first appeared
+	Genome     int // ID of the first genome with the innovation
+}
+
+// Genealogy records every genome created during an experiment so that the ancestry of any genome,
+// such as the champion, can be traced after its ancestors have left the population. The record
+// grows with every genome created unless it is pruned to the ancestry of the current population.
+// It is safe for concurrent use.
+type Genealogy struct {
+	mu        sync.RWMutex
+	Ancestors map[int]*Ancestor // Records by genome ID
+	Origins   map[int]Origin    // Origins by innovation number
+}
+
+func NewGenealogy() *Genealogy {
+	return &Genealogy{Ancestors: make(map[int]*Ancestor), Origins: make(map[int]Origin)}
+}
+
+// Records the genome. Genes which do not appear in any of the recorded parents are noted as
+// innovations of the genome unless they have been seen before. Recording a genome again only
+// updates its fitness.
+func (h *Genealogy) Record(g Genome, parents ...Genome) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.Ancestors == nil {
+		h.Ancestors = make(map[int]*Ancestor)
+		h.Origins = make(map[int]Origin)
+	}
+	if a, ok := h.Ancestors[g.ID]; ok {
+		a.Fitness = g.Fitness
+		return
+	}
+	a := &Ancestor{ID: g.ID, Birth: g.Birth, Fitness: g.Fitness, Mutations: g.Mutations}
+	a.Parents = g.Parents
+	if a.Parents == nil {
+		for _, p := range parents {
+			a.Parents = append(a.Parents, p.ID)
+		}
+	}
+	inherited := func(k int, nodes bool) bool {
+		for _, p := range parents {
+			if nodes {
+				if _, ok := p.Nodes[k]; ok {
+					return true
+				}
+			} else if _, ok := p.Conns[k]; ok {
+				return true
+			}
+		}
+		return false
+	}
+	note := func(k int) {
+		if _, ok := h.Origins[k]; !ok {
+			h.Origins[k] = Origin{Generation: g.Birth, Genome: g.ID}
+			a.Innovations = append(a.Innovations, k)
+		}
+	}
+	for k := range g.Nodes {
+		if !inherited(k, true) {
+			note(k)
+		}
+	}
+	for k := range g.Conns {
+		if !inherited(k, false) {
+			note(k)
+		}
+	}
+	sort.Ints(a.Innovations)
+	h.Ancestors[g.ID] = a
+}
+
+// Updates the fitness of the recorded genomes in the population
+func (h *Genealogy) Update(genomes []Genome) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, g := range genomes {
+		if a, ok := h.Ancestors[g.ID]; ok {
+			a.Fitness = g.Fitness
+		}
+	}
+}
+
+// Removes the records of the genomes which are not among the given genomes or their ancestors.
+// The origins of innovations are kept, even if the genome in which one appeared is removed.
+func (h *Genealogy) Prune(ids ...int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	keep := make(map[int]bool, len(ids))
+	queue := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if !keep[id] {
+			keep[id] = true
+			queue = append(queue, id)
+		}
+	}
+	for ; len(queue) > 0; queue = queue[1:] {
+		a, ok := h.Ancestors[queue[0]]
+		if !ok {
+			continue
+		}
+		for _, p := range a.Parents {
+			if !keep[p] {
+				keep[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	for id := range h.Ancestors {
+		if !keep[id] {
+			delete(h.Ancestors, id)
+		}
+	}
+}
+
+// Returns the record of the genome
+func (h *Genealogy) Ancestor(id int) (Ancestor, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if a, ok := h.Ancestors[id]; ok {
+		return *a, true
+	}
+	return Ancestor{}, false
+}
+
+// Returns the records of the genome and all of its recorded ancestors, nearest first
+func (h *Genealogy) Ancestry(id int) []Ancestor {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var as []Ancestor
+	seen := map[int]bool{id: true}
+	for queue := []int{id}; len(queue) > 0; queue = queue[1:] {
+		a, ok := h.Ancestors[queue[0]]
+		if !ok {
+			continue
+		}
+		as = append(as, *a)
+		for _, p := range a.Parents {
+			if !seen[p] {
+				seen[p] = true
+				queue = append(queue, p)
+			}
+		}
+	}
+	return as
+}
+
+// Returns the records of the genome and its first parent, that parent's first parent and so on
+// back to the initial population
+func (h *Genealogy) Lineage(id int) []Ancestor {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var as []Ancestor
+	for a, ok := h.Ancestors[id]; ok; {
+		as = append(as, *a)
+		if len(a.Parents) == 0 || a.Parents[0] == a.ID {
+			break
+		}
+		a, ok = h.Ancestors[a.Parents[0]]
+	}
+	return as
+}
+
+// Returns where the innovation first appeared
+func (h *Genealogy) Origin(innovation int) (Origin, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	o, ok := h.Origins[innovation]
+	return o, ok
+}
+
+// Writes the phylogenetic tree of the genomes as a Graphviz DOT digraph, with each genome pointing
+// to its children. If no genomes are given, every recorded genome is included; otherwise only the
+// genomes and their ancestors are.
+func (h *Genealogy) WriteDOT(w io.Writer, ids ...int) error {
+	var as []Ancestor
+	if len(ids) == 0 {
+		h.mu.RLock()
+		for _, a := range h.Ancestors {
+			as = append(as, *a)
+		}
+		h.mu.RUnlock()
+	} else {
+		seen := make(map[int]bool)
+		for _, id := range ids {
+			for _, a := range h.Ancestry(id) {
+				if !seen[a.ID] {
+					seen[a.ID] = true
+					as = append(as, a)
+				}
+			}
+		}
+	}
+	sort.Slice(as, func(i, j int) bool { return as[i].ID < as[j].ID })
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "digraph genealogy {\n")
+	fmt.Fprintf(b, "\trankdir=TB;\n")
+	fmt.Fprintf(b, "\tnode [shape=box, fontsize=10];\n")
+	ranks := make(map[int][]string)
+	for _, a := range as {
+		label := fmt.Sprintf("%d\\nfitness %.3f", a.ID, a.Fitness)
+		if len(a.Mutations) > 0 {
+			label += "\\n" + strings.Join(a.Mutations, ", ")
+		}
+		fmt.Fprintf(b, "\tg%d [label=\"%s\"];\n", a.ID, label)
+		ranks[a.Birth] = append(ranks[a.Birth], fmt.Sprintf("g%d", a.ID))
+	}
+	gens := make([]int, 0, len(ranks))
+	for gen := range ranks {
+		gens = append(gens, gen)
+	}
+	sort.Ints(gens)
+	for _, gen := range gens {
+		fmt.Fprintf(b, "\t{ rank=same; %s; }\n", strings.Join(ranks[gen], "; "))
+	}
+	for _, a := range as {
+		for i, p := range a.Parents {
+			style := "solid"
+			if i > 0 {
+				style = "dashed" // second parent of a crossover
+			}
+			fmt.Fprintf(b, "\tg%d -> g%d [style=%s];\n", p, a.ID, style)
+		}
+	}
+	fmt.Fprintf(b, "}\n")
+	return b.Flush()
+}
@@ -0,0 +1,152 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package neat
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Returns a genome with the node and connection innovations
+func genealogyGenome(id, birth int, nodes []int, conns []int) Genome {
+	g := Genome{ID: id, Birth: birth, Nodes: make(Nodes), Conns: make(Connections)}
+	for _, k := range nodes {
+		g.Nodes[k] = Node{Innovation: k}
+	}
+	for _, k := range conns {
+		g.Conns[k] = Connection{Innovation: k}
+	}
+	return g
+}
+
+// Records a small family: 1 and 2 are seeds, 3 is a mutation of 1, 4 a crossover of 3 and 2 and
+// 5 a mutation of 2
+func family() *Genealogy {
+	h := NewGenealogy()
+	g1 := genealogyGenome(1, 0, []int{1, 2}, []int{10})
+	g2 := genealogyGenome(2, 0, []int{1, 2}, []int{10})
+	h.Record(g1)
+	h.Record(g2)
+	g3 := genealogyGenome(3, 1, []int{1, 2, 3}, []int{10, 11, 12})
+	g3.Mutations = []string{AddNodeOp}
+	h.Record(g3, g1)
+	g4 := genealogyGenome(4, 2, []int{1, 2, 3}, []int{10, 11, 12, 13})
+	g4.Mutations = []string{CrossoverOp, AddConnOp}
+	h.Record(g4, g3, g2)
+	g5 := genealogyGenome(5, 1, []int{1, 2}, []int{10, 13})
+	h.Record(g5, g2)
+	return h
+}
+
+func ids(as []Ancestor) []int {
+	x := make([]int, len(as))
+	for i, a := range as {
+		x[i] = a.ID
+	}
+	return x
+}
+
+func TestGenealogyRecordsInnovationsAndAncestry(t *testing.T) {
+	h := family()
+
+	a, ok := h.Ancestor(3)
+	if !ok || !reflect.DeepEqual(a.Parents, []int{1}) || !reflect.DeepEqual(a.Innovations, []int{3, 11, 12}) {
+		t.Errorf("Unexpected record of genome 3: %+v", a)
+	}
+	if o, ok := h.Origin(13); !ok || o.Genome != 4 || o.Generation != 2 {
+		t.Errorf("Connection 13 should first appear in genome 4 in generation 2, not %+v", o)
+	}
+	if a, _ = h.Ancestor(5); len(a.Innovations) != 0 {
+		t.Errorf("Genome 5 should have no innovations of its own but has %v", a.Innovations)
+	}
+	if x := ids(h.Ancestry(4)); !reflect.DeepEqual(x, []int{4, 3, 2, 1}) {
+		t.Errorf("Expected ancestry 4, 3, 2, 1 but got %v", x)
+	}
+	if x := ids(h.Lineage(4)); !reflect.DeepEqual(x, []int{4, 3, 1}) {
+		t.Errorf("Expected lineage 4, 3, 1 but got %v", x)
+	}
+
+	// Recording again only updates the fitness
+	g := genealogyGenome(3, 7, nil, nil)
+	g.Fitness = 2.5
+	h.Record(g)
+	h.Update([]Genome{{ID: 4, Fitness: 3.5}})
+	if a, _ = h.Ancestor(3); a.Fitness != 2.5 || a.Birth != 1 || len(a.Innovations) != 3 {
+		t.Errorf("Unexpected record of genome 3 after recording it again: %+v", a)
+	}
+	if a, _ = h.Ancestor(4); a.Fitness != 3.5 {
+		t.Errorf("Expected fitness of genome 4 to be updated but it is %f", a.Fitness)
+	}
+}
+
+func TestGenealogyPruneKeepsAncestry(t *testing.T) {
+	h := family()
+	h.Prune(3)
+	for id, kept := range map[int]bool{1: true, 2: false, 3: true, 4: false, 5: false} {
+		if _, ok := h.Ancestor(id); ok != kept {
+			t.Errorf("Genome %d kept: %v, expected %v", id, ok, kept)
+		}
+	}
+	if _, ok := h.Origin(13); !ok {
+		t.Errorf("The origins of innovations should survive pruning")
+	}
+}
+
+func TestGenealogyWriteDOT(t *testing.T) {
+	h := family()
+	b := new(bytes.Buffer)
+	if err := h.WriteDOT(b, 4); err != nil {
+		t.Fatal(err)
+	}
+	s := b.String()
+	for _, want := range []string{"g3 -> g4 [style=solid]", "g2 -> g4 [style=dashed]", "crossover, add-conn"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("Expected %q in\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "g5") {
+		t.Errorf("Genome 5 is not an ancestor of 4 but is in\n%s", s)
+	}
+}
+
+func TestMutationsBetween(t *testing.T) {
+	before := genealogyGenome(1, 0, []int{1, 2}, []int{10, 11})
+	after := CopyGenome(before)
+	after.Nodes[3] = Node{Innovation: 3}
+	delete(after.Conns, 11)
+	c := after.Conns[10]
+	c.Weight, c.Enabled = 1, true
+	after.Conns[10] = c
+	if ops := MutationsBetween(before, after); !reflect.DeepEqual(ops, []string{AddNodeOp, DelConnOp, EnableOp, WeightOp}) {
+		t.Errorf("Unexpected mutations %v", ops)
+	}
+	if ops := MutationsBetween(before, before); len(ops) != 0 {
+		t.Errorf("Expected no mutations between a genome and itself but got %v", ops)
+	}
+}
@@ -58,10 +58,26 @@ type ClassicSettings interface {
 
 	// Maximum number of generations a stagnant species may exist
 	MaxStagnation() int
+
+	// Validates each offspring after crossover and mutation, repairing it if necessary. An
+	// offspring which cannot be repaired is replaced by a copy of its first parent.
+	RepairOffspring() bool
+
+	// Requires the offspring to be free of cycles when repairing
+	RequireFeedForward() bool
+
+	// Records the parents, mutations and innovations of every genome created
+	TrackGenealogy() bool
+
+	// Keeps only the ancestry of the current population in the genealogy. Otherwise the genealogy,
+	// which is archived with the generator, grows with every genome created.
+	PruneGenealogy() bool
 }
 
 type Classic struct {
 	ClassicSettings
+	neat.NullListener
+	lineage
 	ctx neat.Context
 
 	cross bool
@@ -77,11 +93,22 @@ func (g *Classic) SetCrossover(v bool) error {
 	return nil
 }
 
+// Returns the generator's internal state so that it can be archived. The genealogy is included
+// whole, so it grows with each generation unless PruneGenealogy is set.
+func (g *Classic) Checkpoint() interface{} {
+	return &struct{ Genealogy *neat.Genealogy }{g.Genealogy()}
+}
+
+// Records the fitness of the evaluated genomes in the genealogy
+func (g *Classic) GenerationEnded(e *neat.Experiment) {
+	g.update(g.ClassicSettings, e.Population())
+}
+
 func (g *Classic) Generate(curr neat.Population) (next neat.Population, err error) {
 	if len(curr.Genomes) == 0 {
-		return generateFirst(g.ctx, g.ClassicSettings)
+		return generateFirst(g.ctx, g.ClassicSettings, g.tracked(g.ClassicSettings))
 	} else {
-		return g.generateNext(curr)
+		return g.generateNext(curr, Improvements.Improvement)
 	}
 }
 
@@ -95,7 +122,7 @@ func (g *Classic) Generate(curr neat.Population) (next neat.Population, err erro
 // placement). The highest performing individual in each species, i.e. the species champions,
 // carries over from each generation. Otherwise the next generation completely replaces the one
 // before. (Stanley, 40)
-func (g *Classic) generateNext(curr neat.Population) (next neat.Population, err error) {
+func (g *Classic) generateNext(curr neat.Population, progress func(Improvements) float64) (next neat.Population, err error) {
 
 	// Update context with current population
 	for _, h := range []interface{}{g.ctx.Comparer(), g.ctx.Crosser(), g.ctx.Mutator(), g.ctx.Speciater()} {
@@ -117,13 +144,15 @@ func (g *Classic) generateNext(curr neat.Population) (next neat.Population, err
 	pool := createPool(curr)
 
 	// Purge stagnant species unliess it contains the best genome
-	purgeSpecies(g.ClassicSettings, curr.Species, pool)
+	purgeSpecies(g.ClassicSettings, curr.Species, pool, progress)
 
 	// Calculate offspring counts
-	cnts := createCounts(g.ClassicSettings, curr.Species, pool)
+	rng := g.ctx.Rand()
+	cnts := createCounts(g.ClassicSettings, rng, curr.Species, pool)
 
 	// Preserve elites
-	for i, l := range pool {
+	for _, i := range poolKeys(pool) {
+		l := pool[i]
 		if len(l) < 5 {
 			cnts[i] = cnts[i] + 1
 		} else {
@@ -132,8 +161,7 @@ func (g *Classic) generateNext(curr neat.Population) (next neat.Population, err
 	}
 
 	// Create the offspring
-	rng := rand.New(rand.NewSource(rand.Int63()))
-	err = createOffspring(g.ctx, g.ClassicSettings, g.cross, rng, pool, cnts, &next)
+	err = createOffspring(g.ctx, g.ClassicSettings, g.cross, rng, pool, cnts, &next, g.tracked(g.ClassicSettings))
 	if err != nil {
 		return
 	}
@@ -148,12 +176,13 @@ func (g *Classic) generateNext(curr neat.Population) (next neat.Population, err
 // can be summarized as follows. Let Fk be the average fitness of species k and |P | be the size
 // of the population. Let F tot = 􏰇k Fk be the total of all species fitness averages. The number of
 // offspring nk allotted to species k is: See figure 3.3 (Stanley, 40)
-func createCounts(cfg ClassicSettings, species []neat.Species, pool map[int]Improvements) (cnts map[int]int) {
+func createCounts(cfg ClassicSettings, rng *rand.Rand, species []neat.Species, pool map[int]Improvements) (cnts map[int]int) {
 
 	// Note the total fitness
 	var tot float64
-	for i, l := range pool {
-		f := l.Improvement()
+	keys := poolKeys(pool)
+	for _, i := range keys {
+		f := pool[i].Improvement()
 		if species[i].Age < 10 {
 			f *= 1.2 // Youth boost
 		} else if species[i].Age > 30 {
@@ -166,8 +195,8 @@ func createCounts(cfg ClassicSettings, species []neat.Species, pool map[int]Impr
 	avail := float64(cfg.PopulationSize() - len(pool)) // preserve room for elite
 	cnt := 0
 	cnts = make(map[int]int)
-	for idx, l := range pool {
-		f := l.Improvement()
+	for _, idx := range keys {
+		f := pool[idx].Improvement()
 		if species[idx].Age < 10 {
 			f *= 1.2 // Youth boost
 		} else if species[idx].Age > 30 {
@@ -182,12 +211,10 @@ func createCounts(cfg ClassicSettings, species []neat.Species, pool map[int]Impr
 
 	// Trim back down to overcome rounding in above calculation
 	for cnt > int(avail) {
-		for idx, n := range cnts { // Go's range over maps is random. Yay!
-			if n > 0 {
-				cnts[idx] = n - 1
-				cnt -= 1
-				break
-			}
+		idx := keys[rng.Intn(len(keys))]
+		if n := cnts[idx]; n > 0 {
+			cnts[idx] = n - 1
+			cnt -= 1
 		}
 	}
 	return
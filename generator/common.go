@@ -29,14 +29,50 @@ package generator
 import (
 	"math"
 	"math/rand"
+	"reflect"
 	"sort"
-	"sync"
 
 	"github.com/rqme/neat"
 )
 
+// Records the genealogy of the genomes created by a generator
+type lineage struct {
+	genealogy *neat.Genealogy
+}
+
+// Returns the record of the genomes created by the generator
+func (l *lineage) Genealogy() *neat.Genealogy {
+	if l.genealogy == nil {
+		l.genealogy = neat.NewGenealogy()
+	}
+	return l.genealogy
+}
+
+// Returns the genealogy if the settings ask for it to be recorded, otherwise nil
+func (l *lineage) tracked(cfg ClassicSettings) *neat.Genealogy {
+	if cfg.TrackGenealogy() {
+		return l.Genealogy()
+	}
+	return nil
+}
+
+// Records the fitness of the population's genomes and, if the settings ask for it, forgets the
+// genomes which are not ancestors of the population
+func (l *lineage) update(cfg ClassicSettings, pop neat.Population) {
+	if h := l.tracked(cfg); h != nil {
+		h.Update(pop.Genomes)
+		if cfg.PruneGenealogy() {
+			ids := make([]int, len(pop.Genomes))
+			for i, g := range pop.Genomes {
+				ids[i] = g.ID
+			}
+			h.Prune(ids...)
+		}
+	}
+}
+
 // Generates the initial population
-func generateFirst(ctx neat.Context, cfg ClassicSettings) (next neat.Population, err error) {
+func generateFirst(ctx neat.Context, cfg ClassicSettings, h *neat.Genealogy) (next neat.Population, err error) {
 	// Create the first generation
 	next = neat.Population{
 		Generation: 0,
@@ -44,22 +80,20 @@ func generateFirst(ctx neat.Context, cfg ClassicSettings) (next neat.Population,
 		Genomes:    make([]neat.Genome, cfg.PopulationSize()),
 	}
 
-	// Create the genomes
-	wg := new(sync.WaitGroup)
+	// Create the genomes. This is done serially so that IDs and random values are assigned in a
+	// repeatable order.
 	for i := 0; i < len(next.Genomes); i++ {
-		wg.Add(1)
-		go func(i int) {
-			genome := createSeed(ctx, cfg)
-			genome.ID = ctx.NextID()
-			genome.SpeciesIdx = 0
-			next.Genomes[i] = genome
-			wg.Done()
-		}(i)
+		genome := createSeed(ctx, cfg)
+		genome.ID = ctx.NextID()
+		genome.SpeciesIdx = 0
+		next.Genomes[i] = genome
+		if h != nil {
+			h.Record(genome)
+		}
 	}
-	wg.Wait()
 
 	// Create the initial species
-	next.Species[0] = neat.Species{Example: next.Genomes[0]}
+	next.Species[0] = neat.Species{ID: next.Genomes[0].ID, Example: next.Genomes[0]}
 
 	return
 }
@@ -78,11 +112,33 @@ func createPool(curr neat.Population) (pool map[int]Improvements) {
 	return
 }
 
+// Returns the species' indexes in the pool in ascending order. Iterating the pool in this order,
+// rather than Go's random map order, keeps the generation repeatable.
+func poolKeys(pool map[int]Improvements) []int {
+	keys := make([]int, 0, len(pool))
+	for idx := range pool {
+		keys = append(keys, idx)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Returns the species' indexes of the offspring counts in ascending order
+func countKeys(cnts map[int]int) []int {
+	keys := make([]int, 0, len(cnts))
+	for idx := range cnts {
+		keys = append(keys, idx)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
 // Removes stagnant species from the pool of possible parents. Allow the species with the most fit
-// genome to continue past stagnation.
+// genome to continue past stagnation. A species stagnates while the progress of its members does not
+// exceed the best progress it has made.
 //
 // TODO: Add setting so that user can control whether species with best is removed if stagnant for too long
-func purgeSpecies(cfg ClassicSettings, species []neat.Species, pool map[int]Improvements) {
+func purgeSpecies(cfg ClassicSettings, species []neat.Species, pool map[int]Improvements, progress func(Improvements) float64) {
 
 	// Update the species' adjusted fitness and stagnation level and note the best
 	max := -1.0
@@ -92,7 +148,7 @@ func purgeSpecies(cfg ClassicSettings, species []neat.Species, pool map[int]Impr
 
 		// Update stagnation and fitness
 		l := pool[i]
-		f := l.Improvement()
+		f := progress(l)
 		if f <= s.Improvement {
 			species[i].Stagnation += 1
 		} else {
@@ -125,12 +181,17 @@ func purgeSpecies(cfg ClassicSettings, species []neat.Species, pool map[int]Impr
 	}
 }
 
-func createOffspring(ctx neat.Context, cfg ClassicSettings, cross bool, rng *rand.Rand, pool map[int]Improvements, cnts map[int]int, next *neat.Population) (err error) {
+// Creates the offspring of each species. Each offspring records its parents and the operations
+// which created it and, if a genealogy is given, is recorded in it.
+func createOffspring(ctx neat.Context, cfg ClassicSettings, cross bool, rng *rand.Rand, pool map[int]Improvements, cnts map[int]int, next *neat.Population, h *neat.Genealogy) (err error) {
 	var child neat.Genome
-	for idx, cnt := range cnts {
+	for _, idx := range countKeys(cnts) {
+		cnt := cnts[idx]
 		l := pool[idx]
 		for i := 0; i < cnt; i++ {
 			p1, p2 := pickParents(cfg, cross, rng, l, pool)
+			var ops []string
+			parents := []neat.Genome{p1}
 			if p1.ID == p2.ID {
 				child = neat.CopyGenome(p1)
 			} else {
@@ -138,10 +199,38 @@ func createOffspring(ctx neat.Context, cfg ClassicSettings, cross bool, rng *ran
 				if err != nil {
 					return
 				}
+				ops = append(ops, neat.CrossoverOp)
+				parents = append(parents, p2)
 			}
+			before := neat.CopyGenome(child)
 			child.ID = ctx.NextID()
 			child.Birth = next.Generation
 			err = ctx.Mutator().Mutate(&child)
+			var repaired bool
+			if cfg.RepairOffspring() {
+				mutated := neat.CopyGenome(child)
+				if child.Repair(cfg.RequireFeedForward()) != nil {
+					id := child.ID
+					child = neat.CopyGenome(p1)
+					child.ID = id
+					child.Birth = next.Generation
+					ops, before, parents = nil, p1, parents[:1]
+					repaired = true
+				} else {
+					repaired = !reflect.DeepEqual(mutated.Nodes, child.Nodes) || !reflect.DeepEqual(mutated.Conns, child.Conns)
+				}
+			}
+			child.Parents = make([]int, len(parents))
+			for j, p := range parents {
+				child.Parents[j] = p.ID
+			}
+			child.Mutations = append(ops, neat.MutationsBetween(before, child)...)
+			if repaired {
+				child.Mutations = append(child.Mutations, neat.RepairOp)
+			}
+			if h != nil {
+				h.Record(child, parents...)
+			}
 			next.Genomes = append(next.Genomes, child)
 		}
 	}
@@ -158,9 +247,8 @@ func pickParents(cfg ClassicSettings, cross bool, rng *rand.Rand, species Improv
 		p2 = p1
 	} else {
 		if rng.Float64() < cfg.InterspeciesMatingRate() { // Offspring could come from any species
-			for _, l := range pool {
-				species = l
-			}
+			keys := poolKeys(pool)
+			species = pool[keys[rng.Intn(len(keys))]]
 		}
 		i = rng.Intn(len(species))
 		p2 = species[i]
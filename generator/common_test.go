@@ -0,0 +1,115 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package generator
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+// Mutator which applies a fixed change to the genome
+type mutateFunc func(*neat.Genome)
+
+func (m mutateFunc) Mutate(g *neat.Genome) error {
+	m(g)
+	return nil
+}
+
+type offspringContext struct {
+	neat.Context
+	mut neat.Mutator
+	id  int
+}
+
+func (c *offspringContext) Mutator() neat.Mutator { return c.mut }
+func (c *offspringContext) NextID() int {
+	c.id += 1
+	return c.id
+}
+
+type offspringSettings struct {
+	ClassicSettings
+}
+
+func (s offspringSettings) RepairOffspring() bool          { return true }
+func (s offspringSettings) RequireFeedForward() bool       { return true }
+func (s offspringSettings) MutateOnlyProbability() float64 { return 1 }
+
+// Returns a child of a valid parent after the mutation
+func offspring(t *testing.T, m mutateFunc) neat.Genome {
+	p := neat.Genome{ID: 1, Nodes: neat.Nodes{
+		1: {Innovation: 1, NeuronType: neat.Input, X: 0},
+		2: {Innovation: 2, NeuronType: neat.Output, X: 1, Y: 1},
+	}, Conns: neat.Connections{
+		3: {Innovation: 3, Source: 1, Target: 2, Weight: 1, Enabled: true},
+	}}
+	ctx := &offspringContext{mut: m, id: 1}
+	next := &neat.Population{Generation: 1}
+	pool := map[int]Improvements{0: {p}}
+	h := neat.NewGenealogy()
+	err := createOffspring(ctx, offspringSettings{}, false, rand.New(rand.NewSource(0)), pool, map[int]int{0: 1}, next, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child := next.Genomes[0]
+	if a, ok := h.Ancestor(child.ID); !ok || !reflect.DeepEqual(a.Mutations, child.Mutations) {
+		t.Errorf("Genealogy recorded %+v for genome with mutations %v", a, child.Mutations)
+	}
+	return child
+}
+
+func TestOffspringRecordsRepair(t *testing.T) {
+
+	// A valid mutation needs no repair
+	child := offspring(t, func(g *neat.Genome) {
+		c := g.Conns[3]
+		c.Weight = 2
+		g.Conns[3] = c
+	})
+	if !reflect.DeepEqual(child.Mutations, []string{neat.WeightOp}) {
+		t.Errorf("Expected only a weight mutation but got %v", child.Mutations)
+	}
+
+	// A connection to a missing node is removed by the repair
+	child = offspring(t, func(g *neat.Genome) {
+		g.Conns[4] = neat.Connection{Innovation: 4, Source: 1, Target: 9, Enabled: true}
+	})
+	if !reflect.DeepEqual(child.Mutations, []string{neat.RepairOp}) || len(child.Conns) != 1 {
+		t.Errorf("Expected the repair to be recorded but got %v", child.Mutations)
+	}
+
+	// A genome without its output cannot be repaired and is replaced by its parent
+	child = offspring(t, func(g *neat.Genome) {
+		delete(g.Nodes, 2)
+	})
+	if !reflect.DeepEqual(child.Mutations, []string{neat.RepairOp}) || len(child.Nodes) != 2 || !reflect.DeepEqual(child.Parents, []int{1}) {
+		t.Errorf("Expected the child to be a repaired copy of its parent but got %v", child)
+	}
+}
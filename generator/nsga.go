@@ -0,0 +1,238 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package generator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/rqme/neat"
+)
+
+// Generator which selects using the Pareto dominance of the genomes' objectives, as in NSGA-II,
+// while keeping NEAT's speciation and explicit fitness sharing.
+//
+// The population is sorted into non-dominated fronts and, within each front, by crowding distance.
+// Each genome's improvement is then replaced by a score which orders the genomes first by front and
+// then by crowding distance. A genome in the first of F fronts scores between F and F + 0.5, one in
+// the second between F - 1 and F - 0.5 and so on, so that no genome in a later front outscores one
+// in an earlier front. The classic generator then uses the scores to allot offspring to the species,
+// choose the parents and preserve the elites.
+//
+// As the scores depend on the number of fronts, they cannot be compared from one generation to the
+// next. A species' progress is instead measured by the best front its genomes reach, 1 / (1 + r)
+// for the zero-based front r, and the species stagnates until it reaches a better front than it has
+// before.
+//
+// Genomes without objectives are scored using their fitness as the only objective.
+//
+// See Deb, K., Pratap, A., Agarwal, S. & Meyarivan, T. (2002). A fast and elitist multiobjective
+// genetic algorithm: NSGA-II. IEEE Transactions on Evolutionary Computation, 6(2), 182-197.
+type NSGA struct {
+	Classic
+}
+
+func NewNSGA(cfg ClassicSettings) *NSGA {
+	return &NSGA{Classic: Classic{ClassicSettings: cfg}}
+}
+
+func (g *NSGA) Generate(curr neat.Population) (next neat.Population, err error) {
+	if len(curr.Genomes) == 0 {
+		return generateFirst(g.ctx, g.ClassicSettings, g.tracked(g.ClassicSettings))
+	}
+
+	// Score a copy of the genomes so the current population is left untouched
+	gs := make([]neat.Genome, len(curr.Genomes))
+	copy(gs, curr.Genomes)
+	fronts, err := scorePareto(gs)
+	if err != nil {
+		return
+	}
+	curr.Genomes = gs
+	return g.Classic.generateNext(curr, frontProgress(gs, fronts))
+}
+
+// Returns a measure of a species' progress from the best front of its genomes, 1 / (1 + r) for the
+// zero-based front r
+func frontProgress(genomes []neat.Genome, fronts [][]int) func(Improvements) float64 {
+	ranks := make(map[int]int, len(genomes))
+	for r, front := range fronts {
+		for _, i := range front {
+			ranks[genomes[i].ID] = r
+		}
+	}
+	return func(l Improvements) float64 {
+		best := -1
+		for _, g := range l {
+			if r := ranks[g.ID]; best < 0 || r < best {
+				best = r
+			}
+		}
+		if best < 0 {
+			return 0
+		}
+		return 1 / (1 + float64(best))
+	}
+}
+
+// Returns the objectives of the genome, using the fitness if there are none
+func objectives(g neat.Genome) []float64 {
+	if len(g.Objectives) == 0 {
+		return []float64{g.Fitness}
+	}
+	return g.Objectives
+}
+
+// Returns true if the first set of objectives dominates the second: it is at least as good in
+// every objective and better in at least one
+func dominates(a, b []float64) bool {
+	better := false
+	for i := range a {
+		if a[i] < b[i] {
+			return false
+		} else if a[i] > b[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// Sorts the genomes into non-dominated fronts, returning the genomes' indexes in each front. The
+// first front contains the genomes which no other genome dominates.
+func ParetoFronts(genomes []neat.Genome) (fronts [][]int, err error) {
+	n := len(genomes)
+	objs := make([][]float64, n)
+	for i, g := range genomes {
+		objs[i] = objectives(g)
+		if len(objs[i]) != len(objs[0]) {
+			return nil, fmt.Errorf("generator.ParetoFronts - Genome %d has %d objectives, expected %d", g.ID, len(objs[i]), len(objs[0]))
+		}
+	}
+
+	// Determine who dominates whom
+	dominated := make([][]int, n) // genomes dominated by each genome
+	counts := make([]int, n)      // number of genomes dominating each genome
+	front := make([]int, 0, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; j++ {
+			if i == j {
+				continue
+			}
+			if dominates(objs[i], objs[j]) {
+				dominated[i] = append(dominated[i], j)
+			} else if dominates(objs[j], objs[i]) {
+				counts[i] += 1
+			}
+		}
+		if counts[i] == 0 {
+			front = append(front, i)
+		}
+	}
+
+	// Peel off the fronts
+	for len(front) > 0 {
+		fronts = append(fronts, front)
+		next := make([]int, 0, len(front))
+		for _, i := range front {
+			for _, j := range dominated[i] {
+				counts[j] -= 1
+				if counts[j] == 0 {
+					next = append(next, j)
+				}
+			}
+		}
+		sort.Ints(next)
+		front = next
+	}
+	return
+}
+
+// Returns the genomes in the first non-dominated front
+func ParetoFront(genomes []neat.Genome) ([]neat.Genome, error) {
+	fronts, err := ParetoFronts(genomes)
+	if err != nil || len(fronts) == 0 {
+		return nil, err
+	}
+	pf := make([]neat.Genome, len(fronts[0]))
+	for i, idx := range fronts[0] {
+		pf[i] = genomes[idx]
+	}
+	return pf, nil
+}
+
+// Returns the crowding distance of each genome in the front. Genomes at the boundary of any
+// objective are given an infinite distance.
+func crowding(genomes []neat.Genome, front []int) []float64 {
+	d := make([]float64, len(front))
+	if len(front) < 3 {
+		for i := range d {
+			d[i] = math.Inf(1)
+		}
+		return d
+	}
+	order := make([]int, len(front)) // positions within the front
+	for m := range objectives(genomes[front[0]]) {
+		for i := range order {
+			order[i] = i
+		}
+		val := func(i int) float64 { return objectives(genomes[front[order[i]]])[m] }
+		sort.SliceStable(order, func(a, b int) bool { return val(a) < val(b) })
+		min, max := val(0), val(len(order)-1)
+		d[order[0]] = math.Inf(1)
+		d[order[len(order)-1]] = math.Inf(1)
+		if max == min {
+			continue
+		}
+		for i := 1; i < len(order)-1; i++ {
+			d[order[i]] += (val(i+1) - val(i-1)) / (max - min)
+		}
+	}
+	return d
+}
+
+// Replaces the genomes' improvement with a score combining their front and crowding distance and
+// returns the fronts
+func scorePareto(genomes []neat.Genome) (fronts [][]int, err error) {
+	if fronts, err = ParetoFronts(genomes); err != nil {
+		return
+	}
+	for r, front := range fronts {
+		base := float64(len(fronts) - r)
+		for i, d := range crowding(genomes, front) {
+			// Normalize the distance into [0, 0.5] so the fronts cannot overlap
+			var c float64
+			if math.IsInf(d, 1) {
+				c = 0.5
+			} else {
+				c = 0.5 * d / (1 + d)
+			}
+			genomes[front[i]].Improvement = base + c
+		}
+	}
+	return
+}
@@ -0,0 +1,223 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+package generator
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+// Returns genomes with the objectives, identified by their position
+func withObjectives(objs ...[]float64) []neat.Genome {
+	gs := make([]neat.Genome, len(objs))
+	for i, o := range objs {
+		gs[i] = neat.Genome{ID: i, Objectives: o}
+	}
+	return gs
+}
+
+func TestDominates(t *testing.T) {
+	var cases = []struct {
+		a, b     []float64
+		expected bool
+	}{
+		{[]float64{2, 2}, []float64{1, 1}, true},
+		{[]float64{2, 1}, []float64{1, 1}, true},
+		{[]float64{1, 1}, []float64{1, 1}, false},
+		{[]float64{2, 0}, []float64{1, 1}, false},
+		{[]float64{1, 1}, []float64{2, 1}, false},
+	}
+	for _, c := range cases {
+		if actual := dominates(c.a, c.b); actual != c.expected {
+			t.Errorf("dominates(%v, %v): expected %v, got %v", c.a, c.b, c.expected, actual)
+		}
+	}
+}
+
+func TestParetoFronts(t *testing.T) {
+	var cases = []struct {
+		desc     string
+		genomes  []neat.Genome
+		expected [][]int
+	}{
+		{"empty", nil, nil},
+		{"chain", withObjectives([]float64{1, 1}, []float64{3, 3}, []float64{2, 2}), [][]int{{1}, {2}, {0}}},
+		{"trade-off", withObjectives([]float64{1, 3}, []float64{3, 1}, []float64{2, 2}, []float64{1, 1}), [][]int{{0, 1, 2}, {3}}},
+		{"ties", withObjectives([]float64{2, 2}, []float64{1, 1}, []float64{2, 2}, []float64{1, 1}), [][]int{{0, 2}, {1, 3}}},
+		{"fitness", []neat.Genome{{ID: 0, Fitness: 1}, {ID: 1, Fitness: 3}, {ID: 2, Fitness: 3}}, [][]int{{1, 2}, {0}}},
+	}
+	for _, c := range cases {
+		actual, err := ParetoFronts(c.genomes)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.desc, err)
+		} else if !reflect.DeepEqual(actual, c.expected) {
+			t.Errorf("%s: expected fronts %v, got %v", c.desc, c.expected, actual)
+		}
+	}
+}
+
+func TestParetoFrontsRejectsMismatchedObjectives(t *testing.T) {
+	_, err := ParetoFronts(withObjectives([]float64{1, 2}, []float64{1}))
+	if err == nil || !strings.Contains(err.Error(), "Genome 1 has 1 objectives, expected 2") {
+		t.Errorf("Expected an error for the mismatched objectives, got %v", err)
+	}
+}
+
+func TestCrowding(t *testing.T) {
+	inf := math.Inf(1)
+	var cases = []struct {
+		desc     string
+		genomes  []neat.Genome
+		expected []float64
+	}{
+		{"small", withObjectives([]float64{1, 2}, []float64{2, 1}), []float64{inf, inf}},
+		{"even", withObjectives([]float64{1, 4}, []float64{2, 3}, []float64{3, 2}, []float64{4, 1}), []float64{inf, 4.0 / 3, 4.0 / 3, inf}},
+		{"uneven", withObjectives([]float64{0, 4}, []float64{1, 3}, []float64{4, 0}), []float64{inf, 2, inf}},
+		{"unordered", withObjectives([]float64{3, 2}, []float64{1, 4}, []float64{4, 1}, []float64{2, 3}), []float64{4.0 / 3, inf, inf, 4.0 / 3}},
+		{"flat", withObjectives([]float64{1, 5}, []float64{2, 5}, []float64{3, 5}), []float64{inf, 1, inf}},
+	}
+	for _, c := range cases {
+		front := make([]int, len(c.genomes))
+		for i := range front {
+			front[i] = i
+		}
+		actual := crowding(c.genomes, front)
+		for i := range actual {
+			if math.Abs(actual[i]-c.expected[i]) > 1e-9 && actual[i] != c.expected[i] {
+				t.Errorf("%s: expected distances %v, got %v", c.desc, c.expected, actual)
+				break
+			}
+		}
+	}
+}
+
+func TestScoreParetoOrdersFronts(t *testing.T) {
+	gs := withObjectives([]float64{1, 3}, []float64{3, 1}, []float64{2, 2}, []float64{1, 1}, []float64{0, 0})
+	fronts, err := scorePareto(gs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for r, front := range fronts {
+		lo := float64(len(fronts) - r)
+		for _, i := range front {
+			if s := gs[i].Improvement; s < lo || s > lo+0.5 {
+				t.Errorf("Genome %d in front %d scored %f, expected within [%f, %f]", i, r, s, lo, lo+0.5)
+			}
+		}
+	}
+
+	// The boundaries of the first front are the most crowded-out, and so score highest
+	if gs[0].Improvement != 3.5 || gs[1].Improvement != 3.5 || gs[2].Improvement >= 3.5 {
+		t.Errorf("Unexpected scores for the first front: %f, %f and %f", gs[0].Improvement, gs[1].Improvement, gs[2].Improvement)
+	}
+}
+
+type nsgaSettings struct {
+	ClassicSettings
+}
+
+func (s nsgaSettings) PopulationSize() int        { return 20 }
+func (s nsgaSettings) MaxStagnation() int         { return 1 }
+func (s nsgaSettings) SurvivalThreshold() float64 { return 1 }
+
+// Scores the genomes and returns the pool of each species and its measure of progress
+func nsgaPool(t *testing.T, gs []neat.Genome) (map[int]Improvements, func(Improvements) float64) {
+	fronts, err := scorePareto(gs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return createPool(neat.Population{Genomes: gs}), frontProgress(gs, fronts)
+}
+
+func TestNSGAStagnationFollowsFronts(t *testing.T) {
+	cfg := nsgaSettings{}
+	species := []neat.Species{{ID: 1}, {ID: 2}}
+
+	// Species 0 holds the second of two fronts and then the second of four. Its score rises with the
+	// number of fronts but it has reached no better front, so it stagnates.
+	var cases = []struct {
+		genomes    []neat.Genome
+		stagnation int
+	}{
+		{[]neat.Genome{
+			{ID: 1, SpeciesIdx: 0, Objectives: []float64{1, 1}},
+			{ID: 2, SpeciesIdx: 1, Objectives: []float64{2, 2}},
+		}, 0},
+		{[]neat.Genome{
+			{ID: 3, SpeciesIdx: 0, Objectives: []float64{3, 3}},
+			{ID: 4, SpeciesIdx: 1, Objectives: []float64{4, 4}},
+			{ID: 5, SpeciesIdx: 1, Objectives: []float64{2, 2}},
+			{ID: 6, SpeciesIdx: 1, Objectives: []float64{1, 1}},
+		}, 1},
+		{[]neat.Genome{
+			{ID: 7, SpeciesIdx: 0, Objectives: []float64{5, 1}},
+			{ID: 8, SpeciesIdx: 1, Objectives: []float64{1, 5}},
+		}, 0},
+	}
+	for i, c := range cases {
+		pool, progress := nsgaPool(t, c.genomes)
+		purgeSpecies(cfg, species, pool, progress)
+		if species[0].Stagnation != c.stagnation {
+			t.Errorf("Generation %d: expected stagnation %d, got %d", i, c.stagnation, species[0].Stagnation)
+		}
+	}
+}
+
+func TestNSGAOffspringCounts(t *testing.T) {
+	var cases = []struct {
+		desc    string
+		genomes []neat.Genome
+		more    int // Species expected to receive more offspring
+	}{
+		{"first front", []neat.Genome{
+			{ID: 1, SpeciesIdx: 0, Objectives: []float64{2, 1}},
+			{ID: 2, SpeciesIdx: 0, Objectives: []float64{1, 2}},
+			{ID: 3, SpeciesIdx: 1, Objectives: []float64{0.5, 0.5}},
+			{ID: 4, SpeciesIdx: 1, Objectives: []float64{0, 0}},
+		}, 0},
+		{"dominated", []neat.Genome{
+			{ID: 1, SpeciesIdx: 0, Objectives: []float64{0, 1}},
+			{ID: 2, SpeciesIdx: 1, Objectives: []float64{3, 3}},
+			{ID: 3, SpeciesIdx: 1, Objectives: []float64{2, 2}},
+		}, 1},
+	}
+	for _, c := range cases {
+		pool, _ := nsgaPool(t, c.genomes)
+		species := []neat.Species{{ID: 1, Age: 15}, {ID: 2, Age: 15}}
+		cnts := createCounts(nsgaSettings{}, rand.New(rand.NewSource(1)), species, pool)
+		if cnts[0]+cnts[1] != 18 {
+			t.Errorf("%s: expected 18 offspring, got %v", c.desc, cnts)
+		}
+		if cnts[c.more] <= cnts[1-c.more] {
+			t.Errorf("%s: expected species %d to receive more offspring, got %v", c.desc, c.more, cnts)
+		}
+	}
+}
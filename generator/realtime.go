@@ -41,6 +41,8 @@ type RealTimeSettings interface {
 
 type RealTime struct {
 	RealTimeSettings
+	neat.NullListener
+	lineage
 	ctx neat.Context
 
 	tick    int
@@ -58,9 +60,24 @@ func (g *RealTime) SetCrossover(v bool) error {
 	return nil
 }
 
+// Returns the generator's internal state so that it can be archived. The genealogy is included
+// whole, so it grows with each generation unless PruneGenealogy is set.
+func (g *RealTime) Checkpoint() interface{} {
+	return &struct {
+		Tick      *int
+		Cross     *bool
+		Genealogy *neat.Genealogy
+	}{&g.tick, &g.cross, g.Genealogy()}
+}
+
+// Records the fitness of the evaluated genomes in the genealogy
+func (g *RealTime) GenerationEnded(e *neat.Experiment) {
+	g.update(g.RealTimeSettings, e.Population())
+}
+
 func (g *RealTime) Generate(curr neat.Population) (next neat.Population, err error) {
 	if len(curr.Genomes) == 0 {
-		next, err = generateFirst(g.ctx, g.RealTimeSettings)
+		next, err = generateFirst(g.ctx, g.RealTimeSettings, g.tracked(g.RealTimeSettings))
 	} else {
 		next, err = g.generateNext(curr)
 	}
@@ -93,23 +110,28 @@ func (g *RealTime) generateNext(curr neat.Population) (next neat.Population, err
 	}
 	// 2. Re-estimate F for all species.
 	ftot := g.reestimate(pool)
-	purgeSpecies(g.RealTimeSettings, curr.Species, pool)
+	purgeSpecies(g.RealTimeSettings, curr.Species, pool, Improvements.Improvement)
 
 	// 3. Choose a parent species to create the new offspring
-	rng := rand.New(rand.NewSource(rand.Int63()))
+	rng := g.ctx.Rand()
 	cnts := make(map[int]int, 1)
 	sidx := g.pickSpecies(pool, ftot, rng)
 	cnts[sidx] = 1
 
 	next.Generation = curr.Generation + 1
 	next.Genomes = make([]neat.Genome, 0, len(curr.Genomes))
-	if err = createOffspring(g.ctx, g.RealTimeSettings, g.cross, rng, pool, cnts, &next); err != nil {
+	if err = createOffspring(g.ctx, g.RealTimeSettings, g.cross, rng, pool, cnts, &next, g.tracked(g.RealTimeSettings)); err != nil {
 		return
 	}
 
 	// 4. Adjust compatibility theshold Ct dynamically and reassign all agents to species
-	for _, list := range pool {
-		next.Genomes = append(next.Genomes, list...)
+	for _, i := range poolKeys(pool) {
+		next.Genomes = append(next.Genomes, pool[i]...)
+	}
+	if ph, ok := g.ctx.Speciater().(neat.Populatable); ok {
+		if err = ph.SetPopulation(curr); err != nil {
+			return
+		}
 	}
 	next.Species, err = g.ctx.Speciater().Speciate(curr.Species, next.Genomes)
 
@@ -122,7 +144,8 @@ func (g *RealTime) removeWorst(pool map[int]Improvements, n, m int) bool {
 	var worst float64 = math.Inf(1)
 	var wg, ws int
 	ws = -1
-	for i, list := range pool {
+	for _, i := range poolKeys(pool) {
+		list := pool[i]
 		for j := len(list) - 1; j >= 0; j-- {
 			adj := list[j].Improvement / float64(len(list))
 			if list[j].Birth*n > m && adj < worst {
@@ -145,8 +168,8 @@ func (g *RealTime) removeWorst(pool map[int]Improvements, n, m int) bool {
 // Section 3.1.2 Step 2: Re-estimagting F (Stanley, p.4)
 func (g *RealTime) reestimate(pool map[int]Improvements) float64 {
 	ftot := 0.0
-	for _, list := range pool {
-		ftot += list.Improvement()
+	for _, i := range poolKeys(pool) {
+		ftot += pool[i].Improvement()
 	}
 	return ftot
 }
@@ -155,8 +178,8 @@ func (g *RealTime) reestimate(pool map[int]Improvements) float64 {
 func (g *RealTime) pickSpecies(pool map[int]Improvements, ftot float64, rng *rand.Rand) int {
 	ftgt := rng.Float64() * ftot
 	fsum := 0.0
-	for i, list := range pool {
-		fsum += list.Improvement()
+	for _, i := range poolKeys(pool) {
+		fsum += pool[i].Improvement()
 		if fsum >= ftgt {
 			return i
 		}
@@ -27,8 +27,6 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package generator
 
 import (
-	"math/rand"
-
 	"github.com/rqme/neat"
 )
 
@@ -62,7 +60,7 @@ func createSeed(ctx neat.Context, cfg ClassicSettings) (adam neat.Genome) {
 		nodes = append(nodes, node)
 	}
 
-	rng := rand.New(rand.NewSource(rand.Int63()))
+	rng := ctx.Rand()
 	adam.Conns = make(map[int]neat.Connection, (1+inputs)*outputs)
 	for i := 0; i < 1+inputs; i++ {
 		for j := 0; j < outputs; j++ {
@@ -41,7 +41,10 @@ type Genome struct {
 	Traits      []float64   // Trait values
 	Fitness     float64     // Fitness of genome as it relates to the problem itself
 	Improvement float64     // Fitness of genome as it relates to the improvement of the population
+	Objectives  []float64   // Values of each objective if the evaluation was multi-objective
 	Birth       int         // Generation during which this genome was born
+	Parents     []int       // IDs of the genomes from which this genome was created
+	Mutations   []string    // Operations which created this genome from its parents
 }
 
 func (g Genome) Complexity() int { return len(g.Nodes) + len(g.Conns) }
@@ -133,5 +136,17 @@ func CopyGenome(g1 Genome) (g2 Genome) {
 	}
 	g2.Traits = make([]float64, len(g1.Traits))
 	copy(g2.Traits, g1.Traits)
+	if g1.Objectives != nil {
+		g2.Objectives = make([]float64, len(g1.Objectives))
+		copy(g2.Objectives, g1.Objectives)
+	}
+	if g1.Parents != nil {
+		g2.Parents = make([]int, len(g1.Parents))
+		copy(g2.Parents, g1.Parents)
+	}
+	if g1.Mutations != nil {
+		g2.Mutations = make([]string, len(g1.Mutations))
+		copy(g2.Mutations, g1.Mutations)
+	}
 	return
 }
@@ -0,0 +1,113 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package neat
+
+// Listener receives notifications as the experiment progresses. Listeners are called from the
+// goroutine running the experiment and may call Stop on it to end the run early. Embed
+// NullListener to implement only the notifications of interest. Helpers which implement Listener
+// are registered automatically when the experiment's context is set.
+type Listener interface {
+	// Called once the next population is generated, before it is evaluated
+	GenerationStarted(e *Experiment)
+
+	// Called once the population has been evaluated. It is not called for a generation whose search
+	// is canceled, so GenerationStarted may be the last notification of a canceled experiment.
+	GenerationEnded(e *Experiment)
+
+	// Called when a new species appears in the population
+	SpeciesCreated(e *Experiment, s Species)
+
+	// Called when a species no longer has any members in the population
+	SpeciesExtinct(e *Experiment, s Species)
+
+	// Called when a genome with a better fitness than the current best is found
+	BestImproved(e *Experiment, g Genome)
+
+	// Called for each genome whose evaluation returned an error
+	EvaluationFailed(e *Experiment, id int, err error)
+
+	// Called when an evaluation signals that the stop condition was met
+	Stopped(e *Experiment)
+}
+
+// Listener which ignores all notifications
+type NullListener struct{}
+
+func (l NullListener) GenerationStarted(e *Experiment)                   {}
+func (l NullListener) GenerationEnded(e *Experiment)                     {}
+func (l NullListener) SpeciesCreated(e *Experiment, s Species)           {}
+func (l NullListener) SpeciesExtinct(e *Experiment, s Species)           {}
+func (l NullListener) BestImproved(e *Experiment, g Genome)              {}
+func (l NullListener) EvaluationFailed(e *Experiment, id int, err error) {}
+func (l NullListener) Stopped(e *Experiment)                             {}
+
+// Registers a listener with the experiment
+func (e *Experiment) AddListener(l Listener) {
+	e.listeners = append(e.listeners, l)
+}
+
+// Asks the experiment to stop once the current iteration completes. Unlike meeting the stop
+// condition, this does not mark the experiment as stopped.
+func (e *Experiment) Stop() {
+	e.halted = true
+}
+
+// Calls the function for each listener, starting with the helpers
+func (e *Experiment) notify(f func(Listener)) {
+	for _, l := range e.hooks {
+		f(l)
+	}
+	for _, l := range e.listeners {
+		f(l)
+	}
+}
+
+// Notifies the listeners of the species created and made extinct between the populations.
+// Species are identified by their IDs.
+func notifySpecies(e *Experiment, prev, next []Species) {
+	if len(e.hooks)+len(e.listeners) == 0 {
+		return
+	}
+	ids := make(map[int]bool, len(prev))
+	for _, s := range prev {
+		ids[s.ID] = true
+	}
+	for _, s := range next {
+		if ids[s.ID] {
+			delete(ids, s.ID)
+		} else {
+			s := s
+			e.notify(func(l Listener) { l.SpeciesCreated(e, s) })
+		}
+	}
+	for _, s := range prev {
+		if ids[s.ID] {
+			s := s
+			e.notify(func(l Listener) { l.SpeciesExtinct(e, s) })
+		}
+	}
+}
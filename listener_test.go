@@ -0,0 +1,129 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+package neat
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// Listener which records the notifications it receives
+type recordingListener struct {
+	events []string
+	stopAt int // Generation after which to stop the experiment. If 0, it is not stopped.
+}
+
+func (l *recordingListener) add(f string, args ...interface{}) {
+	l.events = append(l.events, fmt.Sprintf(f, args...))
+}
+
+func (l *recordingListener) GenerationStarted(e *Experiment) {
+	l.add("started %d", e.Population().Generation)
+}
+func (l *recordingListener) GenerationEnded(e *Experiment) {
+	l.add("ended %d", e.Population().Generation)
+	if e.Population().Generation == l.stopAt {
+		e.Stop()
+	}
+}
+func (l *recordingListener) SpeciesCreated(e *Experiment, s Species) { l.add("created %d", s.ID) }
+func (l *recordingListener) SpeciesExtinct(e *Experiment, s Species) { l.add("extinct %d", s.ID) }
+func (l *recordingListener) BestImproved(e *Experiment, g Genome)    { l.add("best %d", g.ID) }
+func (l *recordingListener) EvaluationFailed(e *Experiment, id int, err error) {
+	l.add("failed %d", id)
+}
+func (l *recordingListener) Stopped(e *Experiment) { l.add("stopped") }
+
+// Evaluator which fails for one genome
+type failingEvaluator struct{ id int }
+
+func (f failingEvaluator) Evaluate(p Phenome) Result {
+	r := activatingEvaluator{}.Evaluate(p)
+	if p.ID() == f.id {
+		return testResult{id: p.ID(), err: errors.New("failed")}
+	}
+	return r
+}
+
+func newListenedExperiment(evl Evaluator, iterations int) (*Experiment, *recordingListener) {
+	gen := &countingGenerator{}
+	ctx := &testContext{arc: &recordingArchiver{gen: gen}, dec: countingDecoder{}, evl: evl,
+		gen: gen, src: orderedSearcher{evl}, vis: nullVisualizer{}}
+	e := &Experiment{ExperimentSettings: testSettings{iterations: iterations}}
+	e.SetContext(ctx)
+	l := &recordingListener{}
+	e.AddListener(l)
+	return e, l
+}
+
+func TestListenerEventOrder(t *testing.T) {
+	e, l := newListenedExperiment(failingEvaluator{id: 3}, 3)
+	if err := Run(e); err == nil {
+		t.Fatalf("Expected the failed evaluation to end the run")
+	}
+
+	// The best is only reported for a generation which evaluated without errors
+	expected := []string{"started 1", "best 1", "ended 1", "started 2", "failed 3"}
+	if !reflect.DeepEqual(l.events, expected) {
+		t.Errorf("Expected events %v, got %v", expected, l.events)
+	}
+}
+
+func TestListenerStopsAfterGeneration(t *testing.T) {
+	e, l := newListenedExperiment(activatingEvaluator{}, 5)
+	l.stopAt = 2
+	if err := Run(e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Iteration() != 2 || e.Stopped() {
+		t.Errorf("Expected to halt after 2 iterations without being stopped, got %d and %v", e.Iteration(), e.Stopped())
+	}
+	if last := l.events[len(l.events)-1]; last != "ended 2" {
+		t.Errorf("Expected the generation to end before halting, last event was %q", last)
+	}
+
+	// A halted experiment continues when run again
+	l.stopAt = 0
+	if err := Run(e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Iteration() != 5 {
+		t.Errorf("Expected to continue to 5 iterations, got %d", e.Iteration())
+	}
+}
+
+func TestNotifySpeciesMatchesByID(t *testing.T) {
+	e, l := newListenedExperiment(activatingEvaluator{}, 1)
+	prev := []Species{{ID: 1}, {ID: 2, Age: 3}, {ID: 3}}
+	next := []Species{{ID: 4}, {ID: 2, Age: 4, Example: Genome{ID: 9}}, {ID: 1, Stagnation: 1}}
+	notifySpecies(e, prev, next)
+	expected := []string{"created 4", "extinct 3"}
+	if !reflect.DeepEqual(l.events, expected) {
+		t.Errorf("Expected events %v, got %v", expected, l.events)
+	}
+}
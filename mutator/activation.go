@@ -27,27 +27,105 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package mutator
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/rqme/neat"
+)
 
-	"math/rand"
+// Weighted palettes of activation types
+var (
+	// Activations suited to networks which are evaluated directly
+	DirectPalette = map[neat.ActivationType]float64{
+		neat.SteependSigmoid: 1, neat.Sigmoid: 1, neat.Tanh: 1, neat.InverseAbs: 1,
+	}
+
+	// Activations suited to CPPNs, favouring those which express symmetry and repetition
+	CPPNPalette = map[neat.ActivationType]float64{
+		neat.Gaussian: 2, neat.Sine: 2, neat.Abs: 1, neat.Tanh: 1, neat.Sigmoid: 1,
+		neat.Step: 0.5, neat.LinearClipped: 1,
+	}
 )
 
 type ActivationSettings interface {
 	MutateActivationProbability() float64 // Probability that the node's activation will be mutated
+
+	// Weighted palette of the activations which may be chosen. If empty, neat.Activations is used
+	// with equal weights.
+	ActivationPalette() map[neat.ActivationType]float64
+
+	// Allow the output nodes' activations to be mutated. Only activations with the same range as
+	// the current one are chosen so that the outputs remain usable.
+	MutateOutputActivation() bool
 }
 
 type Activation struct {
 	ActivationSettings
+	ctx neat.Context
+}
+
+func (m *Activation) SetContext(x neat.Context) error {
+	m.ctx = x
+	return nil
 }
 
-// Mutates a genome's weights
+// Mutates a genome's activations
 func (m Activation) Mutate(g *neat.Genome) error {
-	rng := rand.New(rand.NewSource(rand.Int63()))
-	for k, node := range g.Nodes {
-		if node.NeuronType == neat.Hidden {
+
+	// Build the palette, ordered by type so the choices are repeatable
+	ws := m.ActivationPalette()
+	if len(ws) == 0 {
+		ws = make(map[neat.ActivationType]float64, len(neat.Activations))
+		for _, a := range neat.Activations {
+			ws[a] = 1
+		}
+	}
+	types := make([]neat.ActivationType, 0, len(ws))
+	for a, w := range ws {
+		if _, ok := a.Func(); !ok {
+			return fmt.Errorf("mutator.Activation.Mutate - Unknown activation type %d in palette", byte(a))
+		}
+		if w > 0 {
+			types = append(types, a)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+
+	rng := neat.Rand(m.ctx)
+	nodes, _ := g.GenesByInnovation()
+	for _, node := range nodes {
+		if node.NeuronType == neat.Hidden || (node.NeuronType == neat.Output && m.MutateOutputActivation()) {
 			if rng.Float64() < m.MutateActivationProbability() {
-				node.ActivationType = neat.Activations[rng.Intn(len(neat.Activations))]
-				g.Nodes[k] = node
+
+				// Collect the candidates and their total weight
+				var tot float64
+				cands := make([]neat.ActivationType, 0, len(types))
+				min, max := node.ActivationType.Range()
+				for _, a := range types {
+					if node.NeuronType == neat.Output {
+						if amin, amax := a.Range(); amin != min || amax != max {
+							continue
+						}
+					}
+					cands = append(cands, a)
+					tot += ws[a]
+				}
+				if len(cands) == 0 {
+					continue
+				}
+
+				// Pick the activation in proportion to its weight
+				x := rng.Float64() * tot
+				a := cands[len(cands)-1]
+				for _, c := range cands {
+					if x < ws[c] {
+						a = c
+						break
+					}
+					x -= ws[c]
+				}
+				node.ActivationType = a
+				g.Nodes[node.Innovation] = node
 			}
 		}
 	}
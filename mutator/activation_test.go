@@ -0,0 +1,133 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package mutator
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+type randContext struct {
+	neat.Context
+	rng *rand.Rand
+}
+
+func (c randContext) Rand() *rand.Rand { return c.rng }
+
+type activationSettings struct {
+	palette map[neat.ActivationType]float64
+	outputs bool
+}
+
+func (s activationSettings) MutateActivationProbability() float64 { return 1 }
+func (s activationSettings) ActivationPalette() map[neat.ActivationType]float64 {
+	return s.palette
+}
+func (s activationSettings) MutateOutputActivation() bool { return s.outputs }
+
+// Returns a genome with an input, the hidden nodes and an output, all using the sigmoid
+func activationGenome(hidden int) neat.Genome {
+	g := neat.Genome{Nodes: make(neat.Nodes, hidden+2)}
+	g.Nodes[1] = neat.Node{Innovation: 1, NeuronType: neat.Input, ActivationType: neat.Direct}
+	g.Nodes[2] = neat.Node{Innovation: 2, NeuronType: neat.Output, ActivationType: neat.Sigmoid}
+	for i := 3; i < hidden+3; i++ {
+		g.Nodes[i] = neat.Node{Innovation: i, NeuronType: neat.Hidden, ActivationType: neat.Sigmoid}
+	}
+	return g
+}
+
+func newActivation(s activationSettings) *Activation {
+	m := &Activation{ActivationSettings: s}
+	m.SetContext(randContext{rng: rand.New(rand.NewSource(1))})
+	return m
+}
+
+func TestActivationFollowsPaletteWeights(t *testing.T) {
+	m := newActivation(activationSettings{palette: map[neat.ActivationType]float64{
+		neat.Gaussian: 3, neat.Sine: 1, neat.Tanh: 0,
+	}})
+	g := activationGenome(4000)
+	if err := m.Mutate(&g); err != nil {
+		t.Fatal(err)
+	}
+	cnts := make(map[neat.ActivationType]int)
+	for _, n := range g.Nodes {
+		if n.NeuronType == neat.Hidden {
+			cnts[n.ActivationType] += 1
+		}
+	}
+	if cnts[neat.Tanh] != 0 || cnts[neat.Sigmoid] != 0 {
+		t.Errorf("Expected only activations with weight, got %v", cnts)
+	}
+	if f := float64(cnts[neat.Gaussian]) / 4000; f < 0.72 || f > 0.78 {
+		t.Errorf("Expected about 3 in 4 Gaussian activations, got %f", f)
+	}
+}
+
+func TestActivationLeavesOutputs(t *testing.T) {
+	m := newActivation(activationSettings{palette: map[neat.ActivationType]float64{neat.Gaussian: 1}})
+	g := activationGenome(1)
+	if err := m.Mutate(&g); err != nil {
+		t.Fatal(err)
+	}
+	if g.Nodes[2].ActivationType != neat.Sigmoid || g.Nodes[1].ActivationType != neat.Direct {
+		t.Errorf("Expected the input and output to keep their activations, got %v and %v",
+			g.Nodes[1].ActivationType, g.Nodes[2].ActivationType)
+	}
+	if g.Nodes[3].ActivationType != neat.Gaussian {
+		t.Errorf("Expected the hidden node to be mutated, got %v", g.Nodes[3].ActivationType)
+	}
+}
+
+func TestActivationKeepsOutputRange(t *testing.T) {
+	m := newActivation(activationSettings{outputs: true, palette: map[neat.ActivationType]float64{
+		neat.Tanh: 1, neat.Gaussian: 1, neat.Step: 1, neat.Abs: 1,
+	}})
+	seen := make(map[neat.ActivationType]bool)
+	for i := 0; i < 100; i++ {
+		g := activationGenome(0)
+		if err := m.Mutate(&g); err != nil {
+			t.Fatal(err)
+		}
+		seen[g.Nodes[2].ActivationType] = true
+	}
+
+	// Only the activations with the sigmoid's range of [0, 1] are chosen
+	if len(seen) != 2 || !seen[neat.Gaussian] || !seen[neat.Step] {
+		t.Errorf("Expected the output to use the Gaussian and step activations, got %v", seen)
+	}
+}
+
+func TestActivationRejectsUnknownTypes(t *testing.T) {
+	m := newActivation(activationSettings{palette: map[neat.ActivationType]float64{neat.ActivationType(99): 1}})
+	g := activationGenome(1)
+	if err := m.Mutate(&g); err == nil {
+		t.Errorf("Expected an error for an unknown activation in the palette")
+	}
+}
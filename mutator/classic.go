@@ -45,8 +45,12 @@ func New(cs ComplexifySettings, ws WeightSettings, ts TraitSettings) *Classic {
 }
 
 func (m *Classic) SetContext(x neat.Context) error {
-	m.ctx = x
-	return m.Complexify.SetContext(x)
+	for _, h := range []neat.Contextable{&m.Complexify, &m.Weight, &m.Trait} {
+		if err := h.SetContext(x); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (c Classic) Mutate(g *neat.Genome) error {
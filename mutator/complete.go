@@ -47,8 +47,12 @@ func NewComplete(ps PhasedSettings, cs ComplexifySettings, ns PruningSettings, w
 }
 
 func (m *Complete) SetContext(x neat.Context) error {
-	m.ctx = x
-	return m.Complexify.SetContext(x)
+	for _, h := range []neat.Contextable{&m.Phased, &m.Weight, &m.Trait, &m.Activation} {
+		if err := h.SetContext(x); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Sets the population
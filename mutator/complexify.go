@@ -38,6 +38,7 @@ type ComplexifySettings interface {
 	AddNodeProbability() float64           // Probablity a node will be added to the genome
 	AddConnProbability() float64           // Probability a connection will be added to the genome
 	HiddenActivation() neat.ActivationType // Activation type to assign to new nodes
+	AllowRecurrent() bool                  // Allow recurrent and self-looping connections to be added
 }
 
 type Complexify struct {
@@ -52,7 +53,7 @@ func (m *Complexify) SetContext(x neat.Context) error {
 
 // Mutates a genome's weights
 func (m *Complexify) Mutate(g *neat.Genome) error {
-	rng := rand.New(rand.NewSource(rand.Int63()))
+	rng := neat.Rand(m.ctx)
 	if rng.Float64() < m.AddNodeProbability() {
 		m.addNode(rng, g)
 	} else if rng.Float64() < m.AddConnProbability() {
@@ -74,35 +75,35 @@ func (m *Complexify) Mutate(g *neat.Genome) error {
 // structure provides an opportunity to elaborate on the original behaviors. (Stanley, 35)
 func (m *Complexify) addNode(rng *rand.Rand, g *neat.Genome) {
 
-	// Pick a connection to split
-	var inno int
-	var c0 neat.Connection
-	found := false
-	for k, conn := range g.Conns {
-		c0 = conn
-		inno = k
+	// Identify the connections which can be split
+	nodes, conns := g.GenesByInnovation()
+	avail := make([]neat.Connection, 0, len(conns))
+	for _, conn := range conns {
 
 		// Ensure resultant node doesn't already exist
-		found = true
-		src := g.Nodes[c0.Source]
-		tgt := g.Nodes[c0.Target]
+		found := true
+		src := g.Nodes[conn.Source]
+		tgt := g.Nodes[conn.Target]
 		x := (src.X + tgt.X) / 2.0
 		y := (src.Y + tgt.Y) / 2.0
-		for _, node := range g.Nodes {
+		for _, node := range nodes {
 			if node.X == x && node.Y == y {
 				found = false
 				break
 			}
 		}
 		if found {
-			break
+			avail = append(avail, conn)
 		}
 	}
-	if !found {
+	if len(avail) == 0 {
 		return
 	}
+
+	// Pick a connection to split
+	c0 := avail[rng.Intn(len(avail))]
 	c0.Enabled = false
-	g.Conns[inno] = c0
+	g.Conns[c0.Innovation] = c0
 
 	// Add the new node
 	src := g.Nodes[c0.Source]
@@ -128,11 +129,14 @@ func (m *Complexify) addNode(rng *rand.Rand, g *neat.Genome) {
 func (m *Complexify) addConn(rng *rand.Rand, g *neat.Genome) {
 
 	// Identify two unconnected nodes
-	conns := make(map[int]neat.Connection)
-	c := 0
-	for _, src := range g.Nodes {
-		for _, tgt := range g.Nodes {
-			if src.Y >= tgt.Y {
+	nodes, _ := g.GenesByInnovation()
+	conns := make([]neat.Connection, 0, 10)
+	for _, src := range nodes {
+		for _, tgt := range nodes {
+			if tgt.NeuronType == neat.Bias || tgt.NeuronType == neat.Input {
+				continue // inputs are set, not activated
+			}
+			if src.Y >= tgt.Y && !m.AllowRecurrent() {
 				continue // do not allow recurrent
 			}
 			found := false
@@ -143,18 +147,18 @@ func (m *Complexify) addConn(rng *rand.Rand, g *neat.Genome) {
 				}
 			}
 			if !found {
-				conns[c] = neat.Connection{Source: src.Innovation, Target: tgt.Innovation}
-				c += 1
+				conns = append(conns, neat.Connection{Source: src.Innovation, Target: tgt.Innovation})
 			}
 		}
 	}
-
-	// Go's range over maps is random, so take the first, if any, availble connection
-	for _, conn := range conns {
-		conn.Enabled = true
-		conn.Weight = (rng.Float64()*2.0 - 1.0) * m.WeightRange()
-		conn.Innovation = m.ctx.Innovation(neat.ConnInnovation, conn.Key())
-		g.Conns[conn.Innovation] = conn
-		break
+	if len(conns) == 0 {
+		return
 	}
+
+	// Pick one of the available connections
+	conn := conns[rng.Intn(len(conns))]
+	conn.Enabled = true
+	conn.Weight = (rng.Float64()*2.0 - 1.0) * m.WeightRange()
+	conn.Innovation = m.ctx.Innovation(neat.ConnInnovation, conn.Key())
+	g.Conns[conn.Innovation] = conn
 }
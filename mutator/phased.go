@@ -94,11 +94,32 @@ func NewPhased(ps PhasedSettings, cs ComplexifySettings, ns PruningSettings) *Ph
 	}
 }
 
+// Returns the mutator's internal state so that the current phase survives a restore
+func (m *Phased) Checkpoint() interface{} {
+	return &struct {
+		IsPruning       *bool
+		PruneThresh     *float64
+		TargetMPC       *float64
+		Fitness         *float64
+		AgeMPC          *int
+		AgeImprovement  *int
+		MinMPC          *float64
+		LastImprovement *float64
+	}{&m.isPruning, &m.pruneThresh, &m.targetMPC, &m.fitness, &m.ageMPC, &m.ageImprovement,
+		&m.minMPC, &m.lastImprovement}
+}
+
 func (m *Phased) SetContext(x neat.Context) error {
 	m.ctx = x
-	return m.Complexify.SetContext(x)
+	if err := m.Complexify.SetContext(x); err != nil {
+		return err
+	}
+	return m.Pruning.SetContext(x)
 }
 
+// Returns true if the mutator is in the pruning phase
+func (m Phased) IsPruning() bool { return m.isPruning }
+
 // Mutates the Genome by through complexifiying or pruning depending on current phase
 func (m *Phased) Mutate(g *neat.Genome) (err error) {
 	if m.isPruning {
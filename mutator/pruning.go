@@ -40,11 +40,17 @@ type PruningSettings interface {
 
 type Pruning struct {
 	PruningSettings
+	ctx neat.Context
+}
+
+func (m *Pruning) SetContext(x neat.Context) error {
+	m.ctx = x
+	return nil
 }
 
 // Mutates a genome's structure by selectiving removing nodes and connections
 func (m Pruning) Mutate(g *neat.Genome) error {
-	rng := rand.New(rand.NewSource(rand.Int63()))
+	rng := neat.Rand(m.ctx)
 	if rng.Float64() < m.DelNodeProbability() {
 		m.delNode(rng, g)
 	} else if rng.Float64() < m.DelConnProbability() {
@@ -75,26 +81,29 @@ func (m Pruning) Mutate(g *neat.Genome) error {
 func (m *Pruning) delNode(rng *rand.Rand, g *neat.Genome) {
 
 	type check struct {
+		Node     neat.Node
 		AsSource []neat.Connection
 		AsTarget []neat.Connection
+		Loops    []neat.Connection
 	}
 
-	// Build a map of available nodes to delete
-	var chk check
-	var node neat.Node
-	avail := make(map[neat.Node]check)
-	for _, node = range g.Nodes {
+	// Build a list of available nodes to delete
+	nodes, conns := g.GenesByInnovation()
+	avail := make([]check, 0, len(nodes))
+	for _, node := range nodes {
 		if node.NeuronType == neat.Hidden {
-			chk = check{make([]neat.Connection, 0, 5), make([]neat.Connection, 0, 5)}
-			for _, conn := range g.Conns {
-				if conn.Source == node.Innovation {
+			chk := check{node, make([]neat.Connection, 0, 5), make([]neat.Connection, 0, 5), nil}
+			for _, conn := range conns {
+				if conn.Source == node.Innovation && conn.Target == node.Innovation {
+					chk.Loops = append(chk.Loops, conn)
+				} else if conn.Source == node.Innovation {
 					chk.AsSource = append(chk.AsSource, conn)
 				} else if conn.Target == node.Innovation {
 					chk.AsTarget = append(chk.AsTarget, conn)
 				}
 			}
 			if len(chk.AsSource) <= 1 || len(chk.AsTarget) <= 1 {
-				avail[node] = chk
+				avail = append(avail, chk)
 			}
 		}
 	}
@@ -103,8 +112,10 @@ func (m *Pruning) delNode(rng *rand.Rand, g *neat.Genome) {
 	}
 
 	// Pick a node to delete
-	for node, chk = range avail {
-		break
+	chk := avail[rng.Intn(len(avail))]
+	delete(g.Nodes, chk.Node.Innovation)
+	for _, conn := range chk.Loops {
+		delete(g.Conns, conn.Innovation)
 	}
 
 	// Remove dead-end connections
@@ -117,13 +128,39 @@ func (m *Pruning) delNode(rng *rand.Rand, g *neat.Genome) {
 			delete(g.Conns, conn.Innovation)
 		}
 	} else {
-		// Bypass this node in all the connections. Only one of the slices will have more than 1 connection
-		for _, sc := range chk.AsSource {
+		// Bypass this node. Only one of the slices will have more than 1 connection. The bypassing
+		// connections take the innovation numbers of their new keys. Any which would duplicate an
+		// existing connection are dropped.
+		keys := make(map[neat.InnoKey]bool, len(g.Conns))
+		for _, conn := range g.Conns {
+			keys[conn.Key()] = true
+		}
+		bypass := func(conn neat.Connection) {
+			if keys[conn.Key()] {
+				return
+			}
+			conn.Innovation = m.ctx.Innovation(neat.ConnInnovation, conn.Key())
+			if _, ok := g.Conns[conn.Innovation]; ok {
+				return
+			}
+			g.Conns[conn.Innovation] = conn
+			keys[conn.Key()] = true
+		}
+		if len(chk.AsTarget) == 1 {
+			tc := chk.AsTarget[0]
+			delete(g.Conns, tc.Innovation)
+			for _, sc := range chk.AsSource {
+				delete(g.Conns, sc.Innovation)
+				sc.Source = tc.Source
+				bypass(sc)
+			}
+		} else {
+			sc := chk.AsSource[0]
+			delete(g.Conns, sc.Innovation)
 			for _, tc := range chk.AsTarget {
-				sc.Target = tc.Target
-				tc.Source = sc.Source
-				g.Conns[sc.Innovation] = sc
-				g.Conns[tc.Innovation] = tc
+				delete(g.Conns, tc.Innovation)
+				tc.Target = sc.Target
+				bypass(tc)
 			}
 		}
 	}
@@ -141,10 +178,11 @@ func (m *Pruning) delNode(rng *rand.Rand, g *neat.Genome) {
 func (m *Pruning) delConn(rng *rand.Rand, g *neat.Genome) {
 
 	// Pick a connection at random
-	var conn neat.Connection
-	for _, conn = range g.Conns {
-		break
+	_, conns := g.GenesByInnovation()
+	if len(conns) == 0 {
+		return
 	}
+	conn := conns[rng.Intn(len(conns))]
 
 	// Remove the connection from the genome
 	delete(g.Conns, conn.Innovation)
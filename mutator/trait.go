@@ -42,11 +42,17 @@ type TraitSettings interface {
 
 type Trait struct {
 	TraitSettings
+	ctx neat.Context
+}
+
+func (m *Trait) SetContext(x neat.Context) error {
+	m.ctx = x
+	return nil
 }
 
 // Mutates a genome's traits
 func (m Trait) Mutate(g *neat.Genome) error {
-	rng := rand.New(rand.NewSource(rand.Int63()))
+	rng := neat.Rand(m.ctx)
 	ts := m.Traits()
 	for i, _ := range g.Traits {
 		t := ts[i]
@@ -40,19 +40,26 @@ type WeightSettings interface {
 
 type Weight struct {
 	WeightSettings
+	ctx neat.Context
+}
+
+func (m *Weight) SetContext(x neat.Context) error {
+	m.ctx = x
+	return nil
 }
 
 // Mutates a genome's weights
 func (m Weight) Mutate(g *neat.Genome) error {
-	rng := rand.New(rand.NewSource(rand.Int63()))
-	for k, conn := range g.Conns {
+	rng := neat.Rand(m.ctx)
+	_, conns := g.GenesByInnovation()
+	for _, conn := range conns {
 		if rng.Float64() < m.MutateWeightProbability() {
 			if rng.Float64() < m.ReplaceWeightProbability() {
 				m.replaceWeight(rng, &conn)
 			} else {
 				m.mutateWeight(rng, &conn)
 			}
-			g.Conns[k] = conn
+			g.Conns[conn.Innovation] = conn
 		}
 	}
 	return nil
@@ -0,0 +1,52 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package mutator
+
+import (
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+type weightSettings struct{}
+
+func (s weightSettings) WeightRange() float64              { return 1 }
+func (s weightSettings) MutateWeightProbability() float64  { return 1 }
+func (s weightSettings) ReplaceWeightProbability() float64 { return 0.5 }
+
+func TestWeightWithoutContext(t *testing.T) {
+	g := neat.Genome{Conns: neat.Connections{1: {Innovation: 1, Weight: 0.25}, 2: {Innovation: 2, Weight: 0.25}}}
+	m := &Weight{WeightSettings: weightSettings{}}
+	if err := m.Mutate(&g); err != nil {
+		t.Fatal(err)
+	}
+	for k, c := range g.Conns {
+		if c.Weight == 0.25 {
+			t.Errorf("Weight of connection %d was not mutated", k)
+		}
+	}
+}
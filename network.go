@@ -26,7 +26,12 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 package neat
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+)
 
 // Represents a neural network
 type Network interface {
@@ -35,6 +40,13 @@ type Network interface {
 	Activate(inputs []float64) (outputs []float64, err error)
 }
 
+// IntoActivatable describes a network which can be activated without allocating memory by writing
+// the output values into a slice provided by the caller
+type IntoActivatable interface {
+	// Activates the neural network using the inputs, writing the output values into outputs
+	ActivateInto(inputs, outputs []float64) error
+}
+
 type NeuronType byte
 
 const (
@@ -67,44 +79,114 @@ const (
 	Sigmoid                                   // 3
 	Tanh                                      // 4
 	InverseAbs                                // 5
+	Gaussian                                  // 6
+	Sine                                      // 7
+	Abs                                       // 8
+	Step                                      // 9
+	ReLU                                      // 10
+	LinearClipped                             // 11
+
+	// Activation types assigned to functions registered by applications begin here
+	FirstCustomActivation ActivationType = 128
 )
 
 var (
-	Activations []ActivationType = []ActivationType{SteependSigmoid, Sigmoid, Tanh, InverseAbs}
+	Activations []ActivationType = []ActivationType{SteependSigmoid, Sigmoid, Tanh, InverseAbs,
+		Gaussian, Sine, Abs, Step, ReLU, LinearClipped}
+)
+
+// Definition of an activation function
+type activation struct {
+	name     string
+	fn       func(float64) float64
+	min, max float64
+}
+
+var (
+	activations = map[ActivationType]activation{
+		Direct:          {"Direct", DirectActivation, math.Inf(-1), math.Inf(1)},
+		SteependSigmoid: {"Steepend Sigmoid", SteependSigmoidActivation, 0, 1.0},
+		Sigmoid:         {"Sigmoid", SigmoidActivation, 0, 1.0},
+		Tanh:            {"Tanh", TanhActivation, -1.0, 1.0},
+		InverseAbs:      {"Inverse ABS", InverseAbsActivation, -1.0, 1.0},
+		Gaussian:        {"Gaussian", GaussianActivation, 0, 1.0},
+		Sine:            {"Sine", SineActivation, -1.0, 1.0},
+		Abs:             {"Abs", AbsActivation, 0, math.Inf(1)},
+		Step:            {"Step", StepActivation, 0, 1.0},
+		ReLU:            {"ReLU", ReLUActivation, 0, math.Inf(1)},
+		LinearClipped:   {"Linear Clipped", LinearClippedActivation, -1.0, 1.0},
+	}
+	nextActivation = FirstCustomActivation
+	activationsMu  sync.RWMutex
 )
 
 func (a ActivationType) String() string {
-	switch a {
-	case Direct:
-		return "Direct"
-	case SteependSigmoid:
-		return "Steepend Sigmoid"
-	case Sigmoid:
-		return "Sigmoid"
-	case Tanh:
-		return "Tanh"
-	case InverseAbs:
-		return "Inverse ABS"
-	default:
-		return "Unknown ActivationType"
+	activationsMu.RLock()
+	defer activationsMu.RUnlock()
+	if x, ok := activations[a]; ok {
+		return x.name
 	}
+	return "Unknown ActivationType"
 }
 
 func (a ActivationType) Range() (float64, float64) {
-	switch a {
-	case Direct:
-		return math.Inf(-1), math.Inf(1)
-	case SteependSigmoid:
-		return 0, 1.0
-	case Sigmoid:
-		return 0, 1.0
-	case Tanh:
-		return -1.0, 1.0
-	case InverseAbs:
-		return -1.0, 1.0
-	default:
-		return math.NaN(), math.NaN()
+	activationsMu.RLock()
+	defer activationsMu.RUnlock()
+	if x, ok := activations[a]; ok {
+		return x.min, x.max
 	}
+	return math.NaN(), math.NaN()
+}
+
+// Returns the function for the activation type or false if the type is unknown
+func (a ActivationType) Func() (func(float64) float64, bool) {
+	activationsMu.RLock()
+	defer activationsMu.RUnlock()
+	x, ok := activations[a]
+	return x.fn, ok
+}
+
+// Registers a custom activation function under the name, returning the type assigned to it. The
+// range is the minimum and maximum values the function can produce. Types are assigned in order of
+// registration so an application must register its functions in the same order each time it runs
+// for archived genomes to be restored correctly.
+func RegisterActivation(name string, fn func(float64) float64, min, max float64) (ActivationType, error) {
+	activationsMu.Lock()
+	defer activationsMu.Unlock()
+	if fn == nil {
+		return 0, fmt.Errorf("neat.RegisterActivation - No function provided for %s", name)
+	}
+	k := activationKey(name)
+	for _, x := range activations {
+		if activationKey(x.name) == k {
+			return 0, fmt.Errorf("neat.RegisterActivation - Activation %s is already registered", name)
+		}
+	}
+	if nextActivation == 0 {
+		return 0, fmt.Errorf("neat.RegisterActivation - No activation types remain for %s", name)
+	}
+	a := nextActivation
+	activations[a] = activation{name, fn, min, max}
+	nextActivation += 1 // wraps to 0 once the last type is assigned
+	return a, nil
+}
+
+// Returns the activation type registered under the name. Names are matched ignoring case and
+// spaces so that both "SteependSigmoid" and "steepend sigmoid" find the same type.
+func ActivationByName(name string) (ActivationType, bool) {
+	activationsMu.RLock()
+	defer activationsMu.RUnlock()
+	k := activationKey(name)
+	for a, x := range activations {
+		if activationKey(x.name) == k {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+func activationKey(name string) string {
+	return strings.ToLower(strings.Replace(name, " ", "", -1))
 }
 
 func DirectActivation(x float64) float64          { return x }
@@ -112,6 +194,18 @@ func SigmoidActivation(x float64) float64         { return 1.0 / (1.0 + exp1(-x)
 func SteependSigmoidActivation(x float64) float64 { return 1.0 / (1.0 + exp1(-4.9*x)) }
 func TanhActivation(x float64) float64            { return math.Tanh(0.9 * x) }
 func InverseAbsActivation(x float64) float64      { return x / (1.0 + math.Abs(x)) }
+func GaussianActivation(x float64) float64        { return math.Exp(-x * x) }
+func SineActivation(x float64) float64            { return math.Sin(x) }
+func AbsActivation(x float64) float64             { return math.Abs(x) }
+func ReLUActivation(x float64) float64            { return math.Max(0, x) }
+func LinearClippedActivation(x float64) float64   { return math.Max(-1.0, math.Min(1.0, x)) }
+
+func StepActivation(x float64) float64 {
+	if x > 0 {
+		return 1.0
+	}
+	return 0
+}
 
 // Speed up over math.Exp by using less precision
 // https://codingforspeed.com/using-faster-exponential-approximation/
@@ -32,7 +32,8 @@ import (
 type Neuron struct {
 	neat.NeuronType
 	neat.ActivationType
-	X, Y float64 // Hint at where neuron might be positioned in a 2D representation
+	X, Y     float64   // Hint at where neuron might be positioned in a 2D representation
+	Position []float64 // Full position of the neuron, such as its substrate coordinates, if known
 }
 
 type Neurons []Neuron
@@ -92,20 +93,8 @@ func New(neurons Neurons, synapses Synapses) (net *Classic, err error) {
 			oo = oo || l > neat.Output
 		}
 		l = ng.NeuronType
-		switch ng.ActivationType {
-		case neat.Direct:
-			net.funcs[i] = neat.DirectActivation
-		case neat.Sigmoid:
-			net.funcs[i] = neat.SigmoidActivation
-		case neat.SteependSigmoid:
-			net.funcs[i] = neat.SteependSigmoidActivation
-		case neat.Tanh:
-			net.funcs[i] = neat.TanhActivation
-		case neat.InverseAbs:
-			net.funcs[i] = neat.InverseAbsActivation
-		default:
-			err = fmt.Errorf("network.classic.New - Unknown ActivationType %v", byte(ng.ActivationType))
-			break
+		if net.funcs[i], err = activationFunc(ng.ActivationType); err != nil {
+			return
 		}
 	}
 	if oo {
@@ -137,11 +126,23 @@ func New(neurons Neurons, synapses Synapses) (net *Classic, err error) {
 	return
 }
 
+// Returns the function for the activation type
+func activationFunc(a neat.ActivationType) (Activation, error) {
+	if fn, ok := a.Func(); ok {
+		return fn, nil
+	}
+	return nil, fmt.Errorf("network.classic.New - Unknown ActivationType %v", byte(a))
+}
+
 func (n Classic) String() string {
 	b := bytes.NewBufferString("Network is \n")
 	b.WriteString("\tNeurons:\n")
 	for i, neuron := range n.Neurons {
-		b.WriteString(fmt.Sprintf("\t [%d] Type: %v Activation: %v Position: [%f, %f]\n", i, neuron.NeuronType, neuron.ActivationType, neuron.X, neuron.Y))
+		if len(neuron.Position) > 0 {
+			b.WriteString(fmt.Sprintf("\t [%d] Type: %v Activation: %v Position: %v\n", i, neuron.NeuronType, neuron.ActivationType, neuron.Position))
+		} else {
+			b.WriteString(fmt.Sprintf("\t [%d] Type: %v Activation: %v Position: [%f, %f]\n", i, neuron.NeuronType, neuron.ActivationType, neuron.X, neuron.Y))
+		}
 	}
 	b.WriteString("\tSynapses:\n")
 	for i, synapse := range n.Synapses {
@@ -0,0 +1,187 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package network
+
+import (
+	"fmt"
+)
+
+// Compiled is a flattened form of the Classic network. The neurons are arranged in topological
+// order and each neuron's incoming synapses are stored contiguously so that activation visits
+// every neuron exactly once. A value buffer is kept with the network so that ActivateInto does
+// not allocate. Because of this buffer, ActivateInto must not be called concurrently on the same
+// network; Activate uses its own buffer and remains safe to call from multiple goroutines.
+type Compiled struct {
+	Classic
+
+	// Flattened structure
+	order   []int     // Indexes of the non-input neurons in the order they are activated
+	starts  []int     // Offset of each ordered neuron's incoming synapses. Has len(order)+1 entries
+	sources []int     // Source neuron of each incoming synapse
+	weights []float64 // Weight of each incoming synapse
+
+	// Reusable buffer of activated values
+	vals []float64
+}
+
+// Compiles the classic network. Returns an error if the synapses form a cycle.
+func Compile(net *Classic) (c *Compiled, err error) {
+
+	// Ensure the synapses map to neurons and do not target the inputs
+	cnt := len(net.Neurons)
+	first := net.biases + net.inputs
+	for _, s := range net.Synapses {
+		if s.Source < 0 || s.Source >= cnt || s.Target < 0 || s.Target >= cnt {
+			err = fmt.Errorf("network.compiled.Compile - Synapses do not map to defined neurons")
+			return
+		}
+		if s.Target < first {
+			err = fmt.Errorf("network.compiled.Compile - Synapse targets bias or input neuron %d", s.Target)
+			return
+		}
+	}
+
+	// Order the neurons topologically
+	order, ok := net.topological()
+	if !ok {
+		err = fmt.Errorf("network.compiled.Compile - Synapses contain a cycle. Use a recurrent network instead")
+		return
+	}
+
+	// Group the incoming synapses by target, preserving the synapses' order
+	in := make([][]int, cnt)
+	for i, s := range net.Synapses {
+		in[s.Target] = append(in[s.Target], i)
+	}
+
+	// Flatten the synapses
+	c = &Compiled{
+		Classic: *net,
+		order:   order,
+		starts:  make([]int, len(order)+1),
+		sources: make([]int, 0, len(net.Synapses)),
+		weights: make([]float64, 0, len(net.Synapses)),
+		vals:    make([]float64, cnt),
+	}
+	for k, idx := range order {
+		c.starts[k] = len(c.sources)
+		for _, i := range in[idx] {
+			c.sources = append(c.sources, net.Synapses[i].Source)
+			c.weights = append(c.weights, net.Synapses[i].Weight)
+		}
+	}
+	c.starts[len(order)] = len(c.sources)
+	return
+}
+
+// Returns true if the network can be compiled: its synapses map to its neurons, none targets a
+// bias or input neuron and they contain no cycle, including self-loops
+func (n Classic) FeedForward() bool {
+	cnt := len(n.Neurons)
+	for _, s := range n.Synapses {
+		if s.Source < 0 || s.Source >= cnt || s.Target < n.biases+n.inputs || s.Target >= cnt {
+			return false
+		}
+	}
+	_, ok := n.topological()
+	return ok
+}
+
+// Returns the indexes of the non-input neurons in an order in which each follows the sources of
+// its synapses, or false if the synapses contain a cycle. The synapses must map to the neurons.
+func (n Classic) topological() (order []int, ok bool) {
+	cnt := len(n.Neurons)
+	first := n.biases + n.inputs
+	deg := make([]int, cnt)
+	out := make([][]int, cnt)
+	for _, s := range n.Synapses {
+		out[s.Source] = append(out[s.Source], s.Target)
+		if s.Source >= first {
+			deg[s.Target] += 1
+		}
+	}
+	order = make([]int, 0, cnt-first)
+	for i := first; i < cnt; i++ {
+		if deg[i] == 0 {
+			order = append(order, i)
+		}
+	}
+	for k := 0; k < len(order); k++ {
+		for _, t := range out[order[k]] {
+			deg[t] -= 1
+			if deg[t] == 0 {
+				order = append(order, t)
+			}
+		}
+	}
+	return order, len(order) == cnt-first
+}
+
+// Activates the neural network using the inputs. Returns the output values.
+func (n *Compiled) Activate(inputs []float64) (outputs []float64, err error) {
+	outputs = make([]float64, n.outputs)
+	err = n.activate(make([]float64, len(n.vals)), inputs, outputs)
+	return
+}
+
+// Activates the neural network using the inputs, writing the values of the output neurons into
+// outputs. No memory is allocated.
+func (n *Compiled) ActivateInto(inputs, outputs []float64) error {
+	return n.activate(n.vals, inputs, outputs)
+}
+
+func (n *Compiled) activate(vals, inputs, outputs []float64) error {
+
+	// Check the inputs and outputs
+	if len(inputs) > n.inputs {
+		return fmt.Errorf("network.compiled.Activate - There are more input values (%d) than input neurons (%d)", len(inputs), n.inputs)
+	}
+	if len(outputs) < n.outputs {
+		return fmt.Errorf("network.compiled.Activate - There are fewer output values (%d) than output neurons (%d)", len(outputs), n.outputs)
+	}
+
+	// Set the biases and inputs
+	for i := 0; i < n.biases; i++ {
+		vals[i] = n.funcs[i](1.0)
+	}
+	for i := 0; i < n.inputs; i++ {
+		x := 0.0
+		if i < len(inputs) {
+			x = inputs[i]
+		}
+		vals[i+n.biases] = n.funcs[i+n.biases](x)
+	}
+
+	// Activate the remaining neurons in order
+	for k, idx := range n.order {
+		sum := 0.0
+		for j := n.starts[k]; j < n.starts[k+1]; j++ {
+			sum += vals[n.sources[j]] * n.weights[j]
+		}
+		vals[idx] = n.funcs[idx](sum)
+	}
+
+	// Return the output values
+	copy(outputs, vals[len(vals)-n.outputs:])
+	return nil
+}
@@ -0,0 +1,183 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package network
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+// Defines the layers of a fully connected, feed-forward network
+type shape struct {
+	name   string
+	layers []int // inputs, hiddens..., outputs
+}
+
+var shapes = []shape{
+	{"xor", []int{2, 2, 1}},
+	{"maze", []int{10, 20, 2}},
+	{"ocr", []int{35, 50, 26}},
+	{"boxes", []int{121, 121}},
+	{"substrate", []int{121, 121, 121}},
+}
+
+// Returns a classic network with fully connected layers
+func build(tb testing.TB, rng *rand.Rand, s shape) *Classic {
+	neurons := Neurons{{NeuronType: neat.Bias, ActivationType: neat.Direct}}
+	layers := make([][]int, len(s.layers))
+	for l, n := range s.layers {
+		for i := 0; i < n; i++ {
+			layers[l] = append(layers[l], len(neurons))
+			nn := Neuron{NeuronType: neat.Hidden, ActivationType: neat.SteependSigmoid, X: float64(i) / float64(n), Y: float64(l) / float64(len(s.layers)-1)}
+			switch l {
+			case 0:
+				nn.NeuronType, nn.ActivationType = neat.Input, neat.Direct
+			case len(s.layers) - 1:
+				nn.NeuronType = neat.Output
+			}
+			neurons = append(neurons, nn)
+		}
+	}
+	synapses := make(Synapses, 0, 100)
+	for l := 1; l < len(layers); l++ {
+		for _, t := range layers[l] {
+			synapses = append(synapses, Synapse{Source: 0, Target: t, Weight: rng.NormFloat64()})
+			for _, s := range layers[l-1] {
+				synapses = append(synapses, Synapse{Source: s, Target: t, Weight: rng.NormFloat64()})
+			}
+		}
+	}
+	net, err := New(neurons, synapses)
+	if err != nil {
+		tb.Fatalf("Could not build %s network: %v", s.name, err)
+	}
+	return net
+}
+
+// Returns the network compiled
+func compile(tb testing.TB, net *Classic) *Compiled {
+	cmp, err := Compile(net)
+	if err != nil {
+		tb.Fatalf("Could not compile network: %v", err)
+	}
+	return cmp
+}
+
+// Returns random inputs for the shape
+func inputs(rng *rand.Rand, s shape) []float64 {
+	in := make([]float64, s.layers[0])
+	for i := range in {
+		in[i] = rng.Float64()
+	}
+	return in
+}
+
+func TestCompiledMatchesClassic(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range shapes {
+		net := build(t, rng, s)
+		cmp := compile(t, net)
+		into := make([]float64, s.layers[len(s.layers)-1])
+		for k := 0; k < 5; k++ {
+			in := inputs(rng, s)
+			a, err := net.Activate(in)
+			if err != nil {
+				t.Fatalf("Classic %s network could not activate: %v", s.name, err)
+			}
+			b, err := cmp.Activate(in)
+			if err != nil {
+				t.Fatalf("Compiled %s network could not activate: %v", s.name, err)
+			}
+			if err = cmp.ActivateInto(in, into); err != nil {
+				t.Fatalf("Compiled %s network could not activate into outputs: %v", s.name, err)
+			}
+			if len(a) != len(b) {
+				t.Fatalf("Compiled %s network has %d outputs but classic has %d", s.name, len(b), len(a))
+			}
+			for i := range a {
+				if a[i] != b[i] || a[i] != into[i] {
+					t.Errorf("Compiled %s network output %d is %f (into %f) but classic is %f", s.name, i, b[i], into[i], a[i])
+				}
+			}
+		}
+	}
+}
+
+func TestCompileRejectsCycles(t *testing.T) {
+	net, err := New(Neurons{
+		{NeuronType: neat.Bias, ActivationType: neat.Direct},
+		{NeuronType: neat.Input, ActivationType: neat.Direct},
+		{NeuronType: neat.Hidden, ActivationType: neat.Sigmoid},
+		{NeuronType: neat.Hidden, ActivationType: neat.Sigmoid},
+		{NeuronType: neat.Output, ActivationType: neat.Sigmoid},
+	}, Synapses{{1, 2, 1}, {2, 3, 1}, {3, 2, 1}, {3, 4, 1}})
+	if err != nil {
+		t.Fatalf("Could not build network: %v", err)
+	}
+	if _, err = Compile(net); err == nil {
+		t.Errorf("Expected an error compiling a network with a cycle")
+	}
+}
+
+func TestCompileRejectsInputTargets(t *testing.T) {
+	net, err := New(Neurons{
+		{NeuronType: neat.Input, ActivationType: neat.Direct},
+		{NeuronType: neat.Output, ActivationType: neat.Sigmoid},
+	}, Synapses{{1, 0, 1}})
+	if err != nil {
+		t.Fatalf("Could not build network: %v", err)
+	}
+	if _, err = Compile(net); err == nil {
+		t.Errorf("Expected an error compiling a network with a synapse into an input")
+	}
+}
+
+func benchmark(b *testing.B, activate func(net *Classic, cmp *Compiled, in, out []float64)) {
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range shapes {
+		net := build(b, rng, s)
+		cmp := compile(b, net)
+		in := inputs(rng, s)
+		out := make([]float64, s.layers[len(s.layers)-1])
+		b.Run(s.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				activate(net, cmp, in, out)
+			}
+		})
+	}
+}
+
+func BenchmarkClassicActivate(b *testing.B) {
+	benchmark(b, func(net *Classic, cmp *Compiled, in, out []float64) { net.Activate(in) })
+}
+
+func BenchmarkCompiledActivate(b *testing.B) {
+	benchmark(b, func(net *Classic, cmp *Compiled, in, out []float64) { cmp.Activate(in) })
+}
+
+func BenchmarkCompiledActivateInto(b *testing.B) {
+	benchmark(b, func(net *Classic, cmp *Compiled, in, out []float64) { cmp.ActivateInto(in, out) })
+}
@@ -0,0 +1,274 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package network
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/rqme/neat"
+)
+
+// Kinds of network described by an Interchange
+const (
+	KindFeedForward = "feedforward" // Neurons are activated once, in topological order
+	KindRecurrent   = "recurrent"   // All neurons are activated together, Iterations times per call
+)
+
+// Version of the interchange format written by this package
+const InterchangeVersion = 1
+
+// Interchange is the stable, documented form of a decoded network used to move champions out of
+// an experiment. Its JSON encoding is
+//
+//	{
+//	  "format": "neat-network",
+//	  "version": 1,
+//	  "kind": "feedforward" | "recurrent",
+//	  "iterations": 3,                      // recurrent networks only
+//	  "neurons": [
+//	    {"id": 0, "type": "bias" | "input" | "hidden" | "output",
+//	     "activation": "Sigmoid", "position": [0.5, 1.0]}
+//	  ],
+//	  "synapses": [
+//	    {"source": 0, "target": 3, "weight": -1.25}
+//	  ]
+//	}
+//
+// Neurons are listed bias first, then inputs, hiddens and outputs, and are identified by their
+// index in the list. Activation names are those of neat.ActivationType and may be looked up with
+// neat.ActivationByName. Bias neurons are activated with the value 1 and input neurons with the
+// input values before the synapses are followed. A feed-forward network sums the weighted values
+// arriving at each neuron and activates it once all of its sources are activated. A recurrent
+// network keeps the activated value of every neuron between calls and, on each iteration, sums
+// the values of the previous iteration across every synapse before activating all of the neurons.
+type Interchange struct {
+	Format     string               `json:"format"`
+	Version    int                  `json:"version"`
+	Kind       string               `json:"kind"`
+	Iterations int                  `json:"iterations,omitempty"`
+	Neurons    []InterchangeNeuron  `json:"neurons"`
+	Synapses   []InterchangeSynapse `json:"synapses"`
+}
+
+type InterchangeNeuron struct {
+	ID         int       `json:"id"`
+	Type       string    `json:"type"`
+	Activation string    `json:"activation"`
+	Position   []float64 `json:"position"`
+}
+
+type InterchangeSynapse struct {
+	Source int     `json:"source"`
+	Target int     `json:"target"`
+	Weight float64 `json:"weight"`
+}
+
+// Returns the interchange form of a network decoded by this package. Classic networks are
+// described as feed-forward networks and so must not contain cycles.
+func NewInterchange(net neat.Network) (x *Interchange, err error) {
+	var c Classic
+	x = &Interchange{Format: "neat-network", Version: InterchangeVersion, Kind: KindFeedForward}
+	switch n := net.(type) {
+	case Classic:
+		c = n
+	case *Classic:
+		c = *n
+	case *Compiled:
+		c = n.Classic
+	case *Recurrent:
+		c = n.Classic
+		x.Kind = KindRecurrent
+		x.Iterations = n.Iterations
+	default:
+		return nil, fmt.Errorf("network.NewInterchange - Unsupported network type %T", net)
+	}
+
+	x.Neurons = make([]InterchangeNeuron, len(c.Neurons))
+	for i, n := range c.Neurons {
+		x.Neurons[i] = InterchangeNeuron{
+			ID:         i,
+			Type:       strings.ToLower(n.NeuronType.String()),
+			Activation: n.ActivationType.String(),
+			Position:   []float64{n.X, n.Y},
+		}
+		if len(n.Position) > 0 {
+			x.Neurons[i].Position = append([]float64(nil), n.Position...)
+		}
+	}
+	x.Synapses = make([]InterchangeSynapse, len(c.Synapses))
+	for i, s := range c.Synapses {
+		x.Synapses[i] = InterchangeSynapse{Source: s.Source, Target: s.Target, Weight: s.Weight}
+	}
+	return
+}
+
+// Reads the JSON encoding of an interchange network
+func ReadInterchange(r io.Reader) (x *Interchange, err error) {
+	x = new(Interchange)
+	if err = json.NewDecoder(r).Decode(x); err != nil {
+		return nil, err
+	}
+	if x.Format != "neat-network" {
+		return nil, fmt.Errorf("network.ReadInterchange - Unknown format %q", x.Format)
+	}
+	if x.Version > InterchangeVersion {
+		return nil, fmt.Errorf("network.ReadInterchange - Unsupported version %d", x.Version)
+	}
+	return
+}
+
+// Writes the JSON encoding of the interchange network
+func (x Interchange) WriteJSON(w io.Writer) error {
+	b, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
+
+// Returns the neurons and synapses described by the interchange network
+func (x Interchange) structure() (neurons Neurons, synapses Synapses, err error) {
+	neurons = make(Neurons, len(x.Neurons))
+	for i, n := range x.Neurons {
+		if n.ID != i {
+			err = fmt.Errorf("network.Interchange - Neuron %d is listed at index %d", n.ID, i)
+			return
+		}
+		switch n.Type {
+		case "bias":
+			neurons[i].NeuronType = neat.Bias
+		case "input":
+			neurons[i].NeuronType = neat.Input
+		case "hidden":
+			neurons[i].NeuronType = neat.Hidden
+		case "output":
+			neurons[i].NeuronType = neat.Output
+		default:
+			err = fmt.Errorf("network.Interchange - Unknown type %q for neuron %d", n.Type, i)
+			return
+		}
+		var ok bool
+		if neurons[i].ActivationType, ok = neat.ActivationByName(n.Activation); !ok {
+			err = fmt.Errorf("network.Interchange - Unknown activation %q for neuron %d", n.Activation, i)
+			return
+		}
+		if len(n.Position) > 0 {
+			neurons[i].X = n.Position[0]
+		}
+		if len(n.Position) > 1 {
+			neurons[i].Y = n.Position[1]
+		}
+		if len(n.Position) > 2 {
+			neurons[i].Position = append([]float64(nil), n.Position...)
+		}
+	}
+	synapses = make(Synapses, len(x.Synapses))
+	for i, s := range x.Synapses {
+		if s.Source < 0 || s.Source >= len(neurons) || s.Target < 0 || s.Target >= len(neurons) {
+			err = fmt.Errorf("network.Interchange - Synapse %d does not map to defined neurons", i)
+			return
+		}
+		synapses[i] = Synapse{Source: s.Source, Target: s.Target, Weight: s.Weight}
+	}
+	return
+}
+
+// Returns a network which activates as described by the interchange network
+func (x Interchange) Network() (neat.Network, error) {
+	neurons, synapses, err := x.structure()
+	if err != nil {
+		return nil, err
+	}
+	switch x.Kind {
+	case KindFeedForward:
+		c, err := New(neurons, synapses)
+		if err != nil {
+			return nil, err
+		}
+		return Compile(c)
+	case KindRecurrent:
+		return NewRecurrent(neurons, synapses, x.Iterations)
+	default:
+		return nil, fmt.Errorf("network.Interchange.Network - Unknown kind %q", x.Kind)
+	}
+}
+
+// Writes the network as a Graphviz DOT digraph. Inputs and outputs are ranked together, neurons
+// carry their position as a pos attribute for layout engines such as neato, and synapses are
+// drawn in proportion to their weight, red for negative.
+func (x Interchange) WriteDOT(w io.Writer, name string) error {
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "digraph %q {\n", name)
+	fmt.Fprintf(b, "\trankdir=BT;\n")
+	fmt.Fprintf(b, "\tnode [shape=circle, fontsize=10];\n")
+
+	var ins, outs []string
+	for _, n := range x.Neurons {
+		id := fmt.Sprintf("n%d", n.ID)
+		shape := "circle"
+		switch n.Type {
+		case "bias", "input":
+			shape = "box"
+			ins = append(ins, id)
+		case "output":
+			shape = "doublecircle"
+			outs = append(outs, id)
+		}
+		pos := make([]string, len(n.Position))
+		for i, p := range n.Position {
+			pos[i] = fmt.Sprintf("%g", p)
+		}
+		fmt.Fprintf(b, "\t%s [label=\"%d\\n%s\", shape=%s, pos=\"%s\"];\n", id, n.ID, n.Activation, shape, strings.Join(pos, ","))
+	}
+	if len(ins) > 0 {
+		fmt.Fprintf(b, "\t{ rank=source; %s; }\n", strings.Join(ins, "; "))
+	}
+	if len(outs) > 0 {
+		fmt.Fprintf(b, "\t{ rank=sink; %s; }\n", strings.Join(outs, "; "))
+	}
+
+	max := 0.0
+	for _, s := range x.Synapses {
+		max = math.Max(max, math.Abs(s.Weight))
+	}
+	for _, s := range x.Synapses {
+		color := "black"
+		if s.Weight < 0 {
+			color = "red"
+		}
+		width := 1.0
+		if max > 0 {
+			width += 2.0 * math.Abs(s.Weight) / max
+		}
+		fmt.Fprintf(b, "\tn%d -> n%d [label=\"%.3f\", color=%s, penwidth=%.2f];\n", s.Source, s.Target, s.Weight, color, width)
+	}
+	fmt.Fprintf(b, "}\n")
+	return b.Flush()
+}
@@ -0,0 +1,278 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package network
+
+import (
+	"bytes"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+// Returns a small network with a bias, two inputs, a hidden neuron and an output
+func small(t *testing.T) *Classic {
+	neurons := Neurons{
+		{NeuronType: neat.Bias, ActivationType: neat.Direct, X: 0, Y: 0},
+		{NeuronType: neat.Input, ActivationType: neat.Direct, X: 0.5, Y: 0},
+		{NeuronType: neat.Input, ActivationType: neat.Direct, X: 1, Y: 0},
+		{NeuronType: neat.Hidden, ActivationType: neat.Tanh, X: 0.5, Y: 0.5},
+		{NeuronType: neat.Output, ActivationType: neat.Sigmoid, X: 0.5, Y: 1},
+	}
+	synapses := Synapses{
+		{Source: 0, Target: 3, Weight: 0.5},
+		{Source: 1, Target: 3, Weight: -2},
+		{Source: 2, Target: 3, Weight: 1},
+		{Source: 3, Target: 4, Weight: 1.5},
+	}
+	net, err := New(neurons, synapses)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return net
+}
+
+// Returns the interchange form of the network after writing it as JSON and reading it back
+func roundTrip(t *testing.T, net neat.Network) (*Interchange, *Interchange) {
+	x, err := NewInterchange(net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err = x.WriteJSON(b); err != nil {
+		t.Fatal(err)
+	}
+	y, err := ReadInterchange(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return x, y
+}
+
+func TestInterchangeRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	c := compile(t, build(t, rng, shapes[1]))
+	x, y := roundTrip(t, c)
+	if !reflect.DeepEqual(x, y) {
+		t.Fatalf("Expected the interchange network to read back unchanged")
+	}
+	net, err := y.Network()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		in := make([]float64, 10)
+		for j := range in {
+			in[j] = rng.Float64()
+		}
+		want, _ := c.Activate(in)
+		got, err := net.Activate(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Errorf("Expected outputs %v, got %v", want, got)
+		}
+	}
+}
+
+func TestInterchangeRoundTripRecurrent(t *testing.T) {
+	r := delayLine(t, Synapses{{Source: 0, Target: 1, Weight: 1}, {Source: 1, Target: 1, Weight: 0.5}, {Source: 1, Target: 2, Weight: 2}}, 2)
+	x, y := roundTrip(t, r)
+	if !reflect.DeepEqual(x, y) || y.Kind != KindRecurrent || y.Iterations != 2 {
+		t.Fatalf("Expected the recurrent network to read back unchanged, got %+v", y)
+	}
+	net, err := y.Network()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := activations(t, r, 1, 0, 2, 0)
+	got := activations(t, net.(*Recurrent), 1, 0, 2, 0)
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("Expected outputs %v, got %v", want, got)
+	}
+}
+
+func TestInterchangeKeepsPositions(t *testing.T) {
+	net := small(t)
+	for i := range net.Neurons {
+		net.Neurons[i].Position = []float64{net.Neurons[i].X, net.Neurons[i].Y, float64(i) - 2}
+	}
+	x, y := roundTrip(t, net)
+	for i, n := range y.Neurons {
+		if !reflect.DeepEqual(n.Position, net.Neurons[i].Position) {
+			t.Errorf("Expected neuron %d at %v, got %v", i, net.Neurons[i].Position, n.Position)
+		}
+	}
+	r, err := y.Network()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range r.(*Compiled).Neurons {
+		if !reflect.DeepEqual(n.Position, net.Neurons[i].Position) || n.X != net.Neurons[i].X || n.Y != net.Neurons[i].Y {
+			t.Errorf("Expected neuron %d at %v, got %v with hint (%f, %f)", i, net.Neurons[i].Position, n.Position, n.X, n.Y)
+		}
+	}
+
+	// Two coordinates are only the hints
+	x, y = roundTrip(t, small(t))
+	if r, err = y.Network(); err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range r.(*Compiled).Neurons {
+		if n.Position != nil || !reflect.DeepEqual(x.Neurons[i].Position, []float64{n.X, n.Y}) {
+			t.Errorf("Expected neuron %d only at hint %v, got %v and (%f, %f)", i, x.Neurons[i].Position, n.Position, n.X, n.Y)
+		}
+	}
+}
+
+func TestReadInterchangeRejectsOtherFormats(t *testing.T) {
+	for _, s := range []string{`{"format": "other", "version": 1}`, `{"format": "neat-network", "version": 99}`, `{`} {
+		if _, err := ReadInterchange(strings.NewReader(s)); err == nil {
+			t.Errorf("Expected %s to be rejected", s)
+		}
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	x, err := NewInterchange(small(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err = x.WriteDOT(b, "small"); err != nil {
+		t.Fatal(err)
+	}
+	dot := b.String()
+	for _, s := range []string{
+		"digraph \"small\" {\n",
+		"\tn0 [label=\"0\\nDirect\", shape=box, pos=\"0,0\"];\n",
+		"\tn3 [label=\"3\\nTanh\", shape=circle, pos=\"0.5,0.5\"];\n",
+		"\tn4 [label=\"4\\nSigmoid\", shape=doublecircle, pos=\"0.5,1\"];\n",
+		"\t{ rank=source; n0; n1; n2; }\n",
+		"\t{ rank=sink; n4; }\n",
+		"\tn1 -> n3 [label=\"-2.000\", color=red, penwidth=3.00];\n",
+		"\tn3 -> n4 [label=\"1.500\", color=black, penwidth=2.50];\n",
+	} {
+		if !strings.Contains(dot, s) {
+			t.Errorf("Expected the DOT output to contain %q:\n%s", s, dot)
+		}
+	}
+	if !strings.HasSuffix(dot, "}\n") {
+		t.Errorf("Expected the digraph to be closed")
+	}
+}
+
+// Parses the generated source, ensuring it is formatted, and returns its declarations by name
+func parseGo(t *testing.T, src []byte, pkg string) map[string]ast.Decl {
+	if formatted, err := format.Source(src); err != nil || !bytes.Equal(formatted, src) {
+		t.Errorf("Expected formatted source, got error %v", err)
+	}
+	f, err := parser.ParseFile(token.NewFileSet(), "net.go", src, 0)
+	if err != nil {
+		t.Fatalf("Could not parse the generated source: %v\n%s", err, src)
+	}
+	if f.Name.Name != pkg {
+		t.Errorf("Expected package %s, got %s", pkg, f.Name.Name)
+	}
+	decls := make(map[string]ast.Decl)
+	for _, d := range f.Decls {
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			name := d.Name.Name
+			if d.Recv != nil {
+				name = "." + name
+			}
+			decls[name] = d
+		case *ast.GenDecl:
+			for _, s := range d.Specs {
+				switch s := s.(type) {
+				case *ast.TypeSpec:
+					decls[s.Name.Name] = d
+				case *ast.ValueSpec:
+					for _, n := range s.Names {
+						decls[n.Name] = d
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+func TestWriteGo(t *testing.T) {
+	x, err := NewInterchange(small(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err = x.WriteGo(b, "champion", "Solve"); err != nil {
+		t.Fatal(err)
+	}
+	decls := parseGo(t, b.Bytes(), "champion")
+	for _, name := range []string{"Solve", "SolveInputs", "SolveOutputs", "solveDirect", "solveTanh", "solveSigmoid", "solveExp1"} {
+		if _, ok := decls[name]; !ok {
+			t.Errorf("Expected %s to be declared", name)
+		}
+	}
+	if _, ok := decls["solveGaussian"]; ok {
+		t.Errorf("Expected only the activations used to be declared")
+	}
+}
+
+func TestWriteGoRecurrent(t *testing.T) {
+	x, err := NewInterchange(delayLine(t, Synapses{{Source: 0, Target: 1, Weight: 1}, {Source: 1, Target: 2, Weight: 2}}, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := new(bytes.Buffer)
+	if err = x.WriteGo(b, "champion", "Memory"); err != nil {
+		t.Fatal(err)
+	}
+	decls := parseGo(t, b.Bytes(), "champion")
+	for _, name := range []string{"Memory", ".Activate", ".Reset", "memoryDirect"} {
+		if _, ok := decls[name]; !ok {
+			t.Errorf("Expected %s to be declared", name)
+		}
+	}
+}
+
+func TestWriteGoRejects(t *testing.T) {
+	x, err := NewInterchange(small(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = x.WriteGo(new(bytes.Buffer), "my-package", "Solve"); err == nil {
+		t.Errorf("Expected an invalid package name to be rejected")
+	}
+	x.Neurons[3].Activation = "Custom"
+	if err = x.WriteGo(new(bytes.Buffer), "champion", "Solve"); err == nil {
+		t.Errorf("Expected a custom activation to be rejected")
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package network
+
+import (
+	"fmt"
+)
+
+// Recurrent is a neural network which allows recurrent and self-looping synapses. Unlike the
+// Classic network, the activated value of each neuron persists between calls to Activate so that
+// the network can carry a memory of previous inputs. Use Reset to clear this memory.
+type Recurrent struct {
+	Classic
+
+	// Number of times the network is iterated during each call to Activate
+	Iterations int
+
+	// Internal state
+	state, sums []float64
+}
+
+func NewRecurrent(neurons Neurons, synapses Synapses, iters int) (net *Recurrent, err error) {
+
+	// Validate the structure using the classic network
+	var c *Classic
+	if c, err = New(neurons, synapses); err != nil {
+		return
+	}
+	if iters < 1 {
+		err = fmt.Errorf("network.recurrent.New - Network must be iterated at least once. Iterations %d", iters)
+		return
+	}
+
+	// Create the recurrent network
+	net = &Recurrent{
+		Classic:    *c,
+		Iterations: iters,
+		state:      make([]float64, len(neurons)),
+		sums:       make([]float64, len(neurons)),
+	}
+	return
+}
+
+// Clears the memory of the network
+func (n *Recurrent) Reset() {
+	for i := 0; i < len(n.state); i++ {
+		n.state[i] = 0
+		n.sums[i] = 0
+	}
+}
+
+// Activates the network using the inputs. Each iteration propagates the activated values of the
+// previous iteration (or previous call) across the synapses.
+func (n *Recurrent) Activate(inputs []float64) (outputs []float64, err error) {
+	outputs = make([]float64, n.outputs)
+	err = n.ActivateInto(inputs, outputs)
+	return
+}
+
+// Activates the network using the inputs, writing the values of the output neurons into outputs.
+// No memory is allocated.
+func (n *Recurrent) ActivateInto(inputs, outputs []float64) (err error) {
+
+	// Copy inputs into the network
+	if len(inputs) > n.inputs {
+		err = fmt.Errorf("network.recurrent.Activate - There are more input values (%d) than input neurons (%d)", len(inputs), n.inputs)
+		return
+	}
+	if len(outputs) < n.outputs {
+		err = fmt.Errorf("network.recurrent.Activate - There are fewer output values (%d) than output neurons (%d)", len(outputs), n.outputs)
+		return
+	}
+
+	// Set the biases and inputs
+	for i := 0; i < n.biases; i++ {
+		n.state[i] = n.funcs[i](1.0)
+	}
+	for i, x := range inputs {
+		n.state[i+n.biases] = n.funcs[i+n.biases](x)
+	}
+
+	// Iterate the network
+	first := n.biases + n.inputs
+	for k := 0; k < n.Iterations; k++ {
+		for i := first; i < len(n.sums); i++ {
+			n.sums[i] = 0
+		}
+		for _, s := range n.Synapses {
+			n.sums[s.Target] += n.state[s.Source] * s.Weight
+		}
+		for i := first; i < len(n.state); i++ {
+			n.state[i] = n.funcs[i](n.sums[i])
+		}
+	}
+
+	// Return the output values
+	copy(outputs, n.state[len(n.state)-n.outputs:])
+	return
+}
@@ -0,0 +1,132 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package network
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+// Returns a recurrent network of direct neurons: an input, a hidden neuron and an output
+func delayLine(t *testing.T, synapses Synapses, iters int) *Recurrent {
+	neurons := Neurons{
+		{NeuronType: neat.Input, ActivationType: neat.Direct},
+		{NeuronType: neat.Hidden, ActivationType: neat.Direct},
+		{NeuronType: neat.Output, ActivationType: neat.Direct},
+	}
+	net, err := NewRecurrent(neurons, synapses, iters)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return net
+}
+
+// Activates the network with each input in turn, returning the outputs
+func activations(t *testing.T, net *Recurrent, inputs ...float64) []float64 {
+	outs := make([]float64, len(inputs))
+	for i, x := range inputs {
+		y, err := net.Activate([]float64{x})
+		if err != nil {
+			t.Fatal(err)
+		}
+		outs[i] = y[0]
+	}
+	return outs
+}
+
+func TestRecurrentCarriesStateBetweenActivations(t *testing.T) {
+
+	// With one iteration, a signal takes one activation to cross each synapse
+	net := delayLine(t, Synapses{{Source: 0, Target: 1, Weight: 1}, {Source: 1, Target: 2, Weight: 2}}, 1)
+	if got, want := activations(t, net, 1, 3, 0), []float64{0, 2, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected outputs %v but got %v", want, got)
+	}
+
+	// With two iterations the signal reaches the output in the same activation
+	net = delayLine(t, Synapses{{Source: 0, Target: 1, Weight: 1}, {Source: 1, Target: 2, Weight: 2}}, 2)
+	if got, want := activations(t, net, 1, 3), []float64{2, 6}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected outputs %v but got %v", want, got)
+	}
+}
+
+func TestRecurrentReset(t *testing.T) {
+	net := delayLine(t, Synapses{{Source: 0, Target: 1, Weight: 1}, {Source: 1, Target: 2, Weight: 1}}, 1)
+	activations(t, net, 5)
+	net.Reset()
+	if got := activations(t, net, 1); got[0] != 0 {
+		t.Errorf("Expected the reset network to forget the earlier input but got %f", got[0])
+	}
+
+	// Activating into a slice also starts from the cleared state
+	net.Reset()
+	outs := make([]float64, 1)
+	if err := net.ActivateInto([]float64{7}, outs); err != nil || outs[0] != 0 {
+		t.Errorf("Expected 0 from the first activation after a reset but got %f (%v)", outs[0], err)
+	}
+}
+
+func TestRecurrentSelfLoop(t *testing.T) {
+
+	// The hidden neuron accumulates its inputs by feeding back into itself
+	net := delayLine(t, Synapses{
+		{Source: 0, Target: 1, Weight: 1},
+		{Source: 1, Target: 1, Weight: 1},
+		{Source: 1, Target: 2, Weight: 1},
+	}, 1)
+	if got, want := activations(t, net, 1, 1, 1, 1), []float64{0, 1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected outputs %v but got %v", want, got)
+	}
+	net.Reset()
+	if got, want := activations(t, net, 2, 0), []float64{0, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected outputs %v after a reset but got %v", want, got)
+	}
+}
+
+func TestFeedForwardIgnoresPositions(t *testing.T) {
+	neurons := Neurons{
+		{NeuronType: neat.Input, ActivationType: neat.Direct, Y: 0.5},
+		{NeuronType: neat.Hidden, ActivationType: neat.Direct, Y: 0.5},
+		{NeuronType: neat.Output, ActivationType: neat.Direct, Y: 0.5},
+	}
+	for _, c := range []struct {
+		name     string
+		synapses Synapses
+		forward  bool
+	}{
+		{"chain", Synapses{{Source: 0, Target: 1}, {Source: 1, Target: 2}}, true},
+		{"backwards", Synapses{{Source: 0, Target: 2}, {Source: 2, Target: 1}}, true},
+		{"self-loop", Synapses{{Source: 0, Target: 1}, {Source: 1, Target: 1}, {Source: 1, Target: 2}}, false},
+		{"cycle", Synapses{{Source: 0, Target: 1}, {Source: 1, Target: 2}, {Source: 2, Target: 1}}, false},
+		{"input target", Synapses{{Source: 2, Target: 0}}, false},
+	} {
+		net, err := New(neurons, c.synapses)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if net.FeedForward() != c.forward {
+			t.Errorf("Expected %s network to be feed-forward: %v", c.name, c.forward)
+		}
+	}
+}
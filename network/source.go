@@ -0,0 +1,197 @@
+/*
+Copyright (c) 2015 Brian Hummer (brian@redq.me), All rights reserved.
+
+Redistribution and use in source and binary forms, with or without modification, are permitted
+provided that the following conditions are met:
+
+Redistributions of source code must retain the above copyright notice, this list of conditions
+and the following disclaimer. Redistributions in binary form must reproduce the above copyright
+notice, this list of conditions and the following disclaimer in the documentation and/or other
+materials provided with the distribution. Neither the name of the nor the names of its
+contributors may be used to endorse or promote products derived from this software without
+specific prior written permission. THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND
+CONTRIBUTORS "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO,
+THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE DISCLAIMED.
+IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT,
+INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO,
+PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT
+LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE OF
+THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package network
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Go source of the built-in activation functions. Each body must match the function registered in
+// the neat package so that generated networks produce the same values as decoded ones.
+var activationSources = map[string]struct {
+	body string
+	math bool // Body uses the math package
+	exp1 bool // Body uses the fast exponent approximation
+}{
+	"Direct":           {"return x", false, false},
+	"Steepend Sigmoid": {"return 1.0 / (1.0 + %sExp1(-4.9*x))", false, true},
+	"Sigmoid":          {"return 1.0 / (1.0 + %sExp1(-x))", false, true},
+	"Tanh":             {"return math.Tanh(0.9 * x)", true, false},
+	"Inverse ABS":      {"return x / (1.0 + math.Abs(x))", true, false},
+	"Gaussian":         {"return math.Exp(-x * x)", true, false},
+	"Sine":             {"return math.Sin(x)", true, false},
+	"Abs":              {"return math.Abs(x)", true, false},
+	"Step":             {"if x > 0 {\n\t\treturn 1.0\n\t}\n\treturn 0", false, false},
+	"ReLU":             {"return math.Max(0, x)", true, false},
+	"Linear Clipped":   {"return math.Max(-1.0, math.Min(1.0, x))", true, false},
+}
+
+// Writes a standalone Go source file, in package pkg, which evaluates the network with no
+// dependency on this library. A feed-forward network becomes a function, name(inputs) []float64;
+// a recurrent network becomes a type, name, whose Activate and Reset methods keep the network's
+// memory. Networks using custom activation functions cannot be generated.
+func (x Interchange) WriteGo(w io.Writer, pkg, name string) error {
+	if !token.IsIdentifier(pkg) || !token.IsIdentifier(name) {
+		return fmt.Errorf("network.Interchange.WriteGo - Invalid package %q or name %q", pkg, name)
+	}
+	neurons, synapses, err := x.structure()
+	if err != nil {
+		return err
+	}
+	var net *Classic
+	if net, err = New(neurons, synapses); err != nil {
+		return err
+	}
+
+	// Name the activation function of each neuron and collect the ones which are used
+	prefix := strings.ToLower(name)
+	funcs := make([]string, len(neurons))
+	used := make(map[string]bool)
+	var names []string
+	usesMath, usesExp1 := false, false
+	for i, n := range x.Neurons {
+		src, ok := activationSources[n.Activation]
+		if !ok {
+			return fmt.Errorf("network.Interchange.WriteGo - Cannot generate activation %q of neuron %d", n.Activation, i)
+		}
+		funcs[i] = prefix + strings.Replace(n.Activation, " ", "", -1)
+		if !used[n.Activation] {
+			used[n.Activation] = true
+			names = append(names, n.Activation)
+			usesMath = usesMath || src.math
+			usesExp1 = usesExp1 || src.exp1
+		}
+	}
+
+	b := new(bytes.Buffer)
+	fmt.Fprintf(b, "// Code generated from an evolved network by github.com/rqme/neat/network. DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "package %s\n\n", pkg)
+	if usesMath {
+		fmt.Fprintf(b, "import \"math\"\n\n")
+	}
+	fmt.Fprintf(b, "// Number of input and output values of %s\n", name)
+	fmt.Fprintf(b, "const (\n\t%sInputs = %d\n\t%sOutputs = %d\n)\n\n", name, net.inputs, name, net.outputs)
+
+	first := net.biases + net.inputs
+	offset := len(neurons) - net.outputs
+	outputs := func(v string) string {
+		s := make([]string, net.outputs)
+		for i := range s {
+			s[i] = fmt.Sprintf("%s[%d]", v, offset+i)
+		}
+		return strings.Join(s, ", ")
+	}
+	weight := func(w float64) string { return strconv.FormatFloat(w, 'g', -1, 64) }
+
+	switch x.Kind {
+	case KindFeedForward:
+		var c *Compiled
+		if c, err = Compile(net); err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "// %s activates the evolved network with the inputs and returns the output values. Missing\n", name)
+		fmt.Fprintf(b, "// inputs are treated as 0.\n")
+		fmt.Fprintf(b, "func %s(inputs []float64) []float64 {\n", name)
+		fmt.Fprintf(b, "\tvar v [%d]float64\n", len(neurons))
+		fmt.Fprintf(b, "\tvar in [%d]float64\n", net.inputs)
+		fmt.Fprintf(b, "\tcopy(in[:], inputs)\n")
+		for i := 0; i < net.biases; i++ {
+			fmt.Fprintf(b, "\tv[%d] = %s(1.0)\n", i, funcs[i])
+		}
+		for i := 0; i < net.inputs; i++ {
+			fmt.Fprintf(b, "\tv[%d] = %s(in[%d])\n", i+net.biases, funcs[i+net.biases], i)
+		}
+		for k, idx := range c.order {
+			terms := make([]string, 0, c.starts[k+1]-c.starts[k])
+			for j := c.starts[k]; j < c.starts[k+1]; j++ {
+				terms = append(terms, fmt.Sprintf("v[%d]*%s", c.sources[j], weight(c.weights[j])))
+			}
+			if len(terms) == 0 {
+				terms = append(terms, "0")
+			}
+			fmt.Fprintf(b, "\tv[%d] = %s(%s)\n", idx, funcs[idx], strings.Join(terms, " + "))
+		}
+		fmt.Fprintf(b, "\treturn []float64{%s}\n}\n\n", outputs("v"))
+
+	case KindRecurrent:
+		if x.Iterations < 1 {
+			return fmt.Errorf("network.Interchange.WriteGo - Network must be iterated at least once. Iterations %d", x.Iterations)
+		}
+		fmt.Fprintf(b, "// %s is an evolved recurrent network. The activated value of each neuron persists between\n", name)
+		fmt.Fprintf(b, "// calls to Activate until Reset is called. The zero value is ready to use.\n")
+		fmt.Fprintf(b, "type %s struct {\n\tstate [%d]float64\n}\n\n", name, len(neurons))
+		fmt.Fprintf(b, "// Clears the memory of the network\n")
+		fmt.Fprintf(b, "func (n *%s) Reset() { n.state = [%d]float64{} }\n\n", name, len(neurons))
+		fmt.Fprintf(b, "// Activates the network with the inputs and returns the output values\n")
+		fmt.Fprintf(b, "func (n *%s) Activate(inputs []float64) []float64 {\n", name)
+		fmt.Fprintf(b, "\ts := &n.state\n")
+		for i := 0; i < net.biases; i++ {
+			fmt.Fprintf(b, "\ts[%d] = %s(1.0)\n", i, funcs[i])
+		}
+		for i := 0; i < net.inputs; i++ {
+			fmt.Fprintf(b, "\tif len(inputs) > %d {\n\t\ts[%d] = %s(inputs[%d])\n\t}\n", i, i+net.biases, funcs[i+net.biases], i)
+		}
+		fmt.Fprintf(b, "\tfor k := 0; k < %d; k++ {\n", x.Iterations)
+		fmt.Fprintf(b, "\t\tvar sum [%d]float64\n", len(neurons))
+		for _, s := range synapses {
+			fmt.Fprintf(b, "\t\tsum[%d] += s[%d] * %s\n", s.Target, s.Source, weight(s.Weight))
+		}
+		for i := first; i < len(neurons); i++ {
+			fmt.Fprintf(b, "\t\ts[%d] = %s(sum[%d])\n", i, funcs[i], i)
+		}
+		fmt.Fprintf(b, "\t}\n")
+		fmt.Fprintf(b, "\treturn []float64{%s}\n}\n\n", outputs("s"))
+
+	default:
+		return fmt.Errorf("network.Interchange.WriteGo - Unknown kind %q", x.Kind)
+	}
+
+	// Write the activation functions
+	for _, a := range names {
+		body := activationSources[a].body
+		if activationSources[a].exp1 {
+			body = fmt.Sprintf(body, prefix)
+		}
+		fmt.Fprintf(b, "func %s(x float64) float64 {\n\t%s\n}\n\n", prefix+strings.Replace(a, " ", "", -1), body)
+	}
+	if usesExp1 {
+		fmt.Fprintf(b, "func %sExp1(x float64) float64 {\n\tx = 1.0 + x/256.0\n", prefix)
+		for i := 0; i < 8; i++ {
+			fmt.Fprintf(b, "\tx *= x\n")
+		}
+		fmt.Fprintf(b, "\treturn x\n}\n")
+	}
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return fmt.Errorf("network.Interchange.WriteGo - Could not format source: %v", err)
+	}
+	_, err = w.Write(src)
+	return err
+}
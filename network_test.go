@@ -0,0 +1,123 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+package neat
+
+import (
+	"math"
+	"testing"
+)
+
+func TestActivationByNameRoundTrips(t *testing.T) {
+	for _, a := range append([]ActivationType{Direct}, Activations...) {
+		if actual, ok := ActivationByName(a.String()); !ok || actual != a {
+			t.Errorf("Expected %s to be found as %d, got %d and %v", a, a, actual, ok)
+		}
+	}
+	for _, name := range []string{"SteependSigmoid", "steepend sigmoid", "STEEPEND SIGMOID"} {
+		if actual, ok := ActivationByName(name); !ok || actual != SteependSigmoid {
+			t.Errorf("Expected %q to find the steepend sigmoid, got %d and %v", name, actual, ok)
+		}
+	}
+	if _, ok := ActivationByName("no such activation"); ok {
+		t.Errorf("Expected an unknown name not to be found")
+	}
+}
+
+func TestRegisterActivation(t *testing.T) {
+	square := func(x float64) float64 { return x * x }
+	a, err := RegisterActivation("Test Square", square, 0, math.Inf(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a < FirstCustomActivation {
+		t.Errorf("Expected a custom activation type, got %d", a)
+	}
+	if actual, ok := ActivationByName("testsquare"); !ok || actual != a {
+		t.Errorf("Expected the registered activation to be found by name, got %d and %v", actual, ok)
+	}
+	if fn, ok := a.Func(); !ok || fn(3) != 9 {
+		t.Errorf("Expected the registered function")
+	}
+	if min, max := a.Range(); min != 0 || !math.IsInf(max, 1) {
+		t.Errorf("Expected the registered range, got [%f, %f]", min, max)
+	}
+
+	// Duplicates, including those of built-in activations, and missing functions are rejected
+	for _, name := range []string{"Test Square", "testSquare", "Sigmoid", "linear clipped"} {
+		if _, err := RegisterActivation(name, square, 0, 1); err == nil {
+			t.Errorf("Expected %q to be rejected as a duplicate", name)
+		}
+	}
+	if _, err := RegisterActivation("Test Nil", nil, 0, 1); err == nil {
+		t.Errorf("Expected a nil function to be rejected")
+	}
+	if _, ok := ActivationByName("Test Nil"); ok {
+		t.Errorf("Expected the rejected activation not to be registered")
+	}
+}
+
+func TestActivationValues(t *testing.T) {
+	var cases = []struct {
+		a        ActivationType
+		x, value float64
+	}{
+		{Gaussian, 0, 1},
+		{Gaussian, 1, math.Exp(-1)},
+		{Sine, math.Pi / 2, 1},
+		{Abs, -2, 2},
+		{ReLU, -1, 0},
+		{ReLU, 2, 2},
+		{LinearClipped, 0.5, 0.5},
+		{LinearClipped, 5, 1},
+		{LinearClipped, -5, -1},
+		{Step, 0, 0},
+		{Step, 0.1, 1},
+	}
+	for _, c := range cases {
+		fn, _ := c.a.Func()
+		if actual := fn(c.x); math.Abs(actual-c.value) > 1e-9 {
+			t.Errorf("%s(%f): expected %f, got %f", c.a, c.x, c.value, actual)
+		}
+	}
+}
+
+func TestActivationsStayInRange(t *testing.T) {
+	for _, a := range append([]ActivationType{Direct}, Activations...) {
+		fn, ok := a.Func()
+		if !ok {
+			t.Fatalf("No function for %s", a)
+		}
+		min, max := a.Range()
+		for x := -10.0; x <= 10; x += 0.25 {
+			if y := fn(x); y < min || y > max {
+				t.Errorf("%s(%f) = %f is outside [%f, %f]", a, x, y, min, max)
+			}
+		}
+	}
+	if min, max := ActivationType(0).Range(); !math.IsNaN(min) || !math.IsNaN(max) {
+		t.Errorf("Expected no range for an unknown activation")
+	}
+}
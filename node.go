@@ -105,6 +105,9 @@ type sortNodesByKey struct {
 func (g *sortNodesByKey) Len() int { return len(g.nodes) }
 func (g *sortNodesByKey) Less(i, j int) bool {
 	if g.nodes[i].Y == g.nodes[j].Y {
+		if g.nodes[i].X == g.nodes[j].X {
+			return g.nodes[i].Innovation < g.nodes[j].Innovation // Keep the order repeatable
+		}
 		return g.nodes[i].X < g.nodes[j].X
 	} else {
 		return g.nodes[i].Y < g.nodes[j].Y
@@ -27,10 +27,21 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package searcher
 
 import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
 	"github.com/rqme/neat"
+	"github.com/rqme/neat/result"
 )
 
+type ConcurrentSettings interface {
+	NumWorkers() int // Number of phenomes evaluated at once. If less than 1, GOMAXPROCS is used
+}
+
 type Concurrent struct {
+	ConcurrentSettings
 	ctx neat.Context
 }
 
@@ -41,15 +52,65 @@ func (s *Concurrent) SetContext(x neat.Context) error {
 
 // Searches the phenomes concurrently and returns the results
 func (s Concurrent) Search(phenomes []neat.Phenome) ([]neat.Result, error) {
-	r := make(chan neat.Result)
-	for _, p := range phenomes {
-		go func(p neat.Phenome) {
-			r <- s.ctx.Evaluator().Evaluate(p)
-		}(p)
+	return s.SearchContext(context.Background(), phenomes)
+}
+
+// Searches the phenomes concurrently, using a fixed number of workers, and returns the results in
+// the same order as the phenomes. If the context is done before all the results are in, the search
+// stops handing out phenomes, waits for the evaluations already underway and returns the context's
+// error.
+func (s Concurrent) SearchContext(c context.Context, phenomes []neat.Phenome) ([]neat.Result, error) {
+
+	// Start the workers
+	n := 0
+	if s.ConcurrentSettings != nil {
+		n = s.NumWorkers()
+	}
+	if n < 1 {
+		n = runtime.GOMAXPROCS(0)
 	}
 	results := make([]neat.Result, len(phenomes))
-	for i := 0; i < len(phenomes); i++ {
-		results[i] = <-r
+	jobs := make(chan int)
+	done := make(chan struct{})
+	wg := new(sync.WaitGroup)
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = evaluate(s.ctx.Evaluator(), phenomes[i])
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Hand out the phenomes and wait for the workers to finish
+	for i := range phenomes {
+		select {
+		case jobs <- i:
+		case <-c.Done():
+			close(jobs)
+			<-done
+			return nil, c.Err()
+		}
+	}
+	close(jobs)
+	<-done
+	if err := c.Err(); err != nil {
+		return nil, err
 	}
 	return results, nil
 }
+
+// Evaluates a single phenome, converting a panic into the result's error
+func evaluate(e neat.Evaluator, p neat.Phenome) (r neat.Result) {
+	defer func() {
+		if x := recover(); x != nil {
+			r = result.New(p.ID(), 0, fmt.Errorf("Panic while evaluating phenome [%d]: %v", p.ID(), x), false)
+		}
+	}()
+	return e.Evaluate(p)
+}
@@ -0,0 +1,174 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package searcher
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/result"
+)
+
+// Context providing only the evaluator
+type evalContext struct {
+	neat.Context
+	evl neat.Evaluator
+}
+
+func (c evalContext) Evaluator() neat.Evaluator { return c.evl }
+
+// Phenome identified by its index
+type testPhenome int
+
+func (p testPhenome) ID() int                                  { return int(p) }
+func (p testPhenome) Traits() []float64                        { return nil }
+func (p testPhenome) Activate(in []float64) ([]float64, error) { return in, nil }
+
+// Evaluator which takes a while and counts the evaluations underway
+type slowEvaluator struct {
+	running, started, finished, peak int32
+}
+
+func (e *slowEvaluator) Evaluate(p neat.Phenome) neat.Result {
+	atomic.AddInt32(&e.started, 1)
+	n := atomic.AddInt32(&e.running, 1)
+	for m := atomic.LoadInt32(&e.peak); n > m && !atomic.CompareAndSwapInt32(&e.peak, m, n); {
+		m = atomic.LoadInt32(&e.peak)
+	}
+	time.Sleep(20 * time.Millisecond)
+	atomic.AddInt32(&e.running, -1)
+	atomic.AddInt32(&e.finished, 1)
+	return result.New(p.ID(), float64(p.ID()), nil, false)
+}
+
+func phenomes(n int) []neat.Phenome {
+	ps := make([]neat.Phenome, n)
+	for i := range ps {
+		ps[i] = testPhenome(i)
+	}
+	return ps
+}
+
+type workers int
+
+func (w workers) NumWorkers() int { return int(w) }
+
+func TestConcurrentSearchOrdersResults(t *testing.T) {
+	s := &Concurrent{ConcurrentSettings: workers(4)}
+	s.SetContext(evalContext{evl: &slowEvaluator{}})
+	rs, err := s.Search(phenomes(10))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, r := range rs {
+		if r.ID() != i {
+			t.Errorf("Result %d is for phenome %d", i, r.ID())
+		}
+	}
+}
+
+func TestConcurrentSearchLimitsWorkers(t *testing.T) {
+	e := &slowEvaluator{}
+	s := &Concurrent{ConcurrentSettings: workers(3)}
+	s.SetContext(evalContext{evl: e})
+	if _, err := s.Search(phenomes(12)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if e.peak != 3 {
+		t.Errorf("Expected at most 3 evaluations at once, saw %d", e.peak)
+	}
+}
+
+// Evaluator which panics for odd phenomes
+type panickingEvaluator struct{}
+
+func (panickingEvaluator) Evaluate(p neat.Phenome) neat.Result {
+	if p.ID()%2 == 1 {
+		panic("odd phenome")
+	}
+	return result.New(p.ID(), 1, nil, false)
+}
+
+func TestConcurrentSearchRecoversPanics(t *testing.T) {
+	s := &Concurrent{ConcurrentSettings: workers(2)}
+	s.SetContext(evalContext{evl: panickingEvaluator{}})
+	rs, err := s.Search(phenomes(6))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, r := range rs {
+		if r.ID() != i {
+			t.Errorf("Result %d is for phenome %d", i, r.ID())
+		}
+		if failed := r.Err() != nil; failed != (i%2 == 1) {
+			t.Errorf("Phenome %d: unexpected error %v", i, r.Err())
+		}
+		if r.Err() != nil && !strings.Contains(r.Err().Error(), "odd phenome") {
+			t.Errorf("Phenome %d: error %q does not describe the panic", i, r.Err())
+		}
+	}
+}
+
+func TestConcurrentSearchDrainsOnCancel(t *testing.T) {
+	e := &slowEvaluator{}
+	s := &Concurrent{ConcurrentSettings: workers(4)}
+	s.SetContext(evalContext{evl: e})
+	c, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	rs, err := s.SearchContext(c, phenomes(100))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected the context's error but got %v", err)
+	}
+	if rs != nil {
+		t.Errorf("Expected no results from a canceled search")
+	}
+	if n := atomic.LoadInt32(&e.running); n != 0 {
+		t.Errorf("%d evaluations still running after the search returned", n)
+	}
+	started, finished := atomic.LoadInt32(&e.started), atomic.LoadInt32(&e.finished)
+	if started != finished || started == 100 {
+		t.Errorf("Expected a partial search to finish what it started: started %d, finished %d", started, finished)
+	}
+}
+
+func TestSerialSearchStopsOnCancel(t *testing.T) {
+	e := &slowEvaluator{}
+	s := &Serial{}
+	s.SetContext(evalContext{evl: e})
+	c, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := s.SearchContext(c, phenomes(3)); err != context.Canceled {
+		t.Errorf("Expected the context's error but got %v", err)
+	}
+	if e.started != 0 {
+		t.Errorf("Expected no evaluations after cancellation but %d started", e.started)
+	}
+}
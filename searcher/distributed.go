@@ -0,0 +1,321 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package searcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync/atomic"
+	"time"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/decoder"
+	"github.com/rqme/neat/result"
+)
+
+// Distributed evaluation protocol
+//
+// The Distributed searcher sends genomes, rather than phenomes, to remote workers using JSON-RPC
+// 1.0 (net/rpc/jsonrpc) over TCP. Each worker decodes the genome with its own decoder, so workers
+// must be configured with the same decoder settings as the experiment, and evaluates the phenome
+// with its own evaluator. A worker exposes a single method:
+//
+//   Worker.Evaluate(WorkRequest) WorkResult
+//
+// Request:  {"method":"Worker.Evaluate","params":[{"Genome":{...}}],"id":1}
+// Response: {"id":1,"result":{"ID":12,"Fitness":3.2,"Err":"","Stop":false,"Behavior":null},"error":null}
+//
+// Errors from decoding or evaluating are returned in the result's Err field. Errors in the RPC
+// itself, such as a lost connection, cause the evaluation to be retried on another worker.
+
+// Request sent to a worker
+type WorkRequest struct {
+	Genome neat.Genome
+}
+
+// Result returned from a worker
+type WorkResult struct {
+	ID       int
+	Fitness  float64
+	Err      string // Error from decoding or evaluating the genome, if any
+	Stop     bool
+	Behavior []float64 // Behavior expressed during evaluation if the result is Behaviorable
+}
+
+// Converts the work result into a neat.Result
+func (w WorkResult) result() neat.Result {
+	var err error
+	if w.Err != "" {
+		err = errors.New(w.Err)
+	}
+	if w.Behavior != nil {
+		return result.NewNovelty(w.ID, w.Fitness, err, w.Stop, w.Behavior)
+	}
+	return result.New(w.ID, w.Fitness, err, w.Stop)
+}
+
+type DistributedSettings interface {
+	WorkerAddresses() []string        // Addresses (host:port) of the workers. Repeat an address to send it more work at once.
+	EvaluationTimeout() time.Duration // Time allowed for a single evaluation. If 0, there is no limit
+	MaxRetries() int                  // Number of times a failed evaluation is retried. If 0, it is retried once per worker
+}
+
+// Searches by distributing the evaluations to remote workers
+type Distributed struct {
+	DistributedSettings
+	ctx neat.Context
+}
+
+func (s *Distributed) SetContext(x neat.Context) error {
+	s.ctx = x
+	return nil
+}
+
+// Searches the phenomes using the remote workers and returns the results
+func (s Distributed) Search(phenomes []neat.Phenome) ([]neat.Result, error) {
+	return s.SearchContext(context.Background(), phenomes)
+}
+
+// An evaluation to be performed by a worker
+type job struct {
+	idx   int
+	req   WorkRequest
+	tries int
+}
+
+// Number of consecutive failures to connect after which a worker is no longer used
+const maxDialFailures = 3
+
+// Searches the phenomes using the remote workers and returns the results in the same order as the
+// phenomes. Each worker address is served by its own connection which takes one evaluation at a
+// time. If a worker fails or an evaluation times out, the connection is dropped and the evaluation is
+// handed to the next available worker until the retries are exhausted, at which point the result
+// carries the error. A worker which cannot be connected to hands its evaluation back without using
+// up a retry and waits longer after each failure. Once it fails maxDialFailures times in a row, it
+// is no longer used for the rest of the search. If every worker is retired, the search fails.
+func (s Distributed) SearchContext(c context.Context, phenomes []neat.Phenome) ([]neat.Result, error) {
+
+	addrs := s.WorkerAddresses()
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("searcher.Distributed.Search - No worker addresses provided")
+	}
+	retries := s.MaxRetries()
+	if retries < 1 {
+		retries = len(addrs)
+	}
+
+	// Create the jobs. The queue can hold every job so retries never block.
+	jobs := make(chan *job, len(phenomes))
+	for i, p := range phenomes {
+		var g neat.Genome
+		switch x := p.(type) {
+		case decoder.Phenome:
+			g = x.Genome
+		case *decoder.Phenome:
+			g = x.Genome
+		default:
+			return nil, fmt.Errorf("searcher.Distributed.Search - Cannot find the genome for phenome %d", p.ID())
+		}
+		jobs <- &job{idx: i, req: WorkRequest{Genome: g}}
+	}
+
+	// Start the workers
+	type done struct {
+		idx int
+		r   neat.Result
+	}
+	dc := make(chan done, len(phenomes))
+	lost := make(chan error, 1)
+	stop := make(chan struct{})
+	defer close(stop)
+	wait := func(d time.Duration) bool {
+		select {
+		case <-time.After(d):
+			return true
+		case <-stop:
+			return false
+		}
+	}
+	alive := int32(len(addrs))
+	for _, addr := range addrs {
+		go func(addr string) {
+			var cl *rpc.Client
+			defer func() {
+				if cl != nil {
+					cl.Close()
+				}
+			}()
+			fails := 0
+			for {
+				var j *job
+				select {
+				case j = <-jobs:
+				case <-stop:
+					return
+				}
+
+				// Connect to the worker if necessary. If it cannot be reached, hand the evaluation
+				// back and wait longer after each failure, until the worker is retired.
+				if cl == nil {
+					conn, err := s.dial(c, addr)
+					if err != nil {
+						jobs <- j
+						if fails += 1; fails >= maxDialFailures {
+							if atomic.AddInt32(&alive, -1) == 0 {
+								lost <- err
+							}
+							return
+						}
+						if !wait(time.Duration(fails) * 100 * time.Millisecond) {
+							return
+						}
+						continue
+					}
+					fails = 0
+					cl = jsonrpc.NewClient(conn)
+				}
+
+				var res WorkResult
+				err := s.call(c, cl, addr, j.req, &res)
+				if err == nil {
+					dc <- done{idx: j.idx, r: res.result()}
+					continue
+				}
+
+				// Drop the connection and retry elsewhere, giving the worker a moment to recover
+				cl.Close()
+				cl = nil
+				j.tries += 1
+				if j.tries > retries {
+					id := j.req.Genome.ID
+					dc <- done{idx: j.idx, r: result.New(id, 0, fmt.Errorf("Could not evaluate genome [%d] after %d attempts: %v", id, j.tries, err), false)}
+					continue
+				}
+				jobs <- j
+				if !wait(time.Duration(j.tries) * 100 * time.Millisecond) {
+					return
+				}
+			}
+		}(addr)
+	}
+
+	// Collect the results
+	results := make([]neat.Result, len(phenomes))
+	for i := 0; i < len(phenomes); i++ {
+		select {
+		case d := <-dc:
+			results[d.idx] = d.r
+		case err := <-lost:
+			return nil, fmt.Errorf("searcher.Distributed.Search - Could not connect to any worker: %v", err)
+		case <-c.Done():
+			return nil, c.Err()
+		}
+	}
+	return results, nil
+}
+
+// Connects to the worker, giving up after a few seconds or once the context is done
+func (s Distributed) dial(c context.Context, addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: 5 * time.Second}
+	return d.DialContext(c, "tcp", addr)
+}
+
+// Calls the worker and waits for the result, the timeout or the context, whichever comes first
+func (s Distributed) call(c context.Context, cl *rpc.Client, addr string, req WorkRequest, res *WorkResult) error {
+	var timeout <-chan time.Time
+	if d := s.EvaluationTimeout(); d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+
+	call := cl.Go("Worker.Evaluate", req, res, nil)
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-timeout:
+		return fmt.Errorf("Evaluation of genome [%d] on %s timed out", req.Genome.ID, addr)
+	case <-c.Done():
+		return c.Err()
+	}
+}
+
+// Worker evaluates genomes on behalf of a Distributed searcher using its context's decoder and
+// evaluator
+type Worker struct {
+	ctx neat.Context
+}
+
+func (w *Worker) SetContext(x neat.Context) error {
+	w.ctx = x
+	return nil
+}
+
+// Decodes and evaluates the requested genome
+func (w *Worker) Evaluate(req WorkRequest, res *WorkResult) error {
+	res.ID = req.Genome.ID
+	p, err := w.ctx.Decoder().Decode(req.Genome)
+	if err != nil {
+		res.Err = fmt.Sprintf("Unable to decode genome [%d]: %v", req.Genome.ID, err)
+		return nil
+	}
+	r := evaluate(w.ctx.Evaluator(), p)
+	res.Fitness = r.Fitness()
+	res.Stop = r.Stop()
+	if r.Err() != nil {
+		res.Err = r.Err().Error()
+	}
+	if br, ok := r.(neat.Behaviorable); ok {
+		res.Behavior = br.Behavior()
+		if res.Behavior == nil {
+			res.Behavior = []float64{}
+		}
+	}
+	return nil
+}
+
+// Serves evaluation requests on the listener using the context's decoder and evaluator. Each
+// connection is handled concurrently. Serve returns when the listener fails or is closed.
+func Serve(l net.Listener, ctx neat.Context) error {
+	w := &Worker{}
+	w.SetContext(ctx)
+	srv := rpc.NewServer()
+	if err := srv.RegisterName("Worker", w); err != nil {
+		return err
+	}
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeCodec(jsonrpc.NewServerCodec(conn))
+	}
+}
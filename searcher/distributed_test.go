@@ -0,0 +1,142 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package searcher
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/decoder"
+)
+
+type distributedSettings []string
+
+func (s distributedSettings) WorkerAddresses() []string        { return s }
+func (s distributedSettings) EvaluationTimeout() time.Duration { return 0 }
+func (s distributedSettings) MaxRetries() int                  { return 0 }
+
+// Context providing a worker's decoder and evaluator
+type workerContext struct {
+	neat.Context
+	evl neat.Evaluator
+}
+
+func (c workerContext) Decoder() neat.Decoder     { return testDecoder{} }
+func (c workerContext) Evaluator() neat.Evaluator { return c.evl }
+
+type testDecoder struct{}
+
+func (testDecoder) Decode(g neat.Genome) (neat.Phenome, error) { return testPhenome(g.ID), nil }
+
+// Listener which also closes the connections it accepted when closed, as a worker which dies would
+type closingListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *closingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (l *closingListener) Close() error {
+	err := l.Listener.Close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		conn.Close()
+	}
+	return err
+}
+
+// Starts the workers on local ports and returns their listeners and addresses
+func startWorkers(t *testing.T, n int) ([]*closingListener, distributedSettings) {
+	ls := make([]*closingListener, n)
+	addrs := make(distributedSettings, n)
+	for i := range ls {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ls[i] = &closingListener{Listener: l}
+		addrs[i] = l.Addr().String()
+		go Serve(ls[i], workerContext{evl: &slowEvaluator{}})
+		t.Cleanup(func() { ls[i].Close() })
+	}
+	return ls, addrs
+}
+
+func genomePhenomes(n int) []neat.Phenome {
+	ps := make([]neat.Phenome, n)
+	for i := range ps {
+		ps[i] = decoder.Phenome{Genome: neat.Genome{ID: i}}
+	}
+	return ps
+}
+
+func TestDistributedSearchSurvivesDeadWorker(t *testing.T) {
+	ls, addrs := startWorkers(t, 3)
+	s := &Distributed{DistributedSettings: addrs}
+
+	// Kill a worker part way through the search
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		ls[1].Close()
+	}()
+	rs, err := s.Search(genomePhenomes(30))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for i, r := range rs {
+		if r == nil {
+			t.Fatalf("No result for phenome %d", i)
+		}
+		if r.ID() != i || r.Fitness() != float64(i) || r.Err() != nil {
+			t.Errorf("Result %d: unexpected result for phenome %d with fitness %f and error %v", i, r.ID(), r.Fitness(), r.Err())
+		}
+	}
+}
+
+func TestDistributedSearchFailsWithoutWorkers(t *testing.T) {
+	ls, addrs := startWorkers(t, 2)
+	for _, l := range ls {
+		l.Close()
+	}
+	s := &Distributed{DistributedSettings: addrs}
+	if _, err := s.Search(genomePhenomes(5)); err == nil {
+		t.Errorf("Expected an error when no worker can be reached")
+	}
+}
@@ -27,6 +27,7 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package searcher
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
@@ -81,7 +82,12 @@ func (s *Novelty) SetContext(x neat.Context) error {
 }
 
 // Searches the phenomes one by one and returns the results
-func (s Novelty) Search(phenomes []neat.Phenome) ([]neat.Result, error) {
+func (s *Novelty) Search(phenomes []neat.Phenome) ([]neat.Result, error) {
+	return s.SearchContext(context.Background(), phenomes)
+}
+
+// Searches the phenomes, passing the context along to the inner searcher if it is Cancelable
+func (s *Novelty) SearchContext(c context.Context, phenomes []neat.Phenome) ([]neat.Result, error) {
 
 	// Re-evaluate archive phenomes if necessary
 	var bs BehaviorRecords = make([]BehaviorRecord, 0, len(phenomes)+len(s.behaviors)) // behaviors
@@ -92,13 +98,20 @@ func (s Novelty) Search(phenomes []neat.Phenome) ([]neat.Result, error) {
 	// Execute the search using the inner searcher
 	var rs []neat.Result
 	var err error
+	ps := phenomes
 	if s.NoveltyEvalArchive() && len(s.archive) > 0 {
-		rs, err = s.Searcher.Search(append(phenomes, s.archive...))
+		ps = append(phenomes, s.archive...)
+	}
+	if ch, ok := s.Searcher.(neat.Cancelable); ok {
+		rs, err = ch.SearchContext(c, ps)
 	} else {
-		rs, err = s.Searcher.Search(phenomes)
+		rs, err = s.Searcher.Search(ps)
 	}
 
 	if err != nil {
+		if err == c.Err() {
+			return nil, err
+		}
 		err = fmt.Errorf("Error running search: %v", err)
 		return nil, err
 	}
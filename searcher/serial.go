@@ -26,7 +26,11 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 
 package searcher
 
-import "github.com/rqme/neat"
+import (
+	"context"
+
+	"github.com/rqme/neat"
+)
 
 type Serial struct {
 	ctx neat.Context
@@ -39,9 +43,18 @@ func (s *Serial) SetContext(x neat.Context) error {
 
 // Searches the phenomes one by one and returns the results
 func (s Serial) Search(phenomes []neat.Phenome) ([]neat.Result, error) {
+	return s.SearchContext(context.Background(), phenomes)
+}
+
+// Searches the phenomes one by one and returns the results. The context is checked before each
+// evaluation and, if done, its error is returned.
+func (s Serial) SearchContext(c context.Context, phenomes []neat.Phenome) ([]neat.Result, error) {
 	results := make([]neat.Result, len(phenomes))
 	for i, phenome := range phenomes {
-		results[i] = s.ctx.Evaluator().Evaluate(phenome)
+		if err := c.Err(); err != nil {
+			return nil, err
+		}
+		results[i] = evaluate(s.ctx.Evaluator(), phenome)
 	}
 	return results, nil
 }
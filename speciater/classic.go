@@ -27,16 +27,27 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package speciater
 
 import (
+	"fmt"
+
 	"github.com/rqme/neat"
 )
 
+// Ways of choosing the genome which represents a species when speciating the next generation
+const (
+	PermanentRepresentative = "permanent" // The genome which founded the species
+	RandomRepresentative    = "random"    // A random member of the species in the previous generation
+	ChampionRepresentative  = "champion"  // The fittest member of the species in the previous generation
+)
+
 type ClassicSettings interface {
 	CompatibilityThreshold() float64 // Threshold above which two genomes are not compatible
+	SpeciesRepresentative() string   // Way of choosing each species' representative. If empty, it is permanent.
 }
 
 type Classic struct {
 	ClassicSettings
 	ctx neat.Context
+	pop neat.Population // Previous generation, from which representatives are chosen
 }
 
 func (s *Classic) SetContext(x neat.Context) error {
@@ -44,6 +55,12 @@ func (s *Classic) SetContext(x neat.Context) error {
 	return nil
 }
 
+// Records the previous generation so that representatives can be chosen from its members
+func (s *Classic) SetPopulation(p neat.Population) error {
+	s.pop = p
+	return nil
+}
+
 // Assigns the genomes to a species. Returns new collection of species.
 //
 // Throughout evolution, NEAT maintains a list of species numbered in the order they ap- peared. In
@@ -57,15 +74,14 @@ func (s *Classic) SetContext(x neat.Context) error {
 // generation so that the same species numbers can be used to identify species throughout the run.
 // (Stanley, 39)
 //
-// TODO: Pick a random reprentative each time instead of permanently recording it with the species
-// record
+// The representative is permanent unless the settings ask for a random member or the champion of
+// the species in the previous generation to be chosen each generation.
 func (s Classic) Speciate(curr []neat.Species, genomes []neat.Genome) (next []neat.Species, err error) {
 
-	// Copy the species to the new set
-	next = make([]neat.Species, len(curr))
-	for i, s := range curr {
-		next[i] = s
-		next[i].Age = s.Age + 1
+	// Copy the species to the new set and choose their representatives
+	next = age(curr)
+	if err = s.represent(next); err != nil {
+		return
 	}
 
 	// Iterate the genomes, looking for target species
@@ -90,6 +106,7 @@ func (s Classic) Speciate(curr []neat.Species, genomes []neat.Genome) (next []ne
 			genomes[i].SpeciesIdx = len(next)
 			cnts = append(cnts, 1)
 			species := neat.Species{
+				ID:      genomes[i].ID,
 				Example: neat.CopyGenome(genomes[i]),
 			}
 			next = append(next, species)
@@ -115,3 +132,61 @@ func (s Classic) Speciate(curr []neat.Species, genomes []neat.Genome) (next []ne
 
 	return
 }
+
+// Replaces the representative of each species with one of its members in the previous generation
+func (s Classic) represent(species []neat.Species) error {
+	mode := s.SpeciesRepresentative()
+	switch mode {
+	case "", PermanentRepresentative:
+		return nil
+	case RandomRepresentative, ChampionRepresentative:
+	default:
+		return fmt.Errorf("speciater.Classic.Speciate - Unknown species representative %q", mode)
+	}
+
+	// Group the previous generation by species. The population is only used if it is the one
+	// the species came from.
+	if len(s.pop.Species) != len(species) {
+		return nil
+	}
+	members := make([][]neat.Genome, len(species))
+	for _, g := range s.pop.Genomes {
+		if g.SpeciesIdx >= 0 && g.SpeciesIdx < len(members) {
+			members[g.SpeciesIdx] = append(members[g.SpeciesIdx], g)
+		}
+	}
+
+	// Choose the representatives
+	rng := s.ctx.Rand()
+	for i, l := range members {
+		if len(l) == 0 {
+			continue
+		}
+		r := 0
+		if mode == RandomRepresentative {
+			r = rng.Intn(len(l))
+		} else {
+			for j, g := range l {
+				if g.Fitness > l[r].Fitness {
+					r = j
+				}
+			}
+		}
+		species[i].Example = neat.CopyGenome(l[r])
+	}
+	return nil
+}
+
+// Returns a copy of the species aged by a generation. Species archived before they were given IDs
+// are identified by their example.
+func age(curr []neat.Species) []neat.Species {
+	next := make([]neat.Species, len(curr))
+	for i, s := range curr {
+		next[i] = s
+		next[i].Age = s.Age + 1
+		if s.ID == 0 {
+			next[i].ID = s.Example.ID
+		}
+	}
+	return next
+}
@@ -43,6 +43,9 @@ type DynamicSettings interface {
 type Dynamic struct {
 	DynamicSettings
 	Classic
+
+	// internal state
+	threshold float64
 }
 
 func NewDynamic(ds DynamicSettings, cs ClassicSettings) *Dynamic {
@@ -52,8 +55,18 @@ func NewDynamic(ds DynamicSettings, cs ClassicSettings) *Dynamic {
 	}
 }
 
+// Returns the speciater's adjusted threshold so that it can be archived
+func (s *Dynamic) Checkpoint() interface{} {
+	return &struct{ Threshold *float64 }{&s.threshold}
+}
+
 func (s *Dynamic) Speciate(curr []neat.Species, genomes []neat.Genome) (next []neat.Species, err error) {
 
+	// Apply a restored threshold
+	if s.threshold > 0 {
+		s.SetCompatibilityThreshold(s.threshold)
+	}
+
 	// Speciate using the internal speciater
 	next, err = s.Classic.Speciate(curr, genomes)
 	if err != nil {
@@ -71,5 +84,6 @@ func (s *Dynamic) Speciate(curr []neat.Species, genomes []neat.Genome) (next []n
 		ct += s.CompatibilityModifier()
 	}
 	s.SetCompatibilityThreshold(ct)
+	s.threshold = ct
 	return
 }
@@ -0,0 +1,234 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package speciater
+
+import (
+	"math"
+
+	"github.com/rqme/neat"
+)
+
+const (
+	MaxMedoidIterations = 100 // Limit on the rounds of reassigning genomes and moving medoids
+)
+
+type KMedoidsSettings interface {
+	TargetNumberOfSpecies() int // The desired number of species
+}
+
+// KMedoids clusters the genomes into the target number of species by their compatibility distance
+// instead of adjusting a compatibility threshold. Each species is represented by its medoid, the
+// member whose total distance to the rest of the species is least. The clusters are seeded with
+// the genomes closest to the representatives of the existing species so that species keep their
+// identities from one generation to the next.
+type KMedoids struct {
+	KMedoidsSettings
+	ctx neat.Context
+}
+
+func (s *KMedoids) SetContext(x neat.Context) error {
+	s.ctx = x
+	return nil
+}
+
+func (s KMedoids) Speciate(curr []neat.Species, genomes []neat.Genome) (next []neat.Species, err error) {
+	n := len(genomes)
+	if n == 0 {
+		return
+	}
+	k := s.TargetNumberOfSpecies()
+	if k < 1 {
+		k = 1
+	} else if k > n {
+		k = n
+	}
+
+	// Measure the distances between the genomes
+	cmp := s.ctx.Comparer()
+	d := make([][]float64, n)
+	for i := range d {
+		d[i] = make([]float64, n)
+	}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			if d[i][j], err = cmp.Compare(genomes[i], genomes[j]); err != nil {
+				return
+			}
+			d[j][i] = d[i][j]
+		}
+	}
+
+	// Seed a cluster for each existing species with the genome closest to its representative
+	prev := age(curr)
+	medoids := make([]int, 0, k)
+	origin := make([]int, 0, k) // Index of the species continued by each cluster or -1 if new
+	used := make([]bool, n)
+	var δ float64
+	for j, sp := range prev {
+		if len(medoids) == k {
+			break
+		}
+		best, bd := -1, math.Inf(1)
+		for i, g := range genomes {
+			if used[i] {
+				continue
+			}
+			if δ, err = cmp.Compare(g, sp.Example); err != nil {
+				return
+			}
+			if δ < bd {
+				best, bd = i, δ
+			}
+		}
+		if best == -1 {
+			best = unused(used) // Every distance was NaN or infinite
+		}
+		used[best] = true
+		medoids = append(medoids, best)
+		origin = append(origin, j)
+	}
+
+	// Seed the remaining clusters with the genomes farthest from the existing seeds
+	for len(medoids) < k {
+		best, bd := -1, -1.0
+		for i := range genomes {
+			if used[i] {
+				continue
+			}
+			if _, δ := nearest(d, medoids, i); δ > bd {
+				best, bd = i, δ
+			}
+		}
+		if best == -1 {
+			best = unused(used)
+		}
+		used[best] = true
+		medoids = append(medoids, best)
+		origin = append(origin, -1)
+	}
+
+	// Alternate between assigning each genome to its nearest medoid and moving each medoid to the
+	// member of its cluster closest to the others
+	assign := make([]int, n)
+	for it := 0; ; it++ {
+		for i := range genomes {
+			assign[i], _ = nearest(d, medoids, i)
+		}
+		for c, m := range medoids {
+			assign[m] = c
+		}
+		if it == MaxMedoidIterations || !move(d, assign, medoids) {
+			break
+		}
+	}
+
+	// Create the species. New species take the ID of a member which does not identify an
+	// existing species.
+	ids := make(map[int]bool, len(prev)+k)
+	for _, sp := range prev {
+		ids[sp.ID] = true
+	}
+	next = make([]neat.Species, k)
+	for c, m := range medoids {
+		if origin[c] >= 0 {
+			next[c] = prev[origin[c]]
+		}
+		next[c].Example = neat.CopyGenome(genomes[m])
+	}
+	for c, m := range medoids {
+		if origin[c] >= 0 {
+			continue
+		}
+		next[c].ID = genomes[m].ID
+		for i := 0; ids[next[c].ID] && i < n; i++ {
+			if assign[i] == c {
+				next[c].ID = genomes[i].ID
+			}
+		}
+		ids[next[c].ID] = true
+	}
+	for i := range genomes {
+		genomes[i].SpeciesIdx = assign[i]
+	}
+	return
+}
+
+// Returns the index of the first genome not yet used as a medoid. There is always one as there
+// are no more clusters than genomes.
+func unused(used []bool) int {
+	for i, u := range used {
+		if !u {
+			return i
+		}
+	}
+	return -1
+}
+
+// Returns the index of the medoid nearest the genome and its distance. Ties go to the first
+// medoid, as does a genome which is not a finite distance from any medoid. If there are no
+// medoids, the index is -1 and the distance is infinite.
+func nearest(d [][]float64, medoids []int, i int) (c int, δ float64) {
+	c, δ = -1, math.Inf(1)
+	for j, m := range medoids {
+		if d[i][m] < δ {
+			c, δ = j, d[i][m]
+		}
+	}
+	if c == -1 && len(medoids) > 0 {
+		c = 0
+	}
+	return
+}
+
+// Moves each medoid to the member of its cluster with the least total distance to the other
+// members. Returns true if any medoid moved.
+func move(d [][]float64, assign, medoids []int) (moved bool) {
+	cost := func(c, i int) (t float64) {
+		for j, a := range assign {
+			if a == c {
+				t += d[i][j]
+			}
+		}
+		return
+	}
+	for c, m := range medoids {
+		best, bc := m, cost(c, m)
+		for i, a := range assign {
+			if a != c || i == m {
+				continue
+			}
+			if t := cost(c, i); t < bc {
+				best, bc = i, t
+			}
+		}
+		if best != m {
+			medoids[c] = best
+			moved = true
+		}
+	}
+	return
+}
@@ -0,0 +1,145 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package speciater
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+// Comparer which measures the distance between the genomes' first traits
+type traitComparer func(a, b float64) float64
+
+func (c traitComparer) Compare(g1, g2 neat.Genome) (float64, error) {
+	return c(g1.Traits[0], g2.Traits[0]), nil
+}
+
+type comparerContext struct {
+	neat.Context
+	cmp neat.Comparer
+}
+
+func (c comparerContext) Comparer() neat.Comparer { return c.cmp }
+
+type targetSettings int
+
+func (t targetSettings) TargetNumberOfSpecies() int { return int(t) }
+
+func newKMedoids(k int, f traitComparer) *KMedoids {
+	s := &KMedoids{KMedoidsSettings: targetSettings(k)}
+	s.SetContext(comparerContext{cmp: f})
+	return s
+}
+
+func distance(a, b float64) float64 { return math.Abs(a - b) }
+
+// Returns genomes with IDs from the first and traits from the positions
+func positioned(id int, xs ...float64) []neat.Genome {
+	gs := make([]neat.Genome, len(xs))
+	for i, x := range xs {
+		gs[i] = neat.Genome{ID: id + i, Traits: []float64{x}}
+	}
+	return gs
+}
+
+func TestKMedoidsClustersGenomes(t *testing.T) {
+	s := newKMedoids(2, distance)
+	gs := positioned(1, 0, 1, 2, 100, 101, 102)
+	sp, err := s.Speciate(nil, gs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp) != 2 {
+		t.Fatalf("Expected 2 species but got %d", len(sp))
+	}
+	for i, g := range gs {
+		if g.SpeciesIdx != gs[i/3*3].SpeciesIdx {
+			t.Errorf("Genome %d at %f is not in the species of its neighbours", g.ID, g.Traits[0])
+		}
+	}
+	if gs[0].SpeciesIdx == gs[3].SpeciesIdx {
+		t.Errorf("The two groups share species %d", gs[0].SpeciesIdx)
+	}
+	for _, x := range sp {
+		if m := x.Example.Traits[0]; m != 1 && m != 101 {
+			t.Errorf("Expected the medoids to be the middle of each group, not %f", m)
+		}
+	}
+
+	// The next generation's clusters continue the species nearest them
+	next := positioned(7, 100.5, 101.5, 0.5, 1.5)
+	sp2, err := s.Speciate(sp, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, g := range next {
+		want := sp[gs[0].SpeciesIdx].ID
+		if g.Traits[0] > 50 {
+			want = sp[gs[3].SpeciesIdx].ID
+		}
+		if got := sp2[g.SpeciesIdx]; got.ID != want || got.Age != 1 {
+			t.Errorf("Genome at %f is in species %d aged %d, expected %d aged 1", g.Traits[0], got.ID, got.Age, want)
+		}
+	}
+}
+
+func TestKMedoidsLimitsClustersToGenomes(t *testing.T) {
+	s := newKMedoids(5, distance)
+	gs := positioned(1, 0, 10)
+	sp, err := s.Speciate(nil, gs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sp) != 2 || sp[0].ID == sp[1].ID {
+		t.Errorf("Expected 2 species with their own IDs but got %v", sp)
+	}
+}
+
+func TestKMedoidsSurvivesUndefinedDistances(t *testing.T) {
+	for _, δ := range []float64{math.NaN(), math.Inf(1)} {
+		s := newKMedoids(2, func(a, b float64) float64 { return δ })
+		gs := positioned(1, 0, 1, 2)
+		sp, err := s.Speciate(nil, gs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sp, err = s.Speciate(sp, positioned(4, 0, 1, 2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sp) != 2 {
+			t.Errorf("Expected 2 species with distance %f but got %d", δ, len(sp))
+		}
+		for _, g := range gs {
+			if g.SpeciesIdx < 0 || g.SpeciesIdx >= len(sp) {
+				t.Errorf("Genome %d with distance %f was assigned to species %d", g.ID, δ, g.SpeciesIdx)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+// Package substrate builds the layers of a HyperNEAT substrate from common geometries or from a
+// JSON file so that sensor layouts can be described without hand coding each node's position.
+package substrate
+
+import (
+	"math"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/decoder"
+)
+
+// Returns n values evenly spaced from -1 to 1. A single value is 0.
+func spread(n int) []float64 {
+	vs := make([]float64, n)
+	if n == 1 {
+		return vs
+	}
+	for i := range vs {
+		vs[i] = float64(i)/float64(n-1)*2.0 - 1.0
+	}
+	return vs
+}
+
+// Grid returns a layer of cols by rows nodes evenly spaced over the square from -1 to 1. The
+// nodes are ordered by column and then row so that node x*rows+y is in column x and row y.
+func Grid(t neat.NeuronType, cols, rows int) decoder.SubstrateNodes {
+	l := make(decoder.SubstrateNodes, 0, cols*rows)
+	ys := spread(rows)
+	for _, x := range spread(cols) {
+		for _, y := range ys {
+			l = append(l, decoder.SubstrateNode{Position: []float64{x, y}, NeuronType: t})
+		}
+	}
+	return l
+}
+
+// Line returns a layer of n nodes evenly spaced along the x axis from -1 to 1
+func Line(t neat.NeuronType, n int) decoder.SubstrateNodes {
+	l := make(decoder.SubstrateNodes, 0, n)
+	for _, x := range spread(n) {
+		l = append(l, decoder.SubstrateNode{Position: []float64{x, 0}, NeuronType: t})
+	}
+	return l
+}
+
+// Circle returns a layer of n nodes evenly spaced around a circle of the radius about the origin,
+// starting on the positive x axis and turning counter-clockwise
+func Circle(t neat.NeuronType, n int, radius float64) decoder.SubstrateNodes {
+	l := make(decoder.SubstrateNodes, 0, n)
+	for i := 0; i < n; i++ {
+		a := 2 * math.Pi * float64(i) / float64(n)
+		l = append(l, decoder.SubstrateNode{Position: []float64{radius * math.Cos(a), radius * math.Sin(a)}, NeuronType: t})
+	}
+	return l
+}
+
+// Sandwich returns the state-space sandwich: an input grid and an output grid of the same size
+// whose nodes share their positions so that each output lies opposite its input. (Stanley, p.15)
+func Sandwich(cols, rows int) []decoder.SubstrateNodes {
+	return []decoder.SubstrateNodes{Grid(neat.Input, cols, rows), Grid(neat.Output, cols, rows)}
+}
+
+// Stack returns copies of the layers with a coordinate appended to each position which places
+// every layer on its own plane, evenly spaced from -1 for the first layer to 1 for the last.
+// Stacking 2-D layers produces a 3-D substrate.
+func Stack(layers ...decoder.SubstrateNodes) []decoder.SubstrateNodes {
+	stacked := make([]decoder.SubstrateNodes, len(layers))
+	zs := spread(len(layers))
+	for i, l := range layers {
+		stacked[i] = make(decoder.SubstrateNodes, len(l))
+		for j, n := range l {
+			n.Position = append(append(make([]float64, 0, len(n.Position)+1), n.Position...), zs[i])
+			stacked[i][j] = n
+		}
+	}
+	return stacked
+}
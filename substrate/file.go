@@ -0,0 +1,176 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package substrate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/decoder"
+)
+
+// Shapes of the layers in a substrate file
+const (
+	GridShape   = "grid"   // Cols by Rows nodes. See Grid.
+	LineShape   = "line"   // Count nodes along the x axis. See Line.
+	CircleShape = "circle" // Count nodes around a circle of the Radius. See Circle.
+	PointsShape = "points" // A node at each of the Positions
+)
+
+// File describes a substrate in JSON as its layers, from the inputs to the outputs. For example,
+// the boxes experiment's state-space sandwich is
+//
+//	{
+//	  "Layers": [
+//	    {"Shape": "grid", "Neuron": "input", "Cols": 11, "Rows": 11},
+//	    {"Shape": "grid", "Neuron": "output", "Cols": 11, "Rows": 11}
+//	  ]
+//	}
+//
+// and a 3-D substrate with a ring of hidden nodes and two tanh outputs is
+//
+//	{
+//	  "Stack": true,
+//	  "Layers": [
+//	    {"Shape": "line", "Neuron": "input", "Count": 5},
+//	    {"Shape": "circle", "Neuron": "hidden", "Count": 8, "Radius": 0.5},
+//	    {"Shape": "points", "Neuron": "output", "Positions": [[-1, 0], [1, 0]], "Activation": "tanh"}
+//	  ]
+//	}
+type File struct {
+	Stack  bool    // Place each layer on its own plane. See Stack.
+	Layers []Layer // Layers of the substrate, from the inputs to the outputs
+}
+
+// Layer describes one layer of a substrate file
+type Layer struct {
+	Shape      string      // grid, line, circle or points
+	Neuron     string      // Type of the nodes: input, hidden or output
+	Cols, Rows int         // Size of a grid
+	Count      int         // Number of nodes in a line or circle
+	Radius     float64     // Radius of a circle. If 0, 1
+	Positions  [][]float64 // Positions of the points
+	Activation string      // Name of the nodes' activation. If empty, the decoder chooses.
+}
+
+// Returns the substrate layers described by the file
+func (f File) Build() ([]decoder.SubstrateNodes, error) {
+	layers := make([]decoder.SubstrateNodes, len(f.Layers))
+	for i, x := range f.Layers {
+		l, err := x.build()
+		if err != nil {
+			return nil, fmt.Errorf("substrate.File.Build - Layer %d: %v", i, err)
+		}
+		layers[i] = l
+	}
+	if f.Stack {
+		layers = Stack(layers...)
+	}
+	return layers, nil
+}
+
+// Returns the nodes of the layer
+func (x Layer) build() (l decoder.SubstrateNodes, err error) {
+
+	// Identify the nodes' type and activation
+	var t neat.NeuronType
+	for n := neat.Input; n <= neat.Output; n++ {
+		if strings.EqualFold(x.Neuron, n.String()) {
+			t = n
+		}
+	}
+	if t == 0 {
+		return nil, fmt.Errorf("Unknown neuron type %q", x.Neuron)
+	}
+	var a neat.ActivationType
+	if x.Activation != "" {
+		var ok bool
+		if a, ok = neat.ActivationByName(x.Activation); !ok {
+			return nil, fmt.Errorf("Unknown activation %q", x.Activation)
+		}
+	}
+
+	// Build the nodes in the shape
+	switch strings.ToLower(x.Shape) {
+	case GridShape:
+		if x.Cols < 1 || x.Rows < 1 {
+			return nil, fmt.Errorf("Invalid grid size %d x %d", x.Cols, x.Rows)
+		}
+		l = Grid(t, x.Cols, x.Rows)
+	case LineShape:
+		if x.Count < 1 {
+			return nil, fmt.Errorf("Invalid line count %d", x.Count)
+		}
+		l = Line(t, x.Count)
+	case CircleShape:
+		if x.Count < 1 {
+			return nil, fmt.Errorf("Invalid circle count %d", x.Count)
+		}
+		r := x.Radius
+		if r == 0 {
+			r = 1
+		}
+		l = Circle(t, x.Count, r)
+	case PointsShape:
+		if len(x.Positions) == 0 {
+			return nil, fmt.Errorf("No positions for the points")
+		}
+		l = make(decoder.SubstrateNodes, len(x.Positions))
+		for i, p := range x.Positions {
+			l[i] = decoder.SubstrateNode{Position: p, NeuronType: t}
+		}
+	default:
+		return nil, fmt.Errorf("Unknown shape %q", x.Shape)
+	}
+	for i := range l {
+		l[i].Activation = a
+	}
+	return
+}
+
+// Read decodes a substrate file from the reader and returns its layers
+func Read(r io.Reader) ([]decoder.SubstrateNodes, error) {
+	var f File
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, fmt.Errorf("substrate.Read - Could not decode substrate file: %v", err)
+	}
+	return f.Build()
+}
+
+// Load reads the substrate file at the path and returns its layers
+func Load(path string) ([]decoder.SubstrateNodes, error) {
+	r, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Read(r)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package substrate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+func TestLoadBoxesSubstrate(t *testing.T) {
+	layers, err := Load("../x/examples/boxes/boxes-substrate.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := Sandwich(11, 11); !reflect.DeepEqual(layers, want) {
+		t.Errorf("The boxes substrate file does not describe the 11 x 11 sandwich")
+	}
+}
+
+func TestReadStackedLayers(t *testing.T) {
+	layers, err := Read(strings.NewReader(`{
+	  "Stack": true,
+	  "Layers": [
+	    {"Shape": "line", "Neuron": "input", "Count": 5},
+	    {"Shape": "circle", "Neuron": "hidden", "Count": 8, "Radius": 0.5},
+	    {"Shape": "points", "Neuron": "output", "Positions": [[-1, 0], [1, 0]], "Activation": "tanh"}
+	  ]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 3 || len(layers[0]) != 5 || len(layers[1]) != 8 || len(layers[2]) != 2 {
+		t.Fatalf("Unexpected layer sizes")
+	}
+	for i, l := range layers {
+		for _, n := range l {
+			if len(n.Position) != 3 || n.Position[2] != float64(i-1) {
+				t.Errorf("Node %v of layer %d is not on the layer's plane", n.Position, i)
+			}
+		}
+	}
+	if n := layers[2][1]; n.NeuronType != neat.Output || n.Activation != neat.Tanh || n.Position[0] != 1 {
+		t.Errorf("Unexpected output node %+v", n)
+	}
+	if n := layers[1][0]; n.NeuronType != neat.Hidden || n.Activation != 0 || n.Position[0] != 0.5 {
+		t.Errorf("Unexpected hidden node %+v", n)
+	}
+}
+
+func TestReadRejectsInvalidLayers(t *testing.T) {
+	for _, s := range []string{
+		`{"Layers": [{"Shape": "grid", "Neuron": "input", "Cols": 0, "Rows": 3}]}`,
+		`{"Layers": [{"Shape": "line", "Neuron": "sensor", "Count": 3}]}`,
+		`{"Layers": [{"Shape": "square", "Neuron": "input", "Count": 3}]}`,
+		`{"Layers": [{"Shape": "points", "Neuron": "output"}]}`,
+		`{"Layers": [{"Shape": "line", "Neuron": "output", "Count": 3, "Activation": "unknown"}]}`,
+		`{"Layers": [`,
+	} {
+		if _, err := Read(strings.NewReader(s)); err == nil {
+			t.Errorf("Expected an error reading %s", s)
+		}
+	}
+}
@@ -57,6 +57,7 @@ type Result interface {
 type Results []Result
 
 type Species struct {
+	ID          int // ID of the species, which is the ID of the genome which founded it
 	Age         int // Age in terms of generations
 	Stagnation  int // Number of generations since an improvement
 	Improvement float64
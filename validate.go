@@ -0,0 +1,255 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package neat
+
+import (
+	"fmt"
+	"sort"
+
+	. "github.com/rqme/errors"
+)
+
+// Checks the genome's structure, returning all the problems found. A valid genome has at least one
+// input and one output node, nodes and connections stored under their own innovation numbers, no two
+// nodes at the same position, no two connections between the same nodes and no connection referring
+// to a missing node or leading into an input or bias node.
+func (g Genome) Validate() error {
+	return g.validate(false)
+}
+
+// Checks the genome's structure as Validate does and also ensures that the enabled connections do not
+// form a cycle or lead back to a node with a smaller Y, as required by feed-forward networks
+func (g Genome) ValidateFeedForward() error {
+	return g.validate(true)
+}
+
+func (g Genome) validate(feedForward bool) error {
+	errs := new(Errors)
+
+	// Check the nodes
+	var icnt, ocnt int
+	nkeys := make(map[InnoKey]int, len(g.Nodes))
+	for _, k := range nodeKeys(g.Nodes) {
+		n := g.Nodes[k]
+		if k != n.Innovation {
+			errs.Add(fmt.Errorf("neat.Genome.Validate - Node %d is stored under innovation %d", n.Innovation, k))
+		}
+		if other, ok := nkeys[n.Key()]; ok {
+			errs.Add(fmt.Errorf("neat.Genome.Validate - Nodes %d and %d share the position %v", other, k, n.Key()))
+		} else {
+			nkeys[n.Key()] = k
+		}
+		switch n.NeuronType {
+		case Input:
+			icnt += 1
+		case Output:
+			ocnt += 1
+		}
+	}
+	if icnt == 0 {
+		errs.Add(fmt.Errorf("neat.Genome.Validate - Genome %d has no input nodes", g.ID))
+	}
+	if ocnt == 0 {
+		errs.Add(fmt.Errorf("neat.Genome.Validate - Genome %d has no output nodes", g.ID))
+	}
+
+	// Check the connections
+	ckeys := make(map[InnoKey]int, len(g.Conns))
+	for _, k := range connKeys(g.Conns) {
+		c := g.Conns[k]
+		if k != c.Innovation {
+			errs.Add(fmt.Errorf("neat.Genome.Validate - Connection %d is stored under innovation %d", c.Innovation, k))
+		}
+		if other, ok := ckeys[c.Key()]; ok {
+			errs.Add(fmt.Errorf("neat.Genome.Validate - Connections %d and %d both connect %d to %d", other, k, c.Source, c.Target))
+		} else {
+			ckeys[c.Key()] = k
+		}
+		if _, ok := g.Nodes[c.Source]; !ok {
+			errs.Add(fmt.Errorf("neat.Genome.Validate - Connection %d refers to missing source node %d", k, c.Source))
+		}
+		if n, ok := g.Nodes[c.Target]; !ok {
+			errs.Add(fmt.Errorf("neat.Genome.Validate - Connection %d refers to missing target node %d", k, c.Target))
+		} else if n.NeuronType == Input || n.NeuronType == Bias {
+			errs.Add(fmt.Errorf("neat.Genome.Validate - Connection %d leads into %v node %d", k, n.NeuronType, c.Target))
+		}
+	}
+
+	// Check for backward connections and cycles
+	if feedForward {
+		for _, k := range connKeys(g.Conns) {
+			if c := g.Conns[k]; c.Enabled && backward(g, c) {
+				errs.Add(fmt.Errorf("neat.Genome.Validate - Connection %d leads back from node %d to node %d", k, c.Source, c.Target))
+			}
+		}
+		if k, ok := findCycle(g); ok {
+			errs.Add(fmt.Errorf("neat.Genome.Validate - Connection %d is part of a cycle", k))
+		}
+	}
+	return errs.Err()
+}
+
+// Repairs what it can of the genome's structure and then validates it. Nodes and connections are
+// moved under their own innovation numbers, duplicate nodes are merged into the one with the lowest
+// innovation number, connections referring to missing nodes or leading into input or bias nodes are
+// removed and duplicate connections are merged, keeping the lowest innovation number. If feed-forward
+// is required, enabled connections which lead back to a node with a smaller Y or which close a cycle
+// are disabled, considering the connections in order of innovation. Missing inputs or outputs cannot be repaired and are reported by the validation.
+func (g *Genome) Repair(feedForward bool) error {
+
+	// Store the nodes under their innovation numbers and merge those at the same position
+	nodes := make(map[int]Node, len(g.Nodes))
+	nkeys := make(map[InnoKey]int, len(g.Nodes))
+	merged := make(map[int]int) // dropped node -> kept node
+	for _, k := range nodeKeys(g.Nodes) {
+		n := g.Nodes[k]
+		if kept, ok := nkeys[n.Key()]; ok {
+			merged[n.Innovation] = kept
+			continue
+		}
+		if _, ok := nodes[n.Innovation]; ok {
+			continue // Another node was already stored under this innovation
+		}
+		nodes[n.Innovation] = n
+		nkeys[n.Key()] = n.Innovation
+	}
+	g.Nodes = nodes
+
+	// Store the connections under their innovation numbers, redirect those attached to merged nodes
+	// and remove those which are invalid or duplicated
+	conns := make(map[int]Connection, len(g.Conns))
+	ckeys := make(map[InnoKey]int, len(g.Conns))
+	for _, k := range connKeys(g.Conns) {
+		c := g.Conns[k]
+		if kept, ok := merged[c.Source]; ok {
+			c.Source = kept
+		}
+		if kept, ok := merged[c.Target]; ok {
+			c.Target = kept
+		}
+		if _, ok := g.Nodes[c.Source]; !ok {
+			continue
+		}
+		if n, ok := g.Nodes[c.Target]; !ok || n.NeuronType == Input || n.NeuronType == Bias {
+			continue
+		}
+		if kept, ok := ckeys[c.Key()]; ok {
+			if c.Enabled {
+				x := conns[kept]
+				x.Enabled = true
+				conns[kept] = x
+			}
+			continue
+		}
+		if _, ok := conns[c.Innovation]; ok {
+			continue
+		}
+		conns[c.Innovation] = c
+		ckeys[c.Key()] = c.Innovation
+	}
+	g.Conns = conns
+
+	// Disable backward connections and break any cycles
+	if feedForward {
+		adj := make(map[int][]int, len(g.Nodes))
+		for _, k := range connKeys(g.Conns) {
+			c := g.Conns[k]
+			if !c.Enabled {
+				continue
+			}
+			if backward(*g, c) || c.Source == c.Target || reaches(adj, c.Target, c.Source) {
+				c.Enabled = false
+				g.Conns[k] = c
+				continue
+			}
+			adj[c.Source] = append(adj[c.Source], c.Target)
+		}
+	}
+	return g.validate(feedForward)
+}
+
+// Returns true if the connection leads to a node with a smaller Y than its source
+func backward(g Genome, c Connection) bool {
+	src, ok1 := g.Nodes[c.Source]
+	tgt, ok2 := g.Nodes[c.Target]
+	return ok1 && ok2 && tgt.Y < src.Y
+}
+
+// Returns true if there is a path from one node to another
+func reaches(adj map[int][]int, from, to int) bool {
+	seen := make(map[int]bool, len(adj))
+	stack := []int{from}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if n == to {
+			return true
+		}
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		stack = append(stack, adj[n]...)
+	}
+	return false
+}
+
+// Returns a connection which is part of a cycle formed by the enabled connections, if any
+func findCycle(g Genome) (int, bool) {
+	adj := make(map[int][]int, len(g.Nodes))
+	for _, k := range connKeys(g.Conns) {
+		if c := g.Conns[k]; c.Enabled {
+			adj[c.Source] = append(adj[c.Source], c.Target)
+		}
+	}
+	for _, k := range connKeys(g.Conns) {
+		if c := g.Conns[k]; c.Enabled && reaches(adj, c.Target, c.Source) {
+			return k, true
+		}
+	}
+	return 0, false
+}
+
+// Returns the keys of the node map in ascending order
+func nodeKeys(m map[int]Node) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Returns the keys of the connection map in ascending order
+func connKeys(m map[int]Connection) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+package neat
+
+import (
+	"strings"
+	"testing"
+)
+
+// Returns a valid genome with an input at Y 0, a hidden node at Y 0.5 and an output at Y 1,
+// connected in a chain
+func validGenome() Genome {
+	return Genome{
+		ID: 1,
+		Nodes: Nodes{
+			1: {Innovation: 1, NeuronType: Input, X: 0, Y: 0},
+			2: {Innovation: 2, NeuronType: Output, X: 0, Y: 1},
+			3: {Innovation: 3, NeuronType: Hidden, X: 0, Y: 0.5},
+		},
+		Conns: Connections{
+			4: {Innovation: 4, Source: 1, Target: 3, Enabled: true},
+			5: {Innovation: 5, Source: 3, Target: 2, Enabled: true},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var cases = []struct {
+		desc        string
+		change      func(g *Genome)
+		feedForward bool
+		expected    string // Part of the error expected. If empty, the genome is valid.
+	}{
+		{"valid", func(g *Genome) {}, true, ""},
+		{"no inputs", func(g *Genome) { delete(g.Nodes, 1); delete(g.Conns, 4) }, false, "has no input nodes"},
+		{"no outputs", func(g *Genome) { delete(g.Nodes, 2); delete(g.Conns, 5) }, false, "has no output nodes"},
+		{"node innovation", func(g *Genome) { g.Nodes[7] = Node{Innovation: 8, NeuronType: Hidden, Y: 0.7} }, false, "Node 8 is stored under innovation 7"},
+		{"connection innovation", func(g *Genome) { g.Conns[7] = Connection{Innovation: 8, Source: 1, Target: 2} }, false, "Connection 8 is stored under innovation 7"},
+		{"duplicate nodes", func(g *Genome) { g.Nodes[7] = Node{Innovation: 7, NeuronType: Hidden, Y: 0.5} }, false, "Nodes 3 and 7 share the position"},
+		{"duplicate connections", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 1, Target: 3} }, false, "Connections 4 and 7 both connect 1 to 3"},
+		{"dangling source", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 9, Target: 2} }, false, "Connection 7 refers to missing source node 9"},
+		{"dangling target", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 1, Target: 9} }, false, "Connection 7 refers to missing target node 9"},
+		{"into input", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 3, Target: 1} }, false, "Connection 7 leads into Input node 1"},
+		{"cycle", func(g *Genome) {
+			g.Nodes[6] = Node{Innovation: 6, NeuronType: Hidden, X: 1, Y: 0.5}
+			g.Conns[7] = Connection{Innovation: 7, Source: 3, Target: 6, Enabled: true}
+			g.Conns[8] = Connection{Innovation: 8, Source: 6, Target: 3, Enabled: true}
+		}, true, "is part of a cycle"},
+		{"recurrent allowed", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 2, Target: 3, Enabled: true} }, false, ""},
+		{"backward", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 2, Target: 3, Enabled: true} }, true, "Connection 7 leads back from node 2 to node 3"},
+		{"backward disabled", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 2, Target: 3} }, true, ""},
+	}
+	for _, c := range cases {
+		g := validGenome()
+		c.change(&g)
+		var err error
+		if c.feedForward {
+			err = g.ValidateFeedForward()
+		} else {
+			err = g.Validate()
+		}
+		switch {
+		case c.expected == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", c.desc, err)
+		case c.expected != "" && (err == nil || !strings.Contains(err.Error(), c.expected)):
+			t.Errorf("%s: expected an error containing %q, got %v", c.desc, c.expected, err)
+		}
+	}
+}
+
+func TestRepair(t *testing.T) {
+	var cases = []struct {
+		desc        string
+		change      func(g *Genome)
+		feedForward bool
+		check       func(g Genome) bool
+	}{
+		{"node innovation", func(g *Genome) { g.Nodes[7] = Node{Innovation: 8, NeuronType: Hidden, Y: 0.7} },
+			false, func(g Genome) bool { _, ok := g.Nodes[8]; return ok && len(g.Nodes) == 4 }},
+		{"connection innovation", func(g *Genome) { g.Conns[7] = Connection{Innovation: 8, Source: 1, Target: 2} },
+			false, func(g Genome) bool { _, ok := g.Conns[8]; return ok && len(g.Conns) == 3 }},
+		{"duplicate nodes", func(g *Genome) {
+			g.Nodes[7] = Node{Innovation: 7, NeuronType: Hidden, Y: 0.5}
+			g.Conns[8] = Connection{Innovation: 8, Source: 7, Target: 2, Enabled: true}
+		}, false, func(g Genome) bool { _, ok := g.Nodes[7]; return !ok && len(g.Conns) == 2 && g.Conns[5].Enabled }},
+		{"duplicate connections", func(g *Genome) {
+			c := g.Conns[4]
+			c.Enabled = false
+			g.Conns[4] = c
+			g.Conns[7] = Connection{Innovation: 7, Source: 1, Target: 3, Enabled: true}
+		}, false, func(g Genome) bool { return len(g.Conns) == 2 && g.Conns[4].Enabled }},
+		{"dangling", func(g *Genome) {
+			g.Conns[7] = Connection{Innovation: 7, Source: 9, Target: 2}
+			g.Conns[8] = Connection{Innovation: 8, Source: 1, Target: 9}
+		}, false, func(g Genome) bool { return len(g.Conns) == 2 }},
+		{"into input", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 3, Target: 1, Enabled: true} },
+			false, func(g Genome) bool { return len(g.Conns) == 2 }},
+		{"cycle", func(g *Genome) {
+			g.Nodes[6] = Node{Innovation: 6, NeuronType: Hidden, X: 1, Y: 0.5}
+			g.Conns[7] = Connection{Innovation: 7, Source: 3, Target: 6, Enabled: true}
+			g.Conns[8] = Connection{Innovation: 8, Source: 6, Target: 3, Enabled: true}
+		}, true, func(g Genome) bool { return g.Conns[7].Enabled && !g.Conns[8].Enabled }},
+		{"backward", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 2, Target: 3, Enabled: true} },
+			true, func(g Genome) bool { return len(g.Conns) == 3 && !g.Conns[7].Enabled }},
+		{"recurrent kept", func(g *Genome) { g.Conns[7] = Connection{Innovation: 7, Source: 2, Target: 3, Enabled: true} },
+			false, func(g Genome) bool { return g.Conns[7].Enabled }},
+	}
+	for _, c := range cases {
+		g := validGenome()
+		c.change(&g)
+		if err := g.Repair(c.feedForward); err != nil {
+			t.Errorf("%s: unexpected error %v", c.desc, err)
+		} else if !c.check(g) {
+			t.Errorf("%s: unexpected repair %v", c.desc, g)
+		}
+	}
+}
+
+func TestRepairCannotAddInputs(t *testing.T) {
+	g := validGenome()
+	delete(g.Nodes, 1)
+	if err := g.Repair(true); err == nil || !strings.Contains(err.Error(), "has no input nodes") {
+		t.Errorf("Expected the missing input to be reported, got %v", err)
+	}
+	if len(g.Conns) != 1 {
+		t.Errorf("Expected the connection from the missing input to be removed")
+	}
+}
@@ -0,0 +1,105 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package visualizer
+
+import (
+	"github.com/rqme/neat"
+)
+
+// Visualizes the population with each of the visualizers in turn, such as the web pages and the
+// statistics file. The context, trial and the experiment's notifications are passed along to those
+// visualizers which use them.
+type Multi []neat.Visualizer
+
+func (v Multi) SetContext(x neat.Context) error {
+	for _, h := range v {
+		if ch, ok := h.(neat.Contextable); ok {
+			if err := ch.SetContext(x); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (v Multi) SetTrial(t int) error {
+	for _, h := range v {
+		if th, ok := h.(neat.Trialable); ok {
+			if err := th.SetTrial(t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Visualizes the population with each visualizer, stopping at the first error
+func (v Multi) Visualize(pop neat.Population) error {
+	for _, h := range v {
+		if err := h.Visualize(pop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls the function for each visualizer which is a listener
+func (v Multi) notify(f func(neat.Listener)) {
+	for _, h := range v {
+		if lh, ok := h.(neat.Listener); ok {
+			f(lh)
+		}
+	}
+}
+
+func (v Multi) GenerationStarted(e *neat.Experiment) {
+	v.notify(func(l neat.Listener) { l.GenerationStarted(e) })
+}
+
+func (v Multi) GenerationEnded(e *neat.Experiment) {
+	v.notify(func(l neat.Listener) { l.GenerationEnded(e) })
+}
+
+func (v Multi) SpeciesCreated(e *neat.Experiment, s neat.Species) {
+	v.notify(func(l neat.Listener) { l.SpeciesCreated(e, s) })
+}
+
+func (v Multi) SpeciesExtinct(e *neat.Experiment, s neat.Species) {
+	v.notify(func(l neat.Listener) { l.SpeciesExtinct(e, s) })
+}
+
+func (v Multi) BestImproved(e *neat.Experiment, g neat.Genome) {
+	v.notify(func(l neat.Listener) { l.BestImproved(e, g) })
+}
+
+func (v Multi) EvaluationFailed(e *neat.Experiment, id int, err error) {
+	v.notify(func(l neat.Listener) { l.EvaluationFailed(e, id, err) })
+}
+
+func (v Multi) Stopped(e *neat.Experiment) {
+	v.notify(func(l neat.Listener) { l.Stopped(e) })
+}
@@ -0,0 +1,241 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package visualizer
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/montanaflynn/stats"
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/decoder"
+)
+
+type StatsSettings interface {
+	StatsPath() string   // Path to output the statistics file. If empty, no statistics are written.
+	StatsFormat() string // Format of the statistics file, csv or jsonl. Default is csv.
+}
+
+// Statistics of a single generation
+type StatsRecord struct {
+	Generation     int
+	BestFitness    float64
+	MeanFitness    float64
+	MedianFitness  float64
+	StdDevFitness  float64
+	MeanComplexity float64
+	SpeciesCount   int
+	SpeciesSizes   []int
+	Threshold      float64 // Compatibility threshold, if the speciater has one
+	Phase          string  // Complexify or prune, if the mutator is phased
+	EvaluationTime float64 // Seconds spent evaluating since the last record
+	TrimmedNodes   float64 // Fraction of substrate nodes trimmed since the last record, if the decoder trims substrates
+	TrimmedConns   float64 // Fraction of substrate connections trimmed since the last record
+}
+
+var statsHeader = []string{"Generation", "BestFitness", "MeanFitness", "MedianFitness", "StdDevFitness",
+	"MeanComplexity", "SpeciesCount", "SpeciesSizes", "Threshold", "Phase", "EvaluationTime", "TrimmedNodes",
+	"TrimmedConns"}
+
+// Visualizes the population by appending one record of statistics per generation to a CSV or JSON
+// Lines file so that runs can be plotted and compared with other tools. The evaluation time is
+// collected by listening to the experiment.
+type Stats struct {
+	StatsSettings
+	neat.NullListener
+	ctx neat.Context
+
+	elapsed time.Duration
+	started time.Time
+	trimmed decoder.TrimStats // Decoder's trimming totals at the last record
+
+	useTrials bool
+	trialNum  int
+}
+
+func (v *Stats) SetContext(x neat.Context) error {
+	v.ctx = x
+	return nil
+}
+
+func (v *Stats) SetTrial(t int) error {
+	v.useTrials = true
+	v.trialNum = t
+	return nil
+}
+
+// Records the start of the evaluation
+func (v *Stats) GenerationStarted(e *neat.Experiment) {
+	v.started = time.Now()
+}
+
+// Records the time spent evaluating
+func (v *Stats) GenerationEnded(e *neat.Experiment) {
+	if !v.started.IsZero() {
+		v.elapsed += time.Since(v.started)
+		v.started = time.Time{}
+	}
+}
+
+func (v *Stats) format() (string, error) {
+	switch f := strings.ToLower(v.StatsFormat()); f {
+	case "", "csv":
+		return "csv", nil
+	case "jsonl":
+		return f, nil
+	default:
+		return "", fmt.Errorf("visualizer.Stats - Unknown statistics format %s", v.StatsFormat())
+	}
+}
+
+func (v *Stats) makePath(ext string) (string, error) {
+	p := v.StatsPath()
+	if v.useTrials {
+		p = path.Join(p, strconv.Itoa(v.trialNum))
+	}
+	if p != "" {
+		if err := os.MkdirAll(p, os.ModePerm); err != nil {
+			return "", fmt.Errorf("Could not create stats path %s: %v", p, err)
+		}
+	}
+	return path.Join(p, "stats."+ext), nil
+}
+
+// Appends the population's statistics to the file
+func (v *Stats) Visualize(pop neat.Population) error {
+	if v.StatsPath() == "" {
+		return nil
+	}
+	ext, err := v.format()
+	if err != nil {
+		return err
+	}
+	name, err := v.makePath(ext)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0666)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := v.record(pop)
+	v.elapsed = 0
+	if ext == "jsonl" {
+		return json.NewEncoder(f).Encode(r)
+	}
+
+	// Write the header to a new file
+	w := csv.NewWriter(f)
+	if fi, err := f.Stat(); err == nil && fi.Size() == 0 {
+		w.Write(statsHeader)
+	}
+	sizes := make([]string, len(r.SpeciesSizes))
+	for i, n := range r.SpeciesSizes {
+		sizes[i] = strconv.Itoa(n)
+	}
+	w.Write([]string{
+		strconv.Itoa(r.Generation),
+		strconv.FormatFloat(r.BestFitness, 'g', -1, 64),
+		strconv.FormatFloat(r.MeanFitness, 'g', -1, 64),
+		strconv.FormatFloat(r.MedianFitness, 'g', -1, 64),
+		strconv.FormatFloat(r.StdDevFitness, 'g', -1, 64),
+		strconv.FormatFloat(r.MeanComplexity, 'g', -1, 64),
+		strconv.Itoa(r.SpeciesCount),
+		strings.Join(sizes, ";"),
+		strconv.FormatFloat(r.Threshold, 'g', -1, 64),
+		r.Phase,
+		strconv.FormatFloat(r.EvaluationTime, 'g', -1, 64),
+		strconv.FormatFloat(r.TrimmedNodes, 'g', -1, 64),
+		strconv.FormatFloat(r.TrimmedConns, 'g', -1, 64),
+	})
+	w.Flush()
+	return w.Error()
+}
+
+// Builds the statistics record for the population
+func (v *Stats) record(pop neat.Population) StatsRecord {
+	fit := make([]float64, len(pop.Genomes))
+	cpx := make([]float64, len(pop.Genomes))
+	for i, g := range pop.Genomes {
+		fit[i] = g.Fitness
+		cpx[i] = float64(g.Complexity())
+	}
+	sizes := make([]int, len(pop.Species))
+	for _, g := range pop.Genomes {
+		if g.SpeciesIdx < len(sizes) {
+			sizes[g.SpeciesIdx] += 1
+		}
+	}
+
+	r := StatsRecord{
+		Generation:     pop.Generation,
+		SpeciesCount:   len(pop.Species),
+		SpeciesSizes:   sizes,
+		EvaluationTime: v.elapsed.Seconds(),
+	}
+	if len(fit) > 0 {
+		r.BestFitness, _ = stats.Max(fit)
+		r.MeanFitness, _ = stats.Mean(fit)
+		r.MedianFitness, _ = stats.Median(fit)
+		r.StdDevFitness, _ = stats.StdDevP(fit)
+		r.MeanComplexity, _ = stats.Mean(cpx)
+	}
+
+	// Include the state of the helpers, if available
+	if v.ctx != nil {
+		if th, ok := v.ctx.Speciater().(interface {
+			CompatibilityThreshold() float64
+		}); ok {
+			r.Threshold = th.CompatibilityThreshold()
+		}
+		if ph, ok := v.ctx.Mutator().(interface {
+			IsPruning() bool
+		}); ok {
+			if ph.IsPruning() {
+				r.Phase = "prune"
+			} else {
+				r.Phase = "complexify"
+			}
+		}
+		if th, ok := v.ctx.Decoder().(interface {
+			TrimStats() decoder.TrimStats
+		}); ok {
+			ts := th.TrimStats()
+			r.TrimmedNodes, r.TrimmedConns = ts.Sub(v.trimmed).Fractions()
+			v.trimmed = ts
+		}
+	}
+	return r
+}
@@ -0,0 +1,136 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package visualizer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/rqme/neat"
+)
+
+type statsSettings struct{ path, format string }
+
+func (s statsSettings) StatsPath() string   { return s.path }
+func (s statsSettings) StatsFormat() string { return s.format }
+
+// Returns a population of the generation with fitnesses 1, 2, 3 and 6 in two species of sizes 3
+// and 1. One genome has a node and a connection.
+func statsPopulation(gen int) neat.Population {
+	return neat.Population{
+		Generation: gen,
+		Species:    []neat.Species{{ID: 1}, {ID: 4}},
+		Genomes: neat.Genomes{
+			{ID: 1, Fitness: 1, SpeciesIdx: 0},
+			{ID: 2, Fitness: 2, SpeciesIdx: 0},
+			{ID: 3, Fitness: 3, SpeciesIdx: 0},
+			{ID: 4, Fitness: 6, SpeciesIdx: 1,
+				Nodes: neat.Nodes{1: {Innovation: 1}}, Conns: neat.Connections{2: {Innovation: 2}}},
+		},
+	}
+}
+
+func TestStatsWritesCSV(t *testing.T) {
+	dir := t.TempDir()
+	v := &Stats{StatsSettings: statsSettings{path: dir}}
+	for gen := 1; gen <= 2; gen++ {
+		if err := v.Visualize(statsPopulation(gen)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(path.Join(dir, "stats.csv"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	rows, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d rows", len(rows))
+	}
+	if !reflect.DeepEqual(rows[0], statsHeader) {
+		t.Errorf("Unexpected header %v", rows[0])
+	}
+	for i, row := range rows[1:] {
+		gen := []string{"1", "2"}[i]
+		expected := []string{gen, "6", "3", "2.5", "1.8708286933869707", "0.5", "2", "3;1", "0", "", "0", "0", "0"}
+		if !reflect.DeepEqual(row, expected) {
+			t.Errorf("Row %d: expected %v, got %v", i+1, expected, row)
+		}
+	}
+}
+
+func TestStatsWritesJSONLines(t *testing.T) {
+	dir := t.TempDir()
+	v := &Stats{StatsSettings: statsSettings{path: dir, format: "jsonl"}}
+	v.SetTrial(3)
+	for gen := 1; gen <= 2; gen++ {
+		if err := v.Visualize(statsPopulation(gen)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each trial has its own file
+	f, err := os.Open(path.Join(dir, "3", "stats.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var rs []StatsRecord
+	for s := bufio.NewScanner(f); s.Scan(); {
+		var r StatsRecord
+		if err := json.Unmarshal(s.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		rs = append(rs, r)
+	}
+	if len(rs) != 2 {
+		t.Fatalf("Expected 2 records, got %d", len(rs))
+	}
+	for i, r := range rs {
+		expected := StatsRecord{Generation: i + 1, BestFitness: 6, MeanFitness: 3, MedianFitness: 2.5,
+			StdDevFitness: 1.8708286933869707, MeanComplexity: 0.5, SpeciesCount: 2, SpeciesSizes: []int{3, 1}}
+		if !reflect.DeepEqual(r, expected) {
+			t.Errorf("Record %d: expected %+v, got %+v", i, expected, r)
+		}
+	}
+}
+
+func TestStatsRejectsUnknownFormat(t *testing.T) {
+	v := &Stats{StatsSettings: statsSettings{path: t.TempDir(), format: "xml"}}
+	if err := v.Visualize(statsPopulation(1)); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -49,6 +49,7 @@ import (
 
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path"
 	"strings"
@@ -478,9 +479,15 @@ func visualizeBest(v *Web) error {
 	if !ok {
 		return errors.New("Web visualizer only knows the decoder package's phenome")
 	}
-	net, ok = p.Network.(*network.Classic)
-	if !ok {
-		return errors.New("Web visualizer only knows the Clasic network")
+	switch n := p.Network.(type) {
+	case *network.Classic:
+		net = n
+	case *network.Compiled:
+		net = &n.Classic
+	case *network.Recurrent:
+		net = &n.Classic
+	default:
+		return errors.New("Web visualizer only knows the Clasic and Recurrent networks")
 	}
 
 	// Create the image
@@ -503,6 +510,7 @@ func visualizeBest(v *Web) error {
 	img.DefEnd()
 
 	// Draw neurons
+	xs, ys := layout(net.Neurons)
 	for i, neuron := range net.Neurons {
 		var node_color, font_color string
 		switch neuron.NeuronType {
@@ -519,20 +527,19 @@ func visualizeBest(v *Web) error {
 			node_color = "thistle"
 			font_color = "black"
 		}
-		cx := int(neuron.X*w) + 15
-		cy := int((1.0-neuron.Y)*h) + 15
+		cx := int(xs[i]*w) + 15
+		cy := int((1.0-ys[i])*h) + 15
 		img.Circle(cx, cy, 10, fmt.Sprintf(`fill="%s" stroke="black" stroke-width="1"`, node_color))
 		img.Text(cx-3, cy+3, fmt.Sprintf(`%d`, i), fmt.Sprintf(`font-size="5pt" font-color=%s`, font_color))
 	}
 
 	// Draw synapses
 	for _, synapse := range net.Synapses {
-		src := net.Neurons[synapse.Source]
-		tgt := net.Neurons[synapse.Target]
-		fromX := int(src.X*w) + 15
-		fromY := int((1.0-src.Y)*h) + 15
-		toX := int(tgt.X*w) + 15
-		toY := int((1.0-tgt.Y)*h) + 15
+		src, tgt := synapse.Source, synapse.Target
+		fromX := int(xs[src]*w) + 15
+		fromY := int((1.0-ys[src])*h) + 15
+		toX := int(xs[tgt]*w) + 15
+		toY := int((1.0-ys[tgt])*h) + 15
 
 		var line_color, triangle_color string
 		if synapse.Weight >= 0 {
@@ -562,3 +569,45 @@ func visualizeBest(v *Web) error {
 	f.WriteString(fmt.Sprintf("<P>%s</P>", strings.Replace((*net).String(), "\n", "<BR/>", -1)))
 	return nil
 }
+
+// Returns where to draw each neuron, scaled into [0, 1]. Neurons are drawn at their 2D hints unless
+// they have three or more coordinates, as in a 3-D substrate, in which case their positions are
+// drawn in oblique projection with the third axis receding up and to the right.
+func layout(neurons network.Neurons) (xs, ys []float64) {
+	xs = make([]float64, len(neurons))
+	ys = make([]float64, len(neurons))
+	deep := false
+	for _, n := range neurons {
+		deep = deep || len(n.Position) > 2
+	}
+	if !deep {
+		for i, n := range neurons {
+			xs[i], ys[i] = n.X, n.Y
+		}
+		return
+	}
+
+	const depth = 0.5 // Foreshortening of the third axis
+	for i, n := range neurons {
+		var p [3]float64
+		copy(p[:], n.Position)
+		xs[i] = p[0] + depth*p[2]*math.Cos(math.Pi/6)
+		ys[i] = p[1] + depth*p[2]*math.Sin(math.Pi/6)
+	}
+
+	// Scale both axes together so that the projection keeps its proportions
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for i := range xs {
+		lo = math.Min(lo, math.Min(xs[i], ys[i]))
+		hi = math.Max(hi, math.Max(xs[i], ys[i]))
+	}
+	for i := range xs {
+		if hi > lo {
+			xs[i] = (xs[i] - lo) / (hi - lo)
+			ys[i] = (ys[i] - lo) / (hi - lo)
+		} else {
+			xs[i], ys[i] = 0.5, 0.5
+		}
+	}
+	return
+}
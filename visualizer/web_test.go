@@ -0,0 +1,119 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package visualizer
+
+import (
+	"math"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/decoder"
+	"github.com/rqme/neat/network"
+)
+
+type webSettings struct{ path string }
+
+func (s webSettings) ExperimentName() string { return "test" }
+func (s webSettings) WebPath() string        { return s.path }
+
+// Decodes every genome into the same network
+type netDecoder struct{ net neat.Network }
+
+func (d netDecoder) Decode(g neat.Genome) (neat.Phenome, error) {
+	return decoder.Phenome{Genome: g, Network: d.net}, nil
+}
+
+// Context holding only the decoder and the state
+type decoderContext struct {
+	neat.Context
+	decoder neat.Decoder
+	state   map[string]interface{}
+}
+
+func (c decoderContext) Decoder() neat.Decoder         { return c.decoder }
+func (c decoderContext) State() map[string]interface{} { return c.state }
+
+// Returns a network of a 3-D substrate with two inputs, a hidden neuron and an output, one behind
+// the other
+func deepNetwork(t *testing.T) *network.Compiled {
+	net, err := network.New(network.Neurons{
+		{NeuronType: neat.Input, ActivationType: neat.Direct, Position: []float64{-1, -1, -1}},
+		{NeuronType: neat.Input, ActivationType: neat.Direct, Position: []float64{1, -1, -1}},
+		{NeuronType: neat.Hidden, ActivationType: neat.Sigmoid, Position: []float64{0, 0, 0}},
+		{NeuronType: neat.Output, ActivationType: neat.Sigmoid, Position: []float64{0, 0, 1}},
+	}, network.Synapses{
+		{Source: 0, Target: 2, Weight: 1},
+		{Source: 1, Target: 2, Weight: -1},
+		{Source: 2, Target: 3, Weight: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := network.Compile(net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestLayoutProjectsDepth(t *testing.T) {
+	xs, ys := layout(deepNetwork(t).Neurons)
+	for i := range xs {
+		if xs[i] < 0 || xs[i] > 1 || ys[i] < 0 || ys[i] > 1 {
+			t.Errorf("Neuron %d drawn outside the image at (%f, %f)", i, xs[i], ys[i])
+		}
+	}
+
+	// The hidden and output neurons differ only in depth, which recedes up and to the right
+	if xs[3] <= xs[2] || ys[3] <= ys[2] {
+		t.Errorf("Expected the deeper neuron drawn up and right of (%f, %f), got (%f, %f)", xs[2], ys[2], xs[3], ys[3])
+	}
+	if a := math.Atan2(ys[3]-ys[2], xs[3]-xs[2]); math.Abs(a-math.Pi/6) > 1e-9 {
+		t.Errorf("Expected depth drawn at an angle of %f, got %f", math.Pi/6, a)
+	}
+
+	// Without a third coordinate the hints are drawn
+	ns := network.Neurons{{X: 0.25, Y: 0.75, Position: []float64{5, 6}}, {X: 1, Y: 0}}
+	if xs, ys = layout(ns); xs[0] != 0.25 || ys[0] != 0.75 || xs[1] != 1 || ys[1] != 0 {
+		t.Errorf("Expected the hints to be drawn, got %v and %v", xs, ys)
+	}
+}
+
+func TestVisualizeBestDrawsDeepNetworks(t *testing.T) {
+	dir := t.TempDir()
+	v := &Web{WebSettings: webSettings{path: dir}}
+	v.SetContext(decoderContext{decoder: netDecoder{deepNetwork(t)}, state: make(map[string]interface{})})
+	v.best = []neat.Genome{{ID: 7}}
+	if err := visualizeBest(v); err != nil {
+		t.Fatalf("Could not draw a 3-D network: %v", err)
+	}
+	if _, err := os.Stat(path.Join(dir, "network.svg")); err != nil {
+		t.Errorf("Expected the network's image: %v", err)
+	}
+}
@@ -1,8 +1,8 @@
 package main
 
 import (
-	"github.com/rqme/neat"
 	"github.com/rqme/neat/decoder"
+	"github.com/rqme/neat/substrate"
 )
 
 type SettingsWithLayers struct {
@@ -10,7 +10,14 @@ type SettingsWithLayers struct {
 	layers []decoder.SubstrateNodes
 }
 
-func (h SettingsWithLayers) SubstrateLayers() []decoder.SubstrateNodes { return h.layers }
+// Returns the layers from the settings, such as those read from boxes-substrate.json, or
+// the sandwich for the resolution if the settings have none
+func (h SettingsWithLayers) SubstrateLayers() []decoder.SubstrateNodes {
+	if l := h.ESHyperNEATSettings.SubstrateLayers(); len(l) > 0 {
+		return l
+	}
+	return h.layers
+}
 
 // The solution substrate is configured as a state-space sandwich that includes two sheets: (1) The
 // visual field is a two-dimensional array of sensors that are either on or off (i.e. black or
@@ -20,17 +27,6 @@ func newSettingsWithLayers(cfg decoder.ESHyperNEATSettings) (hns SettingsWithLay
 	hns.ESHyperNEATSettings = cfg
 
 	// Create the substrate layers
-	r := *Resolution
-	var ilayer decoder.SubstrateNodes = make([]decoder.SubstrateNode, 0, r*r)
-	var olayer decoder.SubstrateNodes = make([]decoder.SubstrateNode, 0, r*r)
-	for x := 0; x < r; x++ {
-		for y := 0; y < r; y++ {
-			px := float64(x)/float64(r-1)*2.0 - 1.0
-			py := float64(y)/float64(r-1)*2.0 - 1.0
-			ilayer = append(ilayer, decoder.SubstrateNode{Position: []float64{px, py}, NeuronType: neat.Input})
-			olayer = append(olayer, decoder.SubstrateNode{Position: []float64{px, py}, NeuronType: neat.Output})
-		}
-	}
-	hns.layers = []decoder.SubstrateNodes{ilayer, olayer}
+	hns.layers = substrate.Sandwich(*Resolution, *Resolution)
 	return
 }
@@ -154,7 +154,7 @@ func main() {
 		if *Novelty {
 			ctx = starter.NewContext(eval, func(ctx *starter.Context) {
 				ctx.SetGenerator(gen)
-				ctx.SetSearcher(&searcher.Novelty{NoveltySettings: ctx, Searcher: &searcher.Concurrent{}})
+				ctx.SetSearcher(&searcher.Novelty{NoveltySettings: ctx, Searcher: &searcher.Concurrent{ConcurrentSettings: ctx}})
 			})
 		} else {
 			ctx = starter.NewContext(eval, func(ctx *starter.Context) {
@@ -0,0 +1,362 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package inspect
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/archiver"
+	"github.com/rqme/neat/decoder"
+	"github.com/rqme/neat/searcher"
+	"github.com/rqme/neat/x/starter"
+)
+
+// Lists the snapshots in the archive and a summary of each species of the restored generation
+func list(fs *flag.FlagSet) func(a *Archive, out io.Writer) error {
+	return func(a *Archive, out io.Writer) error {
+		if len(a.Generations) > 0 {
+			fmt.Fprintf(out, "Snapshots of generations %v\n", a.Generations)
+		}
+		pop := a.Population
+		fmt.Fprintf(out, "Experiment %s at generation %d, iteration %d", a.Context.ExperimentName(), pop.Generation, a.Iteration)
+		if a.Stopped {
+			fmt.Fprintf(out, " (stopped)")
+		}
+		fmt.Fprintf(out, "\n%d genomes in %d species. Best genome %d with fitness %f\n\n", len(pop.Genomes), len(pop.Species), a.Best.ID, a.Best.Fitness)
+
+		fmt.Fprintf(out, "Species  Size   Age  Stag.       ID    Best ID  Best Fitness  Mean Fitness\n")
+		fmt.Fprintf(out, "------- ----- ----- ------ -------- ---------- ------------- -------------\n")
+		for i, s := range pop.Species {
+			var cnt, bid int
+			var sum, bf float64
+			for _, g := range pop.Genomes {
+				if g.SpeciesIdx != i {
+					continue
+				}
+				if cnt == 0 || g.Fitness > bf {
+					bid, bf = g.ID, g.Fitness
+				}
+				cnt += 1
+				sum += g.Fitness
+			}
+			mean := 0.0
+			if cnt > 0 {
+				mean = sum / float64(cnt)
+			}
+			fmt.Fprintf(out, "%7d %5d %5d %6d %8d %10d %13f %13f\n", i, cnt, s.Age, s.Stagnation, s.ID, bid, bf, mean)
+		}
+		return nil
+	}
+}
+
+// Prints the best genome, or the one identified by -id
+func best(fs *flag.FlagSet) func(a *Archive, out io.Writer) error {
+	id := fs.String("id", "best", "Genome to print: an ID in the population or \"best\"")
+	asJSON := fs.Bool("json", false, "Prints the genome as JSON")
+	return func(a *Archive, out io.Writer) error {
+		g, err := a.Genome(*id)
+		if err != nil {
+			return err
+		}
+		if *asJSON {
+			e := json.NewEncoder(out)
+			e.SetIndent("", "  ")
+			return e.Encode(g)
+		}
+		_, err = fmt.Fprintln(out, g)
+		return err
+	}
+}
+
+// Compares two genomes gene by gene, matching the genes by innovation
+func diff(fs *flag.FlagSet) func(a *Archive, out io.Writer) error {
+	ida := fs.String("a", "best", "First genome: an ID in the population or \"best\"")
+	idb := fs.String("b", "", "Second genome: an ID in the population or \"best\"")
+	return func(a *Archive, out io.Writer) error {
+		g1, err := a.Genome(*ida)
+		if err != nil {
+			return err
+		}
+		if *idb == "" {
+			return fmt.Errorf("inspect.diff - The second genome (-b) is required")
+		}
+		g2, err := a.Genome(*idb)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "--- genome %d fitness %f\n+++ genome %d fitness %f\n", g1.ID, g1.Fitness, g2.ID, g2.Fitness)
+
+		same := 0
+		fmt.Fprintf(out, "Nodes:\n")
+		for _, k := range innovations(g1.Nodes, g2.Nodes) {
+			n1, ok1 := g1.Nodes[k]
+			n2, ok2 := g2.Nodes[k]
+			switch {
+			case !ok2:
+				fmt.Fprintf(out, "- %s\n", n1)
+			case !ok1:
+				fmt.Fprintf(out, "+ %s\n", n2)
+			case n1 != n2:
+				fmt.Fprintf(out, "- %s\n+ %s\n", n1, n2)
+			default:
+				same += 1
+			}
+		}
+		fmt.Fprintf(out, "  %d nodes match\n", same)
+
+		same = 0
+		var wd float64
+		fmt.Fprintf(out, "Connections:\n")
+		for _, k := range innovations(g1.Conns, g2.Conns) {
+			c1, ok1 := g1.Conns[k]
+			c2, ok2 := g2.Conns[k]
+			switch {
+			case !ok2:
+				fmt.Fprintf(out, "- %s\n", c1)
+			case !ok1:
+				fmt.Fprintf(out, "+ %s\n", c2)
+			case c1 != c2:
+				fmt.Fprintf(out, "- %s\n+ %s\n", c1, c2)
+				if c1.Weight > c2.Weight {
+					wd += c1.Weight - c2.Weight
+				} else {
+					wd += c2.Weight - c1.Weight
+				}
+			default:
+				same += 1
+			}
+		}
+		fmt.Fprintf(out, "  %d connections match. Total weight difference %f\n", same, wd)
+		return nil
+	}
+}
+
+// Returns the sorted innovation numbers appearing in either map of genes
+func innovations(m1, m2 interface{}) []int {
+	set := make(map[int]bool)
+	switch x := m1.(type) {
+	case neat.Nodes:
+		for k := range x {
+			set[k] = true
+		}
+		for k := range m2.(neat.Nodes) {
+			set[k] = true
+		}
+	case neat.Connections:
+		for k := range x {
+			set[k] = true
+		}
+		for k := range m2.(neat.Connections) {
+			set[k] = true
+		}
+	}
+	keys := make([]int, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+// Decodes a genome and writes its network in one of the export formats
+func render(fs *flag.FlagSet) func(a *Archive, out io.Writer) error {
+	id := fs.String("id", "best", "Genome to render: an ID in the population or \"best\"")
+	format := fs.String("format", "dot", "Output format: dot, json or go")
+	file := fs.String("o", "", "File to write. By default the output is written to standard out")
+	pkg := fs.String("package", "main", "Package of the generated Go source")
+	fn := fs.String("func", "Network", "Name of the function or type in the generated Go source")
+	return func(a *Archive, out io.Writer) error {
+		g, err := a.Genome(*id)
+		if err != nil {
+			return err
+		}
+		p, err := a.Context.Decoder().Decode(g)
+		if err != nil {
+			return err
+		}
+		x, err := decoder.Interchange(p)
+		if err != nil {
+			return err
+		}
+		if *file != "" {
+			f, err := os.Create(*file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		switch *format {
+		case "dot":
+			return x.WriteDOT(out, fmt.Sprintf("genome%d", g.ID))
+		case "json":
+			return x.WriteJSON(out)
+		case "go":
+			return x.WriteGo(out, *pkg, *fn)
+		default:
+			return fmt.Errorf("inspect.render - Unknown format %q", *format)
+		}
+	}
+}
+
+// Decodes a genome and evaluates it again, either locally with a registered evaluator or remotely
+// with a worker serving evaluations for a distributed searcher
+func eval(fs *flag.FlagSet) func(a *Archive, out io.Writer) error {
+	id := fs.String("id", "best", "Genome to evaluate: an ID in the population or \"best\"")
+	name := fs.String("evaluator", "", "Name of the registered evaluator")
+	worker := fs.String("worker", "", "Address of a worker to evaluate the genome instead of a registered evaluator")
+	show := fs.Bool("show", false, "Asks the evaluator to show its work")
+	return func(a *Archive, out io.Writer) error {
+		g, err := a.Genome(*id)
+		if err != nil {
+			return err
+		}
+		p, err := a.Context.Decoder().Decode(g)
+		if err != nil {
+			return err
+		}
+
+		var r neat.Result
+		switch {
+		case *worker != "":
+			a.Context.Settings.WorkerAddresses = []string{*worker}
+			s := &searcher.Distributed{DistributedSettings: a.Context}
+			s.SetContext(a.Context)
+			rs, err := s.Search([]neat.Phenome{p})
+			if err != nil {
+				return err
+			}
+			r = rs[0]
+		case *name != "":
+			evl, err := newEvaluator(*name)
+			if err != nil {
+				return err
+			}
+			if ch, ok := evl.(neat.Contextable); ok {
+				if err = ch.SetContext(a.Context); err != nil {
+					return err
+				}
+			}
+			if dh, ok := evl.(neat.Demonstrable); ok {
+				dh.ShowWork(*show)
+			}
+			r = evl.Evaluate(p)
+		default:
+			return fmt.Errorf("inspect.eval - Either a registered evaluator (-evaluator) or a worker (-worker) is required. Registered evaluators are %v", Evaluators())
+		}
+
+		fmt.Fprintf(out, "Genome %d fitness %f (archived %f)", g.ID, r.Fitness(), g.Fitness)
+		if r.Stop() {
+			fmt.Fprintf(out, ", stop condition met")
+		}
+		fmt.Fprintln(out)
+		if oh, ok := r.(neat.Objectivable); ok {
+			fmt.Fprintf(out, "Objectives %v\n", oh.Objectives())
+		}
+		if bh, ok := r.(neat.Behaviorable); ok {
+			fmt.Fprintf(out, "Behavior %v\n", bh.Behavior())
+		}
+		return r.Err()
+	}
+}
+
+// Traces the ancestry of the best genome, or the one identified by -id, back to the initial
+// population. Only the first parent of each genome is followed unless -all is set.
+func lineage(fs *flag.FlagSet) func(a *Archive, out io.Writer) error {
+	id := fs.String("id", "best", "Genome to trace: an ID in the population or \"best\"")
+	all := fs.Bool("all", false, "Follows both parents of crossed genomes")
+	dot := fs.Bool("dot", false, "Writes the phylogenetic tree as DOT")
+	return func(a *Archive, out io.Writer) error {
+		g, err := a.Genome(*id)
+		if err != nil {
+			return err
+		}
+		if _, ok := a.Genealogy.Ancestor(g.ID); !ok {
+			return fmt.Errorf("inspect.lineage - Genome %d is not in the genealogy. Was TrackGenealogy set?", g.ID)
+		}
+		if *dot {
+			return a.Genealogy.WriteDOT(out, g.ID)
+		}
+		var as []neat.Ancestor
+		if *all {
+			as = a.Genealogy.Ancestry(g.ID)
+		} else {
+			as = a.Genealogy.Lineage(g.ID)
+		}
+		fmt.Fprintf(out, "     ID  Birth      Fitness  Parents       Mutations\n")
+		fmt.Fprintf(out, "------- ------ ------------ ------------- ----------------------------\n")
+		for _, x := range as {
+			ps := fmt.Sprint(x.Parents)
+			ms := fmt.Sprint(x.Mutations)
+			if len(x.Innovations) > 0 {
+				ms = fmt.Sprintf("%s new %v", ms, x.Innovations)
+			}
+			fmt.Fprintf(out, "%7d %6d %12f %-13s %s\n", x.ID, x.Birth, x.Fitness, ps, ms)
+		}
+		return nil
+	}
+}
+
+// Converts the archive, including its snapshots, into another format. The state of each helper is
+// converted only if the context created with the tool's options uses the same helpers as the
+// experiment which wrote the archive.
+func convert(fs *flag.FlagSet) func(a *Archive, out io.Writer) error {
+	format := fs.String("format", "gob", "Format of the converted archive: json or gob")
+	dir := fs.String("o", "", "Directory of the converted archive")
+	return func(a *Archive, out io.Writer) error {
+		if *dir == "" {
+			return fmt.Errorf("inspect.convert - The directory of the converted archive (-o) is required")
+		}
+		if a.file == nil {
+			return fmt.Errorf("inspect.convert - Only archives written by archiver.File can be converted")
+		}
+		if _, err := archiver.FormatByName(*format); err != nil {
+			return err
+		}
+		dst := &archiver.File{FileSettings: settings{path: *dir, name: a.file.ArchiveName(), format: *format}}
+		if a.trial != starter.NoTrials {
+			dst.SetTrial(a.trial)
+		}
+		newContext := func() neat.Context {
+			ctx := starter.NewContext(nil, a.options...)
+			exp := &neat.Experiment{ExperimentSettings: ctx}
+			exp.SetContext(ctx)
+			return ctx
+		}
+		if err := archiver.Convert(newContext, a.file, dst); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Converted the archive and %d snapshots to %s in %s\n", len(a.Generations), *format, *dir)
+		return nil
+	}
+}
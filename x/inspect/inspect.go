@@ -0,0 +1,257 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+// Package inspect reads the files written by archiver.File and implements the subcommands of the
+// neat command line tool. Applications which want to re-evaluate genomes with their own
+// evaluators build their own copy of the tool:
+//
+//	func main() {
+//		inspect.RegisterEvaluator("xor", func() neat.Evaluator { return &Evaluator{} })
+//		if err := inspect.Main(os.Args[1:]); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+package inspect
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/archiver"
+	"github.com/rqme/neat/x/starter"
+)
+
+var (
+	evaluators   = make(map[string]func() neat.Evaluator)
+	evaluatorsMu sync.RWMutex
+)
+
+// Registers a function which creates the evaluator used by the eval subcommand under the name
+func RegisterEvaluator(name string, fn func() neat.Evaluator) {
+	evaluatorsMu.Lock()
+	defer evaluatorsMu.Unlock()
+	evaluators[name] = fn
+}
+
+// Returns the names of the registered evaluators
+func Evaluators() []string {
+	evaluatorsMu.RLock()
+	defer evaluatorsMu.RUnlock()
+	names := make([]string, 0, len(evaluators))
+	for n := range evaluators {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func newEvaluator(name string) (neat.Evaluator, error) {
+	evaluatorsMu.RLock()
+	defer evaluatorsMu.RUnlock()
+	if fn, ok := evaluators[name]; ok {
+		return fn(), nil
+	}
+	return nil, fmt.Errorf("inspect - Unknown evaluator %q. Registered evaluators are %v", name, Evaluators())
+}
+
+// Archive is the restored content of an archive
+type Archive struct {
+	Context     *starter.Context
+	Population  neat.Population
+	Best        neat.Genome     // Best genome found by the experiment
+	Iteration   int             // Iterations completed by the experiment
+	Stopped     bool            // True if the experiment's stop condition was met
+	Generations []int           // Generations of the snapshots kept in the archive
+	Genealogy   *neat.Genealogy // Genealogy recorded by the generator, if it was tracked
+
+	// Restorer of the archive, if written by archiver.File, and the options used to create its
+	// context
+	file    *archiver.File
+	trial   int
+	options []func(*starter.Context)
+}
+
+// Restores the archive named name in the path. If trial is not starter.NoTrials, the archive is
+// read from the trial's subdirectory. If generation is not negative, the snapshot of that
+// generation is restored instead of the latest files. Options are applied to the context before
+// the archive is restored so that, for example, an application can set the decoder its
+// experiment used. Archives written by archiver.Store, <name>.db, are read as well but, as the
+// store keeps only the latest state, they have no snapshots.
+func Open(path, name string, trial, generation int, options ...func(*starter.Context)) (a *Archive, err error) {
+	a = &Archive{Context: starter.NewContext(nil, options...), trial: trial, options: options}
+	state := a.Context.State()
+	state["population"] = &a.Population
+	state["experiment"] = &struct {
+		Best      *neat.Genome
+		Iteration *int
+		Stopped   *bool
+	}{&a.Best, &a.Iteration, &a.Stopped}
+	a.Genealogy = neat.NewGenealogy()
+	state["generator"] = &struct{ Genealogy *neat.Genealogy }{a.Genealogy}
+
+	dir := path
+	if trial != starter.NoTrials {
+		dir = filepath.Join(dir, strconv.Itoa(trial))
+	}
+	if _, serr := os.Stat(filepath.Join(dir, name+".db")); serr == nil {
+		if err = openStore(a, settings{path: path, name: name, gen: generation}, trial); err != nil {
+			return nil, err
+		}
+	} else {
+		rst := &archiver.File{FileSettings: settings{path: path, name: name, gen: generation}}
+		if trial != starter.NoTrials {
+			rst.SetTrial(trial)
+		}
+		if err = rst.Restore(a.Context); err != nil {
+			return nil, err
+		}
+		if a.Generations, err = rst.Generations(); err != nil {
+			return nil, err
+		}
+		a.file = rst
+	}
+
+	// Older archives do not record the best genome so look for it in the population
+	if a.Best.ID == 0 && len(a.Best.Nodes) == 0 {
+		for i, g := range a.Population.Genomes {
+			if i == 0 || g.Fitness > a.Best.Fitness {
+				a.Best = g
+			}
+		}
+	}
+	return
+}
+
+// Restores the archive from the store, which rejects a generation other than the latest
+func openStore(a *Archive, s settings, trial int) error {
+	st := &archiver.Store{FileSettings: s}
+	if trial != starter.NoTrials {
+		st.SetTrial(trial)
+	}
+	defer st.Close()
+	return st.Restore(a.Context)
+}
+
+type settings struct {
+	path, name, format string
+	gen                int
+}
+
+func (s settings) ArchiveName() string    { return s.name }
+func (s settings) ArchivePath() string    { return s.path }
+func (s settings) ArchiveFormat() string  { return s.format }
+func (s settings) ArchiveEvery() int      { return 0 }
+func (s settings) ArchiveKeep() int       { return 0 }
+func (s settings) RestoreGeneration() int { return s.gen }
+
+// Returns the genome identified by id, which is either a genome ID in the population or "best"
+func (a Archive) Genome(id string) (neat.Genome, error) {
+	if id == "" || id == "best" {
+		return a.Best, nil
+	}
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return neat.Genome{}, fmt.Errorf("inspect - Invalid genome %q", id)
+	}
+	if a.Best.ID == n {
+		return a.Best, nil
+	}
+	for _, g := range a.Population.Genomes {
+		if g.ID == n {
+			return g, nil
+		}
+	}
+	return neat.Genome{}, fmt.Errorf("inspect - Genome %d is not in the archived population", n)
+}
+
+// A subcommand of the tool. Setup defines the command's flags and returns the function which runs
+// it once the flags are parsed.
+type command struct {
+	name, usage string
+	setup       func(fs *flag.FlagSet) func(a *Archive, out io.Writer) error
+}
+
+var commands = []command{
+	{"list", "Lists the archived snapshots and the species of a generation", list},
+	{"best", "Prints the best genome", best},
+	{"diff", "Compares two genomes by innovation", diff},
+	{"lineage", "Traces the ancestry of a genome recorded by the generator", lineage},
+	{"render", "Writes a genome's network as DOT, interchange JSON or Go source", render},
+	{"eval", "Re-evaluates a genome with a registered evaluator or a remote worker", eval},
+	{"convert", "Copies the archive, including its snapshots, into another format", convert},
+}
+
+// Runs the subcommand named by the first argument. Options are passed to Open.
+func Main(args []string, options ...func(*starter.Context)) error {
+	return run(args, os.Stdout, os.Stderr, options...)
+}
+
+func run(args []string, out, errOut io.Writer, options ...func(*starter.Context)) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "-help" {
+		usage(errOut)
+		return nil
+	}
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+		fs.SetOutput(errOut)
+		path := fs.String("path", ".", "Directory containing the archive")
+		name := fs.String("name", "", "Name prepended to the archive's files, usually the experiment's executable")
+		trial := fs.Int("trial", starter.NoTrials, "Trial whose archive is read. By default the archive is not in a trial directory.")
+		gen := fs.Int("generation", -1, "Generation of the snapshot to read. By default the latest files are read.")
+		fn := c.setup(fs)
+		if err := fs.Parse(args[1:]); err != nil {
+			return err
+		}
+		if *name == "" {
+			return fmt.Errorf("inspect.%s - The archive name is required", c.name)
+		}
+		a, err := Open(*path, *name, *trial, *gen, options...)
+		if err != nil {
+			return err
+		}
+		return fn(a, out)
+	}
+	usage(errOut)
+	return fmt.Errorf("inspect - Unknown command %q", args[0])
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintf(w, "Usage: neat <command> -name <archive name> [-path <dir>] [-trial <n>] [-generation <n>] [flags]\n\nCommands:\n")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintf(w, "\nUse \"neat <command> -h\" for the flags of a command.\n")
+}
@@ -0,0 +1,224 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/archiver"
+	"github.com/rqme/neat/result"
+	"github.com/rqme/neat/visualizer"
+	"github.com/rqme/neat/x/starter"
+)
+
+type xor struct{}
+
+func (e xor) Evaluate(p neat.Phenome) neat.Result {
+	var sum float64
+	for i, in := range [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		outputs, err := p.Activate(in)
+		if err != nil {
+			return result.New(p.ID(), 0, err, false)
+		}
+		sum += math.Abs(outputs[0] - float64((i+1)/2%2))
+	}
+	return result.New(p.ID(), math.Pow(4-sum, 2), nil, false)
+}
+
+func init() {
+	RegisterEvaluator("xor", func() neat.Evaluator { return xor{} })
+}
+
+// Runs a few generations of XOR, archiving them in the directory with the archiver
+func archive(t *testing.T, dir string, store bool) {
+	ctx := starter.NewContext(xor{}, func(c *starter.Context) {
+		if store {
+			c.SetArchiver(&archiver.Store{FileSettings: c})
+		} else {
+			c.SetArchiver(&archiver.File{FileSettings: c})
+		}
+		c.SetVisualizer(visualizer.Null{})
+	})
+	ctx.Settings = starter.Settings{
+		ExperimentName: "XOR", Iterations: 3, FitnessType: neat.Absolute, Seed: 1, NumWorkers: 2,
+		ArchivePath: dir, ArchiveName: "xor", ArchiveEvery: 1, TrackGenealogy: true,
+		DisjointCoefficient: 1, ExcessCoefficient: 1, WeightCoefficient: 0.4,
+		EnableProbability: 0.2, MateByAveragingProbability: 0.4,
+		PopulationSize: 20, NumInputs: 2, NumOutputs: 1, OutputActivation: neat.Sigmoid,
+		WeightRange: 2.5, SurvivalThreshold: 0.2, MutateOnlyProbability: 0.25,
+		InterspeciesMatingRate: 0.001, MaxStagnation: 15,
+		MutateWeightProbability: 0.9, ReplaceWeightProbability: 0.2,
+		AddNodeProbability: 0.1, AddConnProbability: 0.1, HiddenActivation: neat.Sigmoid,
+		CompatibilityThreshold: 3, TargetNumberOfSpecies: 3, CompatibilityModifier: 0.3,
+	}
+	exp := &neat.Experiment{ExperimentSettings: ctx}
+	if err := exp.SetContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.SetPopulation(exp.Population())
+	if err := neat.Run(exp); err != nil {
+		t.Fatal(err)
+	}
+	if st, ok := ctx.Archiver().(*archiver.Store); ok {
+		st.Close()
+	}
+}
+
+// Runs the subcommand and returns its output
+func runCommand(t *testing.T, args ...string) (string, error) {
+	out, errOut := new(bytes.Buffer), new(bytes.Buffer)
+	err := run(args, out, errOut)
+	return out.String() + errOut.String(), err
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	archive(t, dir, false)
+	arc := []string{"-path", dir, "-name", "xor"}
+	var cases = []struct {
+		args     []string
+		expected []string // Parts of the output expected
+	}{
+		{[]string{"list"}, []string{"Snapshots of generations [0 1 2]", "Experiment XOR at generation 2, iteration 3", "Species  Size"}},
+		{[]string{"list", "-generation", "1"}, []string{"at generation 1, iteration 2"}},
+		{[]string{"best"}, []string{"Genome ", "Nodes:"}},
+		{[]string{"best", "-json"}, []string{"\"Nodes\": ["}},
+		{[]string{"diff", "-b", "best"}, []string{"--- genome", "nodes match", "connections match. Total weight difference 0.000000"}},
+		{[]string{"lineage"}, []string{"     ID  Birth"}},
+		{[]string{"lineage", "-dot"}, []string{"digraph"}},
+		{[]string{"render"}, []string{"digraph \"genome"}},
+		{[]string{"render", "-format", "json"}, []string{"\"format\": \"neat-network\""}},
+		{[]string{"render", "-format", "go", "-package", "champion"}, []string{"package champion", "func Network(inputs []float64) []float64"}},
+		{[]string{"eval", "-evaluator", "xor"}, []string{"Genome ", "fitness"}},
+	}
+	for _, c := range cases {
+		out, err := runCommand(t, append(append([]string{c.args[0]}, arc...), c.args[1:]...)...)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", c.args, err)
+			continue
+		}
+		for _, s := range c.expected {
+			if !strings.Contains(out, s) {
+				t.Errorf("%v: expected the output to contain %q:\n%s", c.args, s, out)
+			}
+		}
+	}
+}
+
+func TestBestMatchesArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive(t, dir, false)
+	a, err := Open(dir, "xor", starter.NoTrials, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := runCommand(t, "best", "-path", dir, "-name", "xor", "-json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var g neat.Genome
+	if err = json.Unmarshal([]byte(out), &g); err != nil {
+		t.Fatal(err)
+	}
+	if g.ID != a.Best.ID || g.Fitness != a.Best.Fitness {
+		t.Errorf("Expected the best genome %d, got %d", a.Best.ID, g.ID)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	dir, out := t.TempDir(), t.TempDir()
+	archive(t, dir, false)
+	if _, err := runCommand(t, "convert", "-path", dir, "-name", "xor", "-o", out); err != nil {
+		t.Fatal(err)
+	}
+	a, err := Open(dir, "xor", starter.NoTrials, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Open(out, "xor", starter.NoTrials, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Generations) != len(a.Generations) || b.Population.Generation != 2 {
+		t.Errorf("Expected the converted snapshots %v, got %v at generation %d", a.Generations, b.Generations, b.Population.Generation)
+	}
+}
+
+func TestCommandErrors(t *testing.T) {
+	dir := t.TempDir()
+	archive(t, dir, false)
+	var cases = [][]string{
+		{"unknown"},
+		{"list", "-path", dir},
+		{"list", "-path", dir, "-name", "missing"},
+		{"best", "-path", dir, "-name", "xor", "-id", "-5"},
+		{"diff", "-path", dir, "-name", "xor"},
+		{"render", "-path", dir, "-name", "xor", "-format", "svg"},
+		{"eval", "-path", dir, "-name", "xor"},
+		{"eval", "-path", dir, "-name", "xor", "-evaluator", "missing"},
+		{"convert", "-path", dir, "-name", "xor"},
+	}
+	for _, args := range cases {
+		if _, err := runCommand(t, args...); err == nil {
+			t.Errorf("%v: expected an error", args)
+		}
+	}
+	if out, err := runCommand(t, "help"); err != nil || !strings.Contains(out, "Commands:") {
+		t.Errorf("Expected the usage, got %v:\n%s", err, out)
+	}
+}
+
+func TestStoreArchive(t *testing.T) {
+	dir := t.TempDir()
+	archive(t, dir, true)
+	if _, err := os.Stat(filepath.Join(dir, "xor.db")); err != nil {
+		t.Fatal(err)
+	}
+	out, err := runCommand(t, "list", "-path", dir, "-name", "xor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "Experiment XOR at generation 2, iteration 3") || strings.Contains(out, "Snapshots") {
+		t.Errorf("Expected the store's latest generation without snapshots:\n%s", out)
+	}
+	if _, err = runCommand(t, "best", "-path", dir, "-name", "xor"); err != nil {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if _, err = runCommand(t, "list", "-path", dir, "-name", "xor", "-generation", "1"); err == nil {
+		t.Errorf("Expected a generation of the store to be rejected")
+	}
+	if _, err = runCommand(t, "convert", "-path", dir, "-name", "xor", "-o", t.TempDir()); err == nil || !strings.Contains(err.Error(), "archiver.File") {
+		t.Errorf("Expected converting the store to be rejected, got %v", err)
+	}
+}
@@ -26,7 +26,12 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package starter
 
 import (
+	"fmt"
+	"math/rand"
 	"os"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/rqme/neat"
 	"github.com/rqme/neat/archiver"
@@ -37,6 +42,7 @@ import (
 	"github.com/rqme/neat/mutator"
 	"github.com/rqme/neat/searcher"
 	"github.com/rqme/neat/speciater"
+	"github.com/rqme/neat/substrate"
 	"github.com/rqme/neat/visualizer"
 )
 
@@ -56,6 +62,8 @@ type Context struct {
 	Settings
 	state map[string]interface{}
 	identify
+	rnd source
+	rng *rand.Rand
 }
 
 func NewContext(evl neat.Evaluator, options ...func(*Context)) *Context {
@@ -64,9 +72,11 @@ func NewContext(evl neat.Evaluator, options ...func(*Context)) *Context {
 	ctx := &Context{
 		state: make(map[string]interface{}),
 		identify: identify{
-			innos: make(map[innovation]int, 100),
+			innos: make(innovations, 100),
 		},
 	}
+	ctx.state["random"] = &ctx.rnd
+	ctx.state["identify"] = ctx.identify.checkpoint()
 
 	// Set the default helpers
 	if ctx.Settings.ArchivePath == "" && *ConfigName == "" {
@@ -81,14 +91,19 @@ func NewContext(evl neat.Evaluator, options ...func(*Context)) *Context {
 	ctx.arc = &archiver.File{FileSettings: ctx}
 	ctx.cmp = &comparer.Classic{ClassicSettings: ctx}
 	ctx.crs = &crosser.Classic{ClassicSettings: ctx}
-	ctx.dec = &decoder.Classic{}
+	ctx.dec = &decoder.Classic{ClassicSettings: ctx}
 	ctx.evl = evl
 	ctx.gen = &generator.Classic{ClassicSettings: ctx}
 	ctx.mut = mutator.New(ctx, ctx, ctx)
-	ctx.src = &searcher.Concurrent{}
+	if *Workers != "" {
+		ctx.Settings.WorkerAddresses = strings.Split(*Workers, ",")
+		ctx.src = &searcher.Distributed{DistributedSettings: ctx}
+	} else {
+		ctx.src = &searcher.Concurrent{ConcurrentSettings: ctx}
+	}
 	ctx.spc = speciater.NewDynamic(ctx, ctx)
 	//ctx.spc = &speciater.Classic{ClassicSettings: ctx}
-	ctx.vis = &visualizer.Web{WebSettings: ctx}
+	ctx.vis = visualizer.Multi{&visualizer.Web{WebSettings: ctx}, &visualizer.Stats{StatsSettings: ctx}}
 
 	// Override with the options
 	for _, option := range options {
@@ -108,6 +123,57 @@ func attachContext(ctx *Context) {
 	}
 }
 
+// Replaces the helpers named in the settings and connects them to the context. Helpers which the
+// settings do not name are kept, including those set by the options.
+func (c *Context) configure() error {
+	switch strings.ToLower(c.Settings.Archiver) {
+	case "":
+	case "file":
+		c.arc = &archiver.File{FileSettings: c}
+	case "store":
+		c.arc = &archiver.Store{FileSettings: c}
+	default:
+		return fmt.Errorf("starter.Context.configure - Unknown archiver %q", c.Settings.Archiver)
+	}
+	switch strings.ToLower(c.Settings.Generator) {
+	case "":
+	case "classic":
+		c.gen = &generator.Classic{ClassicSettings: c}
+	case "nsga":
+		c.gen = generator.NewNSGA(c)
+	case "realtime":
+		c.gen = &generator.RealTime{RealTimeSettings: c}
+	default:
+		return fmt.Errorf("starter.Context.configure - Unknown generator %q", c.Settings.Generator)
+	}
+	switch strings.ToLower(c.Settings.Speciater) {
+	case "":
+	case "dynamic":
+		c.spc = speciater.NewDynamic(c, c)
+	case "classic":
+		c.spc = &speciater.Classic{ClassicSettings: c}
+	case "kmedoids":
+		c.spc = &speciater.KMedoids{KMedoidsSettings: c}
+	default:
+		return fmt.Errorf("starter.Context.configure - Unknown speciater %q", c.Settings.Speciater)
+	}
+
+	// Load the substrate layers from their file. A relative path is found from the configuration
+	// path.
+	if f := c.Settings.SubstrateFile; f != "" {
+		if !path.IsAbs(f) {
+			f = path.Join(*ConfigPath, f)
+		}
+		layers, err := substrate.Load(f)
+		if err != nil {
+			return fmt.Errorf("starter.Context.configure - Could not load substrate file %s: %v", f, err)
+		}
+		c.Settings.SubstrateLayers = layers
+	}
+	attachContext(c)
+	return nil
+}
+
 // Main context methods
 func (c Context) Archiver() neat.Archiver       { return c.arc }
 func (c Context) Comparer() neat.Comparer       { return c.cmp }
@@ -121,6 +187,21 @@ func (c Context) Speciater() neat.Speciater     { return c.spc }
 func (c Context) Visualizer() neat.Visualizer   { return c.vis }
 func (c Context) State() map[string]interface{} { return c.state }
 
+// Returns the context's random number generator, creating it from the seed setting on first use.
+// A restored generator continues from its archived position instead.
+func (c *Context) Rand() *rand.Rand {
+	if c.rng == nil {
+		if c.rnd.Origin == 0 {
+			if c.Settings.Seed == 0 {
+				c.Settings.Seed = time.Now().UnixNano()
+			}
+			c.rnd.Origin = c.Settings.Seed
+		}
+		c.rng = rand.New(&c.rnd)
+	}
+	return c.rng
+}
+
 func (c *Context) SetArchiver(h neat.Archiver)     { c.arc = h }
 func (c *Context) SetComparer(h neat.Comparer)     { c.cmp = h }
 func (c *Context) SetCrosser(h neat.Crosser)       { c.crs = h }
@@ -137,10 +218,15 @@ func (c Context) Iterations() int               { return c.Settings.Iterations }
 func (c Context) Traits() neat.Traits           { return c.Settings.Traits }
 func (c Context) FitnessType() neat.FitnessType { return c.Settings.FitnessType }
 func (c Context) ExperimentName() string        { return c.Settings.ExperimentName }
+func (c Context) NumWorkers() int               { return c.Settings.NumWorkers }
 
 // File archiver settings
-func (c Context) ArchivePath() string { return c.Settings.ArchivePath }
-func (c Context) ArchiveName() string { return c.Settings.ArchiveName }
+func (c Context) ArchivePath() string    { return c.Settings.ArchivePath }
+func (c Context) ArchiveName() string    { return c.Settings.ArchiveName }
+func (c Context) ArchiveEvery() int      { return c.Settings.ArchiveEvery }
+func (c Context) ArchiveKeep() int       { return c.Settings.ArchiveKeep }
+func (c Context) ArchiveFormat() string  { return c.Settings.ArchiveFormat }
+func (c Context) RestoreGeneration() int { return *Generation }
 
 // Classic comparer settings
 func (c Context) DisjointCoefficient() float64 { return c.Settings.DisjointCoefficient }
@@ -151,8 +237,29 @@ func (c Context) WeightCoefficient() float64   { return c.Settings.WeightCoeffic
 func (c Context) EnableProbability() float64          { return c.Settings.EnableProbability }
 func (c Context) MateByAveragingProbability() float64 { return c.Settings.MateByAveragingProbability }
 
+// Classic decoder settings
+func (c Context) ActivationIterations() int { return c.Settings.ActivationIterations }
+
 // HyperNEAT decoder settings
 func (c Context) SubstrateLayers() []decoder.SubstrateNodes { return c.Settings.SubstrateLayers }
+func (c Context) LinkExpressionOutput() bool                { return c.Settings.LinkExpressionOutput }
+func (c Context) ExpressionThreshold() float64              { return c.Settings.ExpressionThreshold }
+func (c Context) WeightScaling() string                     { return c.Settings.WeightScaling }
+func (c Context) BiasOutputs() bool                         { return c.Settings.BiasOutputs }
+func (c Context) CppnInputs() []string                      { return c.Settings.CppnInputs }
+
+// Returns the activations of the substrate's nodes. Unknown names are included with an invalid
+// type so that the decoder reports them.
+func (c Context) NodeActivations() []neat.ActivationType {
+	if len(c.Settings.NodeActivations) == 0 {
+		return nil
+	}
+	acts := make([]neat.ActivationType, len(c.Settings.NodeActivations))
+	for i, name := range c.Settings.NodeActivations {
+		acts[i], _ = neat.ActivationByName(name)
+	}
+	return acts
+}
 
 // ESHyperNEAT decoder settings
 func (c Context) InitialDepth() int          { return c.Settings.InitialDepth }
@@ -173,6 +280,10 @@ func (c Context) SurvivalThreshold() float64            { return c.Settings.Surv
 func (c Context) MutateOnlyProbability() float64        { return c.Settings.MutateOnlyProbability }
 func (c Context) InterspeciesMatingRate() float64       { return c.Settings.InterspeciesMatingRate }
 func (c Context) MaxStagnation() int                    { return c.Settings.MaxStagnation }
+func (c Context) RepairOffspring() bool                 { return c.Settings.RepairOffspring }
+func (c Context) RequireFeedForward() bool              { return !c.Settings.AllowRecurrent }
+func (c Context) TrackGenealogy() bool                  { return c.Settings.TrackGenealogy }
+func (c Context) PruneGenealogy() bool                  { return c.Settings.PruneGenealogy }
 
 // Real-Time generator settings
 func (c Context) IneligiblePercent() float64 { return c.Settings.IneligiblePercent }
@@ -189,8 +300,31 @@ func (c Context) ReplaceSettingProbability() float64    { return c.Settings.Repl
 func (c Context) AddNodeProbability() float64           { return c.Settings.AddNodeProbability }
 func (c Context) AddConnProbability() float64           { return c.Settings.AddConnProbability }
 func (c Context) HiddenActivation() neat.ActivationType { return c.Settings.HiddenActivation }
+func (c Context) AllowRecurrent() bool                  { return c.Settings.AllowRecurrent }
 func (c Context) DelNodeProbability() float64           { return c.Settings.DelNodeProbability }
 func (c Context) DelConnProbability() float64           { return c.Settings.DelConnProbability }
+func (c Context) MutateOutputActivation() bool          { return c.Settings.MutateOutputActivation }
+
+// Returns the weighted palette of activations. Unknown names are included with an invalid type so
+// that the mutator reports them.
+func (c Context) ActivationPalette() map[neat.ActivationType]float64 {
+	if len(c.Settings.ActivationWeights) > 0 {
+		p := make(map[neat.ActivationType]float64, len(c.Settings.ActivationWeights))
+		for k, w := range c.Settings.ActivationWeights {
+			a, _ := neat.ActivationByName(k)
+			p[a] = w
+		}
+		return p
+	}
+	switch strings.ToLower(c.Settings.ActivationPalette) {
+	case "direct":
+		return mutator.DirectPalette
+	case "cppn":
+		return mutator.CPPNPalette
+	default:
+		return nil
+	}
+}
 
 // Phased mutator settings
 func (c Context) PruningPhaseThreshold() float64    { return c.Settings.PruningPhaseThreshold }
@@ -203,11 +337,23 @@ func (c Context) NoveltyEvalArchive() bool         { return c.Settings.NoveltyEv
 func (c Context) NoveltyArchiveThreshold() float64 { return c.Settings.NoveltyArchiveThreshold }
 func (c Context) NumNearestNeighbors() int         { return c.Settings.NumNearestNeighbors }
 
+// Distributed searcher settings
+func (c Context) WorkerAddresses() []string { return c.Settings.WorkerAddresses }
+func (c Context) EvaluationTimeout() time.Duration {
+	return time.Duration(c.Settings.EvaluationTimeout * float64(time.Second))
+}
+func (c Context) MaxRetries() int { return c.Settings.MaxRetries }
+
 // Classic speciate settings
 func (c Context) CompatibilityThreshold() float64      { return c.Settings.CompatibilityThreshold }
 func (c *Context) SetCompatibilityThreshold(v float64) { c.Settings.CompatibilityThreshold = v }
 func (c Context) TargetNumberOfSpecies() int           { return c.Settings.TargetNumberOfSpecies }
 func (c Context) CompatibilityModifier() float64       { return c.Settings.CompatibilityModifier }
+func (c Context) SpeciesRepresentative() string        { return c.Settings.SpeciesRepresentative }
 
 // Web visualizer settings
 func (c Context) WebPath() string { return c.Settings.WebPath }
+
+// Stats visualizer settings
+func (c Context) StatsPath() string   { return c.Settings.StatsPath }
+func (c Context) StatsFormat() string { return c.Settings.StatsFormat }
@@ -0,0 +1,128 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+package starter
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/rqme/neat"
+	"github.com/rqme/neat/archiver"
+	"github.com/rqme/neat/result"
+	"github.com/rqme/neat/visualizer"
+)
+
+type xor struct{}
+
+func (e xor) Evaluate(p neat.Phenome) neat.Result {
+	var sum float64
+	for i, in := range [][]float64{{0, 0}, {0, 1}, {1, 0}, {1, 1}} {
+		outputs, err := p.Activate(in)
+		if err != nil {
+			return result.New(p.ID(), 0, err, false)
+		}
+		sum += math.Abs(outputs[0] - float64((i+1)/2%2))
+	}
+	return result.New(p.ID(), math.Pow(4-sum, 2), nil, false)
+}
+
+// Creates an XOR experiment seeded with the seed. If dir is set, the experiment is archived
+// there every generation.
+func newSeeded(t *testing.T, seed int64, dir string) (*Context, *neat.Experiment) {
+	ctx := NewContext(xor{}, func(c *Context) {
+		if dir == "" {
+			c.SetArchiver(archiver.Null{})
+		} else {
+			c.SetArchiver(&archiver.File{FileSettings: c})
+		}
+		c.SetVisualizer(visualizer.Null{})
+	})
+	ctx.Settings = Settings{
+		Iterations: 5, FitnessType: neat.Absolute, Seed: seed, NumWorkers: 4,
+		ArchivePath: dir, ArchiveName: "xor",
+		DisjointCoefficient: 1, ExcessCoefficient: 1, WeightCoefficient: 0.4,
+		EnableProbability: 0.2, MateByAveragingProbability: 0.4,
+		PopulationSize: 30, NumInputs: 2, NumOutputs: 1, OutputActivation: neat.Sigmoid,
+		WeightRange: 2.5, SurvivalThreshold: 0.2, MutateOnlyProbability: 0.25,
+		InterspeciesMatingRate: 0.001, MaxStagnation: 15,
+		MutateWeightProbability: 0.9, ReplaceWeightProbability: 0.2,
+		AddNodeProbability: 0.03, AddConnProbability: 0.05, HiddenActivation: neat.Sigmoid,
+		CompatibilityThreshold: 3, TargetNumberOfSpecies: 5, CompatibilityModifier: 0.3,
+	}
+	exp := &neat.Experiment{ExperimentSettings: ctx}
+	if err := exp.SetContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.SetPopulation(exp.Population())
+	return ctx, exp
+}
+
+// Runs a few generations of XOR from the seed and returns the final population
+func seededRun(t *testing.T, seed int64) neat.Population {
+	_, exp := newSeeded(t, seed, "")
+	if err := neat.Run(exp); err != nil {
+		t.Fatal(err)
+	}
+	return exp.Population()
+}
+
+func TestSameSeedSamePopulation(t *testing.T) {
+	a := seededRun(t, 42)
+	b := seededRun(t, 42)
+	if len(a.Genomes) == 0 {
+		t.Fatal("Population is empty")
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Errorf("Runs with the same seed produced different populations")
+	}
+	if c := seededRun(t, 43); reflect.DeepEqual(a, c) {
+		t.Errorf("Runs with different seeds produced the same population")
+	}
+}
+
+func TestResumedRunMatchesUninterrupted(t *testing.T) {
+	want := seededRun(t, 42)
+
+	// Stop after a few generations and then resume from the archive
+	dir := t.TempDir()
+	ctx, exp := newSeeded(t, 42, dir)
+	ctx.Settings.Iterations = 2
+	if err := neat.Run(exp); err != nil {
+		t.Fatal(err)
+	}
+	ctx, exp = newSeeded(t, 0, dir)
+	if err := (&archiver.File{FileSettings: ctx}).Restore(ctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Settings.Iterations = 5
+	if err := neat.Run(exp); err != nil {
+		t.Fatal(err)
+	}
+	if got := exp.Population(); !reflect.DeepEqual(want, got) {
+		t.Errorf("Resumed run produced a different population")
+	}
+}
@@ -41,28 +41,45 @@ const (
 var (
 	ConfigPath = flag.String("config-path", "", "Path to configuration file to override archive.")
 	ConfigName = flag.String("config-name", "", "Name prepended to all configuration and state files")
+	Workers    = flag.String("workers", "", "Comma separated addresses of remote workers. If set, evaluations are distributed to them.")
+	Generation = flag.Int("generation", -1, "Generation of the archived snapshot to restore. By default the latest archive is restored.")
 )
 
 type ConfigSettings struct {
 	path, name string
+	gen        int
 }
 
-func (s ConfigSettings) ArchiveName() string { return s.name }
-func (s ConfigSettings) ArchivePath() string { return s.path }
+func (s ConfigSettings) ArchiveName() string    { return s.name }
+func (s ConfigSettings) ArchivePath() string    { return s.path }
+func (s ConfigSettings) ArchiveEvery() int      { return 0 }
+func (s ConfigSettings) ArchiveKeep() int       { return 0 }
+func (s ConfigSettings) RestoreGeneration() int { return s.gen }
 
 func NewExperiment(ctx neat.Context, cfg neat.ExperimentSettings, t int) (exp *neat.Experiment, err error) {
 
-	// Create the experiment
-	exp = &neat.Experiment{ExperimentSettings: cfg}
-	exp.SetContext(ctx)
-
-	// Restore the saved setting and, if available, state
+	// Read the saved settings so that the helpers they name are in place before the experiment
+	// registers their state
 	if *ConfigName == "" {
 		*ConfigName = os.Args[0] // Use the executable's name
 	}
 	rst := &archiver.File{
-		FileSettings: ConfigSettings{path: *ConfigPath, name: *ConfigName},
+		FileSettings: ConfigSettings{path: *ConfigPath, name: *ConfigName, gen: *Generation},
+	}
+	if c, ok := ctx.(*Context); ok {
+		if err = rst.Restore(ctx); err != nil {
+			return
+		}
+		if err = c.configure(); err != nil {
+			return
+		}
 	}
+
+	// Create the experiment
+	exp = &neat.Experiment{ExperimentSettings: cfg}
+	exp.SetContext(ctx)
+
+	// Restore the saved setting and, if available, state
 	if err = rst.Restore(ctx); err != nil {
 		return
 	}
@@ -26,6 +26,8 @@ OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
 package starter
 
 import (
+	"encoding/json"
+	"sort"
 	"sync"
 
 	"github.com/rqme/neat"
@@ -36,10 +38,49 @@ type innovation struct {
 	Key  neat.InnoKey
 }
 
+// Innovation history which can be archived
+type innovations map[innovation]int
+
+type innovationRecord struct {
+	innovation
+	ID int
+}
+
+func (h innovations) MarshalJSON() ([]byte, error) {
+	rs := make([]innovationRecord, 0, len(h))
+	for in, id := range h {
+		rs = append(rs, innovationRecord{innovation: in, ID: id})
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].ID < rs[j].ID })
+	return json.Marshal(rs)
+}
+
+func (h *innovations) UnmarshalJSON(data []byte) error {
+	var rs []innovationRecord
+	if err := json.Unmarshal(data, &rs); err != nil {
+		return err
+	}
+	if *h == nil {
+		*h = make(innovations, len(rs))
+	}
+	for _, r := range rs {
+		(*h)[r.innovation] = r.ID
+	}
+	return nil
+}
+
 type identify struct {
 	sync.Mutex
 	lastID int
-	innos  map[innovation]int
+	innos  innovations
+}
+
+// Returns the ID sequence and innovation history so that they can be archived
+func (x *identify) checkpoint() interface{} {
+	return &struct {
+		LastID      *int
+		Innovations *innovations
+	}{&x.lastID, &x.innos}
 }
 
 // NextID returns the next id in the context's sequence
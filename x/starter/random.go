@@ -0,0 +1,91 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+
+package starter
+
+import (
+	"math/rand"
+)
+
+// Lengths of the lags of the additive generator behind math/rand's sources
+const (
+	lagLong  = 607
+	lagShort = 273
+)
+
+// A source of random numbers which tracks its position in the sequence so that it can be archived
+// and, when restored, continue exactly where it left off. It produces the same sequence as
+// rand.NewSource(Origin) but keeps the generator's state, the last values drawn, so that restoring
+// it does not replay the sequence.
+type source struct {
+	Origin int64    // Seed which started the sequence
+	Draws  uint64   // Number of values drawn from the sequence
+	Lags   []uint64 // Last values drawn, indexed by their position modulo the long lag
+}
+
+// Ensures the lags are present, creating them from the origin if necessary. Archives which predate
+// the lags are restored by replaying their draws.
+func (s *source) sync() {
+	if len(s.Lags) == lagLong {
+		return
+	}
+
+	// The sequence continues y[n] = y[n-607] + y[n-273]. Recover the values before the first draw
+	// from the first values of math/rand's source.
+	src := rand.NewSource(s.Origin).(rand.Source64)
+	y := make([]uint64, lagLong*2)
+	for i := lagLong; i < len(y); i++ {
+		y[i] = src.Uint64()
+	}
+	for i := lagLong - 1; i >= 0; i-- {
+		y[i] = y[i+lagLong] - y[i+lagLong-lagShort]
+	}
+	s.Lags = y[:lagLong]
+
+	n := s.Draws
+	for s.Draws = 0; s.Draws < n; {
+		s.Uint64()
+	}
+}
+
+func (s *source) Int63() int64 {
+	return int64(s.Uint64() & (1<<63 - 1))
+}
+
+func (s *source) Uint64() uint64 {
+	s.sync()
+	i := s.Draws % lagLong
+	x := s.Lags[i] + s.Lags[(s.Draws+lagLong-lagShort)%lagLong]
+	s.Lags[i] = x
+	s.Draws += 1
+	return x
+}
+
+func (s *source) Seed(seed int64) {
+	s.Origin = seed
+	s.Draws = 0
+	s.Lags = nil
+}
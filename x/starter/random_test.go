@@ -0,0 +1,79 @@
+/*
+Copyright (c) 2015, Brian Hummer (brian@redq.me)
+All rights reserved.
+
+Redistribution and use in source and binary forms, with or without
+modification, are permitted provided that the following conditions are met:
+
+* Redistributions of source code must retain the above copyright notice, this
+  list of conditions and the following disclaimer.
+
+* Redistributions in binary form must reproduce the above copyright notice,
+  this list of conditions and the following disclaimer in the documentation
+  and/or other materials provided with the distribution.
+
+THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE ARE
+DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE LIABLE
+FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR CONSEQUENTIAL
+DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR
+SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER
+CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY,
+OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+*/
+package starter
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func TestSourceMatchesMathRand(t *testing.T) {
+	for _, seed := range []int64{1, 42, -7, 1 << 40} {
+		want := rand.NewSource(seed).(rand.Source64)
+		got := &source{Origin: seed}
+		for i := 0; i < 5000; i++ {
+			if w, g := want.Uint64(), got.Uint64(); w != g {
+				t.Fatalf("Seed %d draw %d: expected %d, got %d", seed, i, w, g)
+			}
+		}
+	}
+}
+
+func TestSourceResumes(t *testing.T) {
+	want := &source{Origin: 42}
+	got := &source{Origin: 42}
+	for i := 0; i < 1000; i++ {
+		want.Int63()
+		got.Int63()
+	}
+
+	// Archive and restore the source as a checkpoint would
+	b, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = &source{}
+	if err = json.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if w, g := want.Int63(), got.Int63(); w != g {
+			t.Fatalf("Draw %d after resuming: expected %d, got %d", i, w, g)
+		}
+	}
+}
+
+func TestSourceReplaysArchivesWithoutLags(t *testing.T) {
+	want := rand.NewSource(42).(rand.Source64)
+	for i := 0; i < 100; i++ {
+		want.Uint64()
+	}
+	got := &source{Origin: 42, Draws: 100}
+	if w, g := want.Uint64(), got.Uint64(); w != g {
+		t.Errorf("Expected %d, got %d", w, g)
+	}
+}
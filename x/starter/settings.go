@@ -41,10 +41,16 @@ type Settings struct {
 	Traits         neat.Traits
 	FitnessType    neat.FitnessType
 	ExperimentName string
+	Seed           int64 // Seed for the random number generator. If 0, one is chosen and recorded here
+	NumWorkers     int   // Number of concurrent decoders and evaluators. If 0, GOMAXPROCS is used
 
 	// File archiver settings
-	ArchivePath string
-	ArchiveName string
+	ArchivePath   string
+	ArchiveName   string
+	ArchiveEvery  int    // Generations between snapshots kept in the archive. If 0, only the latest files are kept
+	ArchiveKeep   int    // Number of the most recent snapshots kept. If 0, all are kept
+	ArchiveFormat string // Encoding of the archive: "json" or "gob" (gzip-compressed). If empty, JSON is used
+	Archiver      string // file or store. If empty, the context's archiver is kept
 
 	// Classic comparer settings
 	DisjointCoefficient float64
@@ -55,8 +61,18 @@ type Settings struct {
 	EnableProbability          float64
 	MateByAveragingProbability float64
 
+	// Classic decoder settings
+	ActivationIterations int // Iterations per activation of a recurrent network. If 0, calculated from the network
+
 	// HyperNEAT decoder settings
-	SubstrateLayers []decoder.SubstrateNodes
+	SubstrateLayers      []decoder.SubstrateNodes
+	SubstrateFile        string   // JSON file describing the substrate layers, replacing SubstrateLayers. Relative to the config path. See package substrate.
+	LinkExpressionOutput bool     // Use a link expression output per pair of layers
+	ExpressionThreshold  float64  // Threshold for expressing a connection. If 0, the decoder's default is used
+	WeightScaling        string   // threshold or linear. If empty, threshold unless LEO is used
+	BiasOutputs          bool     // Use an output per layer for the biases of the nodes
+	NodeActivations      []string // Names of the activations an output per layer selects from for the nodes
+	CppnInputs           []string // Parts of the CPPN's inputs: positions, deltas, distance, bias or direction. If empty, the decoder's default
 
 	// ESHyperNEAT decoder settings
 	InitialDepth      int
@@ -76,6 +92,10 @@ type Settings struct {
 	MutateOnlyProbability  float64
 	InterspeciesMatingRate float64
 	MaxStagnation          int
+	RepairOffspring        bool   // Validate and repair offspring. Feed-forward is required unless AllowRecurrent is set.
+	TrackGenealogy         bool   // Record the parents, mutations and innovations of each genome
+	PruneGenealogy         bool   // Keep only the ancestry of the current population in the genealogy
+	Generator              string // classic, nsga or realtime. If empty, the context's generator is kept
 	SeedGenome             neat.Genome
 
 	// Real-Time generator settings
@@ -93,8 +113,12 @@ type Settings struct {
 	AddNodeProbability          float64             // Probablity a node will be added to the genome
 	AddConnProbability          float64             // Probability a connection will be added to the genome
 	HiddenActivation            neat.ActivationType // Activation type to assign to new nodes
+	AllowRecurrent              bool                // Allow recurrent and self-looping connections to be added
 	DelNodeProbability          float64             // Probablity a node will be removed to the genome
 	DelConnProbability          float64             // Probability a connection will be removed to the genome
+	ActivationPalette           string              // Palette of activations to mutate to: direct, cppn or empty for all
+	ActivationWeights           map[string]float64  // Weights of activations by name, overriding the palette
+	MutateOutputActivation      bool                // Allow output activations to be mutated within their range
 
 	// Phased mutator settings
 	PruningPhaseThreshold float64
@@ -107,13 +131,28 @@ type Settings struct {
 	NoveltyArchiveThreshold float64
 	NumNearestNeighbors     int
 
+	// Distributed searcher settings
+	WorkerAddresses   []string
+	EvaluationTimeout float64 // Seconds allowed for each evaluation. If 0, there is no limit
+	MaxRetries        int
+
 	// Classic speciater settings
 	CompatibilityThreshold float64
 	TargetNumberOfSpecies  int
 	CompatibilityModifier  float64
+	SpeciesRepresentative  string // permanent, random or champion. If empty, it is permanent.
+
+	// Speciater choice. Dynamic remains the default so that existing configurations, and the
+	// compatibility thresholds archived with them, keep their behaviour. KMedoids reaches the
+	// target number of species exactly but compares every pair of genomes each generation.
+	Speciater string // dynamic, classic or kmedoids. If empty, the context's speciater is kept
 
 	// Web visualizer settings
 	WebPath string
+
+	// Stats visualizer settings
+	StatsPath   string // Path of the statistics file written alongside the web pages. If empty, none is written
+	StatsFormat string // csv or jsonl
 }
 
 func (s Settings) String() string {
@@ -29,6 +29,7 @@ package trials
 import (
 	"flag"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 	"time"
@@ -36,6 +37,7 @@ import (
 	"github.com/davecheney/profile"
 	"github.com/montanaflynn/stats"
 	"github.com/rqme/neat"
+	"github.com/rqme/neat/searcher"
 )
 
 var (
@@ -44,6 +46,7 @@ var (
 	ShowWork   = flag.Bool("show-work", false, "Evaluates the best genome separately, showing the detail ")
 	SkipEvolve = flag.Bool("skip-evolve", false, "Skips evolution phase if population restored. Use with -best to display archived population.")
 	Profile    = flag.Bool("profile", false, "Enables profiling")
+	Worker     = flag.String("worker", "", "Serves evaluations for a distributed searcher on this address instead of running trials")
 )
 
 func Run(f func(int) (*neat.Experiment, error)) error {
@@ -52,6 +55,20 @@ func Run(f func(int) (*neat.Experiment, error)) error {
 		defer profile.Start(profile.CPUProfile).Stop()
 	}
 
+	// Act as a worker for a distributed search
+	if *Worker != "" {
+		exp, err := f(-1) // not a trial
+		if err != nil {
+			return err
+		}
+		l, err := net.Listen("tcp", *Worker)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Serving evaluations on %s\n", l.Addr())
+		return searcher.Serve(l, exp.Context())
+	}
+
 	// Create the collection variables
 	n := *Trials
 	exps := make([]*neat.Experiment, n)